@@ -2,8 +2,10 @@ package main
 
 import (
 	"github.com/bacpack-system/packager/internal/packager_error"
+	"github.com/bacpack-system/packager/internal/config"
 	"github.com/bacpack-system/packager/internal/constants"
 	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/git_mirror"
 	"github.com/bacpack-system/packager/internal/log"
 	"github.com/bacpack-system/packager/internal/bacpack_package"
 	"github.com/bacpack-system/packager/internal/prerequisites"
@@ -11,33 +13,39 @@ import (
 	"github.com/bacpack-system/packager/internal/repository"
 	"github.com/bacpack-system/packager/internal/sysroot"
 	"fmt"
+	"os"
 	"slices"
+	"sort"
 )
 
 // BuildApp
 func BuildApp(cmdLine *BuildAppCmdLineArgs, contextPath string) error {
-	platformString, err := determinePlatformString(*cmdLine.DockerImageName, uint16(*cmdLine.Port))
-	if err != nil {
-		return err
-	}
-	repo := repository.GitLFSRepository{
-		GitRepoPath: *cmdLine.OutputDir,
-	}
-	err = prerequisites.Initialize(&repo)
-	if err != nil {
-		return err
-	}
 	contextManager := context.ContextManager{
 		ContextPath: contextPath,
 		ForPackage: false,
 	}
-	err = prerequisites.Initialize(&contextManager)
+	err := prerequisites.Initialize(&contextManager)
 	if err != nil {
 		logger := log.GetLogger()
 		logger.Error("Context consistency error - %s", err)
 		return packager_error.ContextErr
 	}
-	err = performPreBuildChecks(&repo, &contextManager, platformString, *cmdLine.DockerImageName)
+	imageConfig := getImageConfig(*cmdLine.Executor, *cmdLine.Offline, &contextManager, *cmdLine.DockerImageName)
+	platformString, err := getPlatformString(*cmdLine.DockerImageName, uint16(*cmdLine.Port), *cmdLine.DryRun, imageConfig.Executor)
+	if err != nil {
+		return err
+	}
+	if *cmdLine.DryRun {
+		return printAppBuildPlan(cmdLine, &contextManager, platformString)
+	}
+	if *cmdLine.UseLocalRepo && *cmdLine.RepositoryType == repository.TypeS3 {
+		return fmt.Errorf("local repository cannot be used with %s Package Repository", repository.TypeS3)
+	}
+	repo, err := repository.CreateRepository(*cmdLine.RepositoryType, *cmdLine.OutputDir)
+	if err != nil {
+		return err
+	}
+	err = performPreBuildChecks(repo, &contextManager, platformString, *cmdLine.DockerImageName, *cmdLine.StaleImage, getKeepSuperseded(*cmdLine.KeepSuperseded, repo))
 	if err != nil {
 		return err
 	}
@@ -45,11 +53,30 @@ func BuildApp(cmdLine *BuildAppCmdLineArgs, contextPath string) error {
 	handleRemover := process.SignalHandlerAddHandler(repo.RestoreAllChanges)
 	defer handleRemover()
 
+	mirrorCache, err := getMirrorCache(*cmdLine.GitMirrorDir, imageConfig.Offline)
+	if err != nil {
+		return err
+	}
+
+	var transaction *sessionTransaction
+	if *cmdLine.SingleCommit {
+		transaction, err = beginSessionTransaction(repo, nil)
+		if err != nil {
+			return err
+		}
+		rollbackRemover := process.SignalHandlerAddHandler(transaction.Rollback)
+		defer rollbackRemover()
+	}
+
 	if *cmdLine.All {
-		return buildAllApps(cmdLine, &contextManager, platformString, repo)
+		err = buildAllApps(cmdLine, &contextManager, platformString, repo, mirrorCache, imageConfig)
 	} else {
-		return buildSingleApp(cmdLine, &contextManager, platformString, repo)
+		err = buildSingleApp(cmdLine, &contextManager, platformString, repo, mirrorCache, imageConfig)
+	}
+	if err != nil || transaction == nil {
+		return err
 	}
+	return transaction.Commit(*cmdLine.DockerImageName, platformString)
 }
 
 // buildAllApps
@@ -58,7 +85,9 @@ func buildAllApps(
 	cmdLine        *BuildAppCmdLineArgs,
 	contextManager *context.ContextManager,
 	platformString *bacpack_package.PlatformString,
-	repo           repository.GitLFSRepository,
+	repo           repository.Repository,
+	mirrorCache    *git_mirror.MirrorCache,
+	imageConfig    config.ImageConfig,
 ) error {
 	configMap := contextManager.GetAllConfigsMap()
 
@@ -70,7 +99,8 @@ func buildAllApps(
 				platformString,
 				uint16(*cmdLine.Port),
 				*cmdLine.UseLocalRepo,
-				repo.GitRepoPath,
+				*cmdLine.OutputDir,
+				imageConfig,
 			)
 			if err != nil {
 				return err
@@ -78,8 +108,10 @@ func buildAllApps(
 			if len(buildConfigs) == 0 {
 				continue
 			}
+			setMirrorCache(buildConfigs, mirrorCache)
+			setKeepFailed(buildConfigs, *cmdLine.KeepFailed)
 			count++
-			err = buildAndCopyPackage(&buildConfigs, platformString, repo, constants.AppDirName)
+			err = buildAndCopyPackage(&buildConfigs, platformString, repo, constants.AppDirName, getManifestDependencies(config, contextManager))
 			if err != nil {
 				return fmt.Errorf("cannot build App '%s' - %w", config.Package.Name, err)
 			}
@@ -102,7 +134,9 @@ func buildSingleApp(
 	cmdLine        *BuildAppCmdLineArgs,
 	contextManager *context.ContextManager,
 	platformString *bacpack_package.PlatformString,
-	repo           repository.GitLFSRepository,
+	repo           repository.Repository,
+	mirrorCache    *git_mirror.MirrorCache,
+	imageConfig    config.ImageConfig,
 ) error {
 	configList, err := prepareConfigsNoBuildDeps(*cmdLine.Name, contextManager, platformString, constants.AppDirName)
 	if err != nil {
@@ -120,15 +154,58 @@ func buildSingleApp(
 			platformString,
 			uint16(*cmdLine.Port),
 			*cmdLine.UseLocalRepo,
-			repo.GitRepoPath,
+			*cmdLine.OutputDir,
+			imageConfig,
 		)
 		if err != nil {
 			return err
 		}
-		err = buildAndCopyPackage(&buildConfigs, platformString, repo, constants.AppDirName)
+		setMirrorCache(buildConfigs, mirrorCache)
+		setKeepFailed(buildConfigs, *cmdLine.KeepFailed)
+		err = buildAndCopyPackage(&buildConfigs, platformString, repo, constants.AppDirName, getManifestDependencies(config, contextManager))
 		if err != nil {
 			return fmt.Errorf("cannot build App '%s' - %w", *cmdLine.Name, err)
 		}
 	}
 	return nil
 }
+
+// printAppBuildPlan
+// Prints build plan of Apps specified by cmdLine to stdout. Nothing is built.
+func printAppBuildPlan(
+	cmdLine        *BuildAppCmdLineArgs,
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+) error {
+	var configList []config.Config
+	if *cmdLine.All {
+		configMap := contextManager.GetAllConfigsMap()
+		appNames := make([]string, 0, len(configMap))
+		for appName := range configMap {
+			appNames = append(appNames, appName)
+		}
+		sort.Strings(appNames)
+		for _, appName := range appNames {
+			configList = append(configList, configMap[appName]...)
+		}
+	} else {
+		var err error
+		configList, err = prepareConfigsNoBuildDeps(*cmdLine.Name, contextManager, platformString, constants.AppDirName)
+		if err != nil {
+			return err
+		}
+		if len(configList) == 0 {
+			return fmt.Errorf("nothing to build")
+		}
+		for _, config := range configList {
+			if !slices.Contains(config.DockerMatrix.ImageNames, *cmdLine.DockerImageName) {
+				return fmt.Errorf("'%s' does not support %s image", config.Package.Name, *cmdLine.DockerImageName)
+			}
+		}
+	}
+	plan, err := createBuildPlan(configList, *cmdLine.DockerImageName, platformString)
+	if err != nil {
+		return err
+	}
+	return plan.Print(os.Stdout, *cmdLine.PlanFormat)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/config"
+	"github.com/bacpack-system/packager/internal/constants"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"github.com/bacpack-system/packager/internal/sysroot"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+)
+
+const (
+	// Output (build plan, verification report) is printed as a table
+	outputFormatTable = "table"
+	// Output (build plan, verification report) is printed as JSON
+	outputFormatJSON = "json"
+	outputIndent      = "\x20\x20\x20\x20" // four spaces
+
+	planActionBuild = "build"
+	planActionSkip  = "skip"
+)
+
+// buildPlanItem
+// Represents one Config in the build plan.
+type buildPlanItem struct {
+	// Order of the build, starting from 1
+	Order int
+	// Name of the Package or App
+	Name string
+	// BuildType debug or release
+	BuildType string
+	// Action which will be performed (build, skip)
+	Action string
+	// Reason why the Action is performed
+	Reason string `json:",omitempty"`
+}
+
+// buildPlan
+// Represents what would be built in which order.
+type buildPlan struct {
+	ImageName      string
+	PlatformString string
+	Items          []buildPlanItem
+}
+
+// createBuildPlan
+// Creates build plan for the Configs in the given order. The Config is skipped if it does not
+// support the image or if it is already built in sysroot. The Git commit hash is not known without
+// cloning the repository, so the skipped Config is still built if its Git commit has changed.
+func createBuildPlan(
+	configList     []config.Config,
+	imageName      string,
+	platformString *bacpack_package.PlatformString,
+) (*buildPlan, error) {
+	plan := buildPlan{
+		ImageName:      imageName,
+		PlatformString: platformString.Serialize(),
+		Items:          []buildPlanItem{},
+	}
+	sysrt := sysroot.Sysroot{
+		IsDebug:        false,
+		PlatformString: platformString,
+	}
+	err := prerequisites.Initialize(&sysrt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, cfg := range configList {
+		item := buildPlanItem{
+			Order:     i + 1,
+			Name:      cfg.Package.Name,
+			BuildType: "release",
+			Action:    planActionBuild,
+		}
+		if cfg.Package.IsDebug {
+			item.BuildType = "debug"
+		}
+		sysrt.IsDebug = cfg.Package.IsDebug
+		builtPackage := sysroot.BuiltPackage{
+			Name:          cfg.Package.GetShortPackageName(),
+			DirName:       sysrt.GetDirNameInSysroot(),
+			GitUri:        cfg.Git.URI,
+			GitCommitHash: constants.EmptyGitCommitHash,
+		}
+		if !slices.Contains(cfg.DockerMatrix.ImageNames, imageName) {
+			item.Action = planActionSkip
+			item.Reason = fmt.Sprintf("%s image is not supported", imageName)
+		} else if sysrt.IsPackageInSysroot(builtPackage) {
+			item.Action = planActionSkip
+			item.Reason = "already built in sysroot (built if Git commit has changed)"
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return &plan, nil
+}
+
+// Print
+// Prints the build plan to writer in given format (table, json).
+func (plan *buildPlan) Print(writer io.Writer, format string) error {
+	switch format {
+	case outputFormatTable:
+		return plan.printTable(writer)
+	case outputFormatJSON:
+		bytes, err := json.MarshalIndent(plan, "", outputIndent)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(append(bytes, '\n'))
+		return err
+	}
+	return fmt.Errorf("unsupported build plan format '%s'", format)
+}
+
+// printTable
+// Prints the build plan as a table with summary of Configs to build.
+func (plan *buildPlan) printTable(writer io.Writer) error {
+	fmt.Fprintf(writer, "Build plan for %s image (%s)\n", plan.ImageName, plan.PlatformString)
+	tableWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tableWriter, "ORDER\tNAME\tBUILD TYPE\tACTION\tREASON")
+	buildCount := 0
+	for _, item := range plan.Items {
+		if item.Action == planActionBuild {
+			buildCount++
+		}
+		fmt.Fprintf(tableWriter, "%d\t%s\t%s\t%s\t%s\n", item.Order, item.Name, item.BuildType, item.Action, item.Reason)
+	}
+	err := tableWriter.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "%d to build, %d to skip\n", buildCount, len(plan.Items) - buildCount)
+	return err
+}
+
+// getPlatformString
+// Returns platform string for the build. In dry run the platform string cached by previous dry run
+// for the image is used, so no container is started. If it is not cached yet, it is determined by
+// running the image without any mounted directories and cached for later dry runs. Outside of dry
+// run the platform string is always determined and the cache is not touched. The container is run
+// with Executor of executorType.
+func getPlatformString(dockerImageName string, dockerPort uint16, dryRun bool, executorType string) (*bacpack_package.PlatformString, error) {
+	if !dryRun {
+		return determinePlatformString(dockerImageName, dockerPort, executorType)
+	}
+
+	platformString, err := sysroot.LoadPlatformString(dockerImageName)
+	if err != nil {
+		return nil, err
+	}
+	if platformString != nil {
+		return platformString, nil
+	}
+	log.GetLogger().Info("Platform string of %s image is not cached, determining it by running the image", dockerImageName)
+	platformString, err = determinePlatformString(dockerImageName, dockerPort, executorType)
+	if err != nil {
+		return nil, err
+	}
+	err = sysroot.SavePlatformString(dockerImageName, platformString)
+	if err != nil {
+		return nil, err
+	}
+	return platformString, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/config"
+	"fmt"
+	"strconv"
+)
+
+// buildScheduler
+// Schedules builds of topologically sorted Configs on concurrent jobs. The Config build is started
+// only if all its dependencies present in the Configs list are already built. Each job has its own
+// port, so each job runs its own docker container.
+type buildScheduler struct {
+	// Configs topologically sorted Configs to build
+	Configs []config.Config
+	// Jobs maximum number of concurrently running builds
+	Jobs int
+	// BasePort port for the first job, other jobs use following ports
+	BasePort uint16
+}
+
+// buildResult
+// Result of one Config build.
+type buildResult struct {
+	index int
+	port  uint16
+	err   error
+}
+
+// Run
+// Runs buildFunc for all Configs. The buildFunc is called with a port which is not used by any other
+// running build. When any build fails, no other builds are started, the running builds are waited
+// for and the first error is returned.
+func (scheduler *buildScheduler) Run(buildFunc func(cfg config.Config, port uint16) error) error {
+	if scheduler.Jobs < 1 {
+		return fmt.Errorf("number of jobs must be at least 1")
+	}
+
+	configsInPlan := make(map[string]bool)
+	for _, cfg := range scheduler.Configs {
+		configsInPlan[getConfigKey(cfg.Package.Name, cfg.Package.IsDebug)] = true
+	}
+
+	var freePorts []uint16
+	for i := scheduler.Jobs - 1; i >= 0; i-- {
+		freePorts = append(freePorts, scheduler.BasePort + uint16(i))
+	}
+
+	builtConfigs := make(map[string]bool)
+	started := make([]bool, len(scheduler.Configs))
+	results := make(chan buildResult)
+	remaining := len(scheduler.Configs)
+	running := 0
+	var firstErr error
+
+	for remaining > 0 {
+		if firstErr == nil {
+			for i, cfg := range scheduler.Configs {
+				if len(freePorts) == 0 {
+					break
+				}
+				if started[i] || !isConfigReady(cfg, configsInPlan, builtConfigs) {
+					continue
+				}
+				started[i] = true
+				port := freePorts[len(freePorts) - 1]
+				freePorts = freePorts[:len(freePorts) - 1]
+				running++
+				go func(index int, cfg config.Config, port uint16) {
+					results <- buildResult{index: index, port: port, err: buildFunc(cfg, port)}
+				}(i, cfg, port)
+			}
+		}
+		if running == 0 {
+			if firstErr != nil {
+				break
+			}
+			return fmt.Errorf("no Package can be scheduled for build, dependencies can't be satisfied")
+		}
+
+		result := <-results
+		running--
+		remaining--
+		freePorts = append(freePorts, result.port)
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			continue
+		}
+		cfg := scheduler.Configs[result.index]
+		builtConfigs[getConfigKey(cfg.Package.Name, cfg.Package.IsDebug)] = true
+	}
+
+	return firstErr
+}
+
+// isConfigReady
+// Returns true if all dependencies of cfg, which are in the build plan, are already built.
+func isConfigReady(cfg config.Config, configsInPlan map[string]bool, builtConfigs map[string]bool) bool {
+	for _, dep := range cfg.DependsOn {
+		depKey := getConfigKey(dep, cfg.Package.IsDebug)
+		if configsInPlan[depKey] && !builtConfigs[depKey] {
+			return false
+		}
+	}
+	return true
+}
+
+// getConfigKey
+// Returns key which identifies the Config in build plan.
+func getConfigKey(packageName string, isDebug bool) string {
+	return packageName + ":" + strconv.FormatBool(isDebug)
+}
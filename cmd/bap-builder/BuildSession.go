@@ -0,0 +1,130 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/build_session"
+	"github.com/bacpack-system/packager/internal/config"
+	"github.com/bacpack-system/packager/internal/constants"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"github.com/bacpack-system/packager/internal/sysroot"
+	"slices"
+)
+
+// prepareBuildSession
+// Loads build session for the image, platform string and target. If resume is false, the previous
+// session of the same target is discarded, so all its Packages are built again. Sessions of other
+// targets are kept.
+func prepareBuildSession(
+	imageName      string,
+	platformString *bacpack_package.PlatformString,
+	target         string,
+	resume         bool,
+) (*build_session.BuildSession, error) {
+	session := build_session.BuildSession{
+		ImageName:      imageName,
+		PlatformString: platformString.Serialize(),
+		Target:         target,
+	}
+	err := prerequisites.Initialize(&session)
+	if err != nil {
+		return nil, err
+	}
+	if !resume {
+		err = session.Reset()
+		if err != nil {
+			return nil, err
+		}
+	} else if session.IsEmpty() {
+		logger := log.GetLogger()
+		logger.Warn("No build session to resume for %s image and %s target - no Package is skipped", imageName, target)
+	}
+	return &session, nil
+}
+
+// getSessionTarget
+// Returns build session target of the build-package command, so --resume continues only the
+// build with the same Packages selection.
+func getSessionTarget(cmdLine *BuildPackageCmdLineArgs) string {
+	if *cmdLine.All {
+		return "all"
+	}
+	target := *cmdLine.Name
+	if *cmdLine.BuildDeps {
+		target += " build-deps"
+	}
+	if *cmdLine.BuildDepsOn {
+		target += " build-deps-on"
+	}
+	if *cmdLine.BuildDepsOnRecursive {
+		target += " build-deps-on-recursive"
+	}
+	return target
+}
+
+// getUnfinishedConfigs
+// Returns Configs which are not completed in the session. A completed Config is built again if it
+// is not present in sysroot anymore (Configs not supported by the image are never in sysroot).
+func getUnfinishedConfigs(
+	configList     []config.Config,
+	session        *build_session.BuildSession,
+	platformString *bacpack_package.PlatformString,
+) ([]config.Config, error) {
+	sysrt := sysroot.Sysroot{
+		IsDebug:        false,
+		PlatformString: platformString,
+	}
+	err := prerequisites.Initialize(&sysrt)
+	if err != nil {
+		return []config.Config{}, err
+	}
+
+	var unfinishedConfigs []config.Config
+	for _, cfg := range configList {
+		if session.IsCompleted(cfg.Package.Name, cfg.Package.IsDebug) {
+			sysrt.IsDebug = cfg.Package.IsDebug
+			builtPackage := sysroot.BuiltPackage{
+				Name:          cfg.Package.GetShortPackageName(),
+				DirName:       sysrt.GetDirNameInSysroot(),
+				GitUri:        cfg.Git.URI,
+				GitCommitHash: constants.EmptyGitCommitHash,
+			}
+			if !slices.Contains(cfg.DockerMatrix.ImageNames, session.ImageName) || sysrt.IsPackageInSysroot(builtPackage) {
+				continue
+			}
+		}
+		unfinishedConfigs = append(unfinishedConfigs, cfg)
+	}
+	skippedCount := len(configList) - len(unfinishedConfigs)
+	if skippedCount > 0 {
+		logger := log.GetLogger()
+		logger.Info("Resuming build session - skipping %d completed Packages", skippedCount)
+	}
+	return unfinishedConfigs, nil
+}
+
+// runSessionBuilds
+// Runs the scheduler and records completed and failed Configs to the session. When all builds
+// succeed, the session is reset.
+func runSessionBuilds(
+	scheduler *buildScheduler,
+	session   *build_session.BuildSession,
+	buildFunc func(cfg config.Config, port uint16) error,
+) error {
+	err := scheduler.Run(func(cfg config.Config, port uint16) error {
+		err := buildFunc(cfg, port)
+		if err != nil {
+			sessionErr := session.MarkFailed(cfg.Package.Name, cfg.Package.IsDebug)
+			if sessionErr != nil {
+				logger := log.GetLogger()
+				logger.Warn("Can't save build session - %s", sessionErr)
+			}
+			return err
+		}
+		return session.MarkCompleted(cfg.Package.Name, cfg.Package.IsDebug)
+	})
+	if err != nil {
+		return err
+	}
+	return session.Reset()
+}
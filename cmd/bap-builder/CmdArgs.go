@@ -2,10 +2,24 @@ package main
 
 import (
 	"github.com/bacpack-system/packager/internal/constants"
+	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/executor"
+	"github.com/bacpack-system/packager/internal/graph"
+	"github.com/bacpack-system/packager/internal/repository"
 	"fmt"
+	"os"
 	"github.com/akamensky/argparse"
 )
 
+const (
+	// Environment variable with default container runtime
+	containerRuntimeEnv = "BAP_CONTAINER_RUNTIME"
+
+	// Values of --keep-superseded option, if not set the Package Repository config is used
+	keepSupersededTrue  = "true"
+	keepSupersededFalse = "false"
+)
+
 // BuildImageCmdLineArgs
 // Options/setting for Docker mode
 type BuildImageCmdLineArgs struct {
@@ -13,6 +27,10 @@ type BuildImageCmdLineArgs struct {
 	All *bool
 	// Name of the image to build
 	Name *string
+	// WithDeps builds the image with all Context images it is built from
+	WithDeps *bool
+	// OnlyStale builds only images which definition has changed since they were built
+	OnlyStale *bool
 }
 
 // BuildPackageCmdLineArgs
@@ -33,10 +51,41 @@ type BuildPackageCmdLineArgs struct {
 	// If not empty, only Packages which contains DockerImageName in DockerMatrix will be built.
 	// If not empty, Packages are built only by toolchain represented by DockerImageName
 	DockerImageName *string
-	// OutputDir relative (to program working dir) ot absolute path where the Package will be stored
+	// OutputDir relative (to program working dir) ot absolute path where the Package will be stored,
+	// <bucket>[/<prefix>] for s3 RepositoryType
 	OutputDir *string
-	// Port for Docker container
+	// RepositoryType type of the Package Repository (git-lfs, dir, s3)
+	RepositoryType *string
+	// Port for Docker container. When more jobs are used, each job uses next port after Port.
 	Port *int
+	// Jobs maximum number of Packages built concurrently
+	Jobs *int
+	// Resume skips Packages completed in previous (interrupted or failed) build session
+	Resume *bool
+	// CacheDir directory of the build cache, if empty the local build cache is not used
+	CacheDir *string
+	// RemoteCache URL of the remote build cache, if empty the remote build cache is not used
+	RemoteCache *string
+	// DryRun only prints the build plan, nothing is built
+	DryRun *bool
+	// PlanFormat format of the build plan printed in dry run (table, json)
+	PlanFormat *string
+	// Executor which runs commands in docker container, if empty Executor from Image config is used
+	Executor *string
+	// Offline clones the repositories on the host and runs the containers without network access
+	Offline *bool
+	// GitMirrorDir directory of the git mirror cache, if empty the git mirrors are not used (offline
+	// build uses the cache in the user cache directory)
+	GitMirrorDir *string
+	// StaleImage how the image with changed definition is handled (warn, fail, ignore)
+	StaleImage *string
+	// KeepFailed keeps the container of the failed build running
+	KeepFailed *bool
+	// KeepSuperseded older versions of Packages/Apps in Context are not consistency errors (true,
+	// false), if empty the Package Repository config is used
+	KeepSuperseded *string
+	// SingleCommit commits all built Packages/Apps at once at the end of the build
+	SingleCommit *bool
 }
 
 // BuildAppCmdLineArgs
@@ -51,25 +100,139 @@ type BuildAppCmdLineArgs struct {
 	// If not empty, only Apps which contains DockerImageName in DockerMatrix will be built.
 	// If not empty, Apps are built only by toolchain represented by DockerImageName
 	DockerImageName *string
-	// OutputDir relative (to program working dir) ot absolute path where the App will be stored
+	// OutputDir relative (to program working dir) ot absolute path where the App will be stored,
+	// <bucket>[/<prefix>] for s3 RepositoryType
 	OutputDir *string
+	// RepositoryType type of the Package Repository (git-lfs, dir, s3)
+	RepositoryType *string
 	// Port for Docker container
 	Port *int
 	// Use local Package Repository inside docker container
 	UseLocalRepo *bool
+	// DryRun only prints the build plan, nothing is built
+	DryRun *bool
+	// PlanFormat format of the build plan printed in dry run (table, json)
+	PlanFormat *string
+	// Executor which runs commands in docker container, if empty Executor from Image config is used
+	Executor *string
+	// Offline clones the repositories on the host and runs the containers without network access
+	Offline *bool
+	// GitMirrorDir directory of the git mirror cache, if empty the git mirrors are not used (offline
+	// build uses the cache in the user cache directory)
+	GitMirrorDir *string
+	// StaleImage how the image with changed definition is handled (warn, fail, ignore)
+	StaleImage *string
+	// KeepFailed keeps the container of the failed build running
+	KeepFailed *bool
+	// KeepSuperseded older versions of Packages/Apps in Context are not consistency errors (true,
+	// false), if empty the Package Repository config is used
+	KeepSuperseded *string
+	// SingleCommit commits all built Packages/Apps at once at the end of the build
+	SingleCommit *bool
 }
 
 // CreateSysrootCmdLineArgs
 // Options/setting for Sysroot mode
 type CreateSysrootCmdLineArgs struct {
-	// Path to the Git Lfs repository with Packages
+	// Path to the Package Repository with Packages, <bucket>[/<prefix>] for s3 RepositoryType
 	Repo *string
+	// RepositoryType type of the Package Repository (git-lfs, dir, s3)
+	RepositoryType *string
 	// Name of the new sysroot directory to be created
 	Sysroot *string
 	// Name of the docker image which are the Packages build for
 	ImageName *string
 	// Port for Docker container
 	Port *int
+	// Executor which runs commands in docker container, if empty Executor from Image config is used
+	Executor *string
+	// KeepSuperseded older versions of Packages/Apps in Context are not consistency errors (true,
+	// false), if empty the Package Repository config is used
+	KeepSuperseded *string
+}
+
+// GraphCmdLineArgs
+// Options/setting for Graph mode
+type GraphCmdLineArgs struct {
+	// Name of the Package which limits the graph. If empty, all Packages are in the graph
+	Name *string
+	// Reverse limits the graph to Packages which depends on Package (Name) instead of its dependencies
+	Reverse *bool
+	// Format of the graph output (dot, json, mermaid)
+	Format *string
+	// OutputFile path to the output file. If empty, the graph is written to stdout
+	OutputFile *string
+	// ShowBuildTypes marks debug/release Configs on each node
+	ShowBuildTypes *bool
+	// ShowImages marks supported images (DockerMatrix) on each node
+	ShowImages *bool
+}
+
+// VerifyRepoCmdLineArgs
+// Options/setting for Verify repository mode
+type VerifyRepoCmdLineArgs struct {
+	// Path to the Package Repository with Packages, <bucket>[/<prefix>] for s3 RepositoryType
+	Repo *string
+	// RepositoryType type of the Package Repository (git-lfs, dir, s3)
+	RepositoryType *string
+	// Format of the report (table, json)
+	Format *string
+	// KeepSuperseded older versions of Packages/Apps in Context are not consistency errors (true,
+	// false), if empty the Package Repository config is used
+	KeepSuperseded *string
+}
+
+// ShellCmdLineArgs
+// Options/setting for Shell mode
+type ShellCmdLineArgs struct {
+	// Name of the docker image to run
+	ImageName *string
+	// Name of the Package which is cloned in the container. If empty, nothing is cloned
+	Package *string
+	// Debug uses debug Config of the Package and debug sysroot
+	Debug *bool
+	// Port for Docker container
+	Port *int
+	// Executor which runs commands in docker container, if empty Executor from Image config is used
+	Executor *string
+}
+
+// MirrorCmdLineArgs
+// Options/setting for Mirror mode
+type MirrorCmdLineArgs struct {
+	// GitMirrorDir directory of the git mirror cache
+	GitMirrorDir *string
+	// If true, the mirrors are listed
+	List bool
+	// If true, the mirrors of repositories not used by the Context are removed
+	Prune bool
+	// PruneDryRun only prints the mirrors which would be removed by prune
+	PruneDryRun *bool
+	// If true, the mirrors of all repositories used by the Context are created or updated
+	Refresh bool
+}
+
+// RepoCmdLineArgs
+// Options/setting for Repository mode
+type RepoCmdLineArgs struct {
+	// Path to the Package Repository with Packages, <bucket>[/<prefix>] for s3 RepositoryType
+	Repo *string
+	// RepositoryType type of the Package Repository (git-lfs, dir, s3)
+	RepositoryType *string
+	// If true, the archives not in the Context are removed
+	Prune bool
+	// PruneDryRun only prints the files which would be removed by prune
+	PruneDryRun *bool
+	// PruneKeep number of newest removable versions kept for each Package/App
+	PruneKeep *int
+	// PruneImageName limits prune to platform of the image, if empty all platforms are pruned
+	PruneImageName *string
+	// PrunePort for Docker container
+	PrunePort *int
+	// PruneExecutor which runs commands in docker container, if empty Executor from Image config is used
+	PruneExecutor *string
+	// If true, Git LFS is set up in the Package Repository
+	SetupLFS bool
 }
 
 // CmdLineArgs
@@ -78,10 +241,19 @@ type CreateSysrootCmdLineArgs struct {
 // - build Docker images (Docker mode),
 // - build package (package mode)
 // - create sysroot (Sysroot mode)
+// - create dependency graph (Graph mode)
+// - verify Package Repository (Verify repository mode)
+// - manage git mirrors (Mirror mode)
+// - manage Package Repository (Repository mode)
+// - run interactive shell in the image (Shell mode)
 // Exactly one of these modes can be active in a time.
 type CmdLineArgs struct {
 	// Absolute/relative path to config directory
 	Context *string
+	// ContainerRuntime which runs containers and builds images (docker, podman)
+	ContainerRuntime *string
+	// SSHPasswordAuth enables password authentication to containers as a fallback of key authentication
+	SSHPasswordAuth *bool
 	// If true the program is in the "Docker" mode
 	BuildImage bool
 	// Standard Cmd line arguments for Docker mode
@@ -92,13 +264,38 @@ type CmdLineArgs struct {
 	BuildApp        bool
 	// If true the program is in the "Sysroot" mode
 	CreateSysroot       bool
+	// If true the program is in the "Graph" mode
+	Graph               bool
+	// If true the program is in the "Verify repository" mode
+	VerifyRepo          bool
+	// If true the program is in the "Mirror" mode
+	Mirror              bool
+	// If true the program is in the "Repository" mode
+	Repo                bool
+	// If true the program is in the "Shell" mode
+	Shell               bool
 	BuildPackageArgs    BuildPackageCmdLineArgs
 	BuildAppArgs        BuildAppCmdLineArgs
 	CreateSysrootArgs   CreateSysrootCmdLineArgs
+	GraphArgs           GraphCmdLineArgs
+	VerifyRepoArgs      VerifyRepoCmdLineArgs
+	MirrorArgs          MirrorCmdLineArgs
+	RepoArgs            RepoCmdLineArgs
+	ShellArgs           ShellCmdLineArgs
 	buildImageParser    *argparse.Command
 	buildPackageParser  *argparse.Command
 	buildAppParser      *argparse.Command
 	createSysrootParser *argparse.Command
+	graphParser         *argparse.Command
+	verifyRepoParser    *argparse.Command
+	mirrorParser        *argparse.Command
+	mirrorListParser    *argparse.Command
+	mirrorPruneParser   *argparse.Command
+	mirrorRefreshParser *argparse.Command
+	repoParser          *argparse.Command
+	repoPruneParser     *argparse.Command
+	repoSetupLFSParser  *argparse.Command
+	shellParser         *argparse.Command
 	parser              *argparse.Parser
 }
 
@@ -113,6 +310,23 @@ func (cmd *CmdLineArgs) InitFlags() {
 			Help:     "Context directory where are the json definition of Packages",
 		},
 	)
+	cmd.ContainerRuntime = cmd.parser.Selector("", "container-runtime",
+		docker.Runtimes(),
+		&argparse.Options{
+			Required: false,
+			Default:  os.Getenv(containerRuntimeEnv),
+			Help:     "Container runtime which runs containers and builds images. If not set, " +
+			containerRuntimeEnv + " environment variable is used, else docker",
+		},
+	)
+	cmd.SSHPasswordAuth = cmd.parser.Flag("", "ssh-password-auth",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Try password authentication (root/1234) when the authentication by the SSH key " +
+			"generated for the run fails. Needed for images without support of root login by key",
+		},
+	)
 
 	cmd.buildPackageParser = cmd.parser.NewCommand("build-package", "Build package")
 	cmd.BuildPackageArgs.All = cmd.buildPackageParser.Flag("", "all",
@@ -129,6 +343,117 @@ func (cmd *CmdLineArgs) InitFlags() {
 			Default:  constants.DefaultSSHPort,
 		},
 	)
+	cmd.BuildPackageArgs.Jobs = cmd.buildPackageParser.Int("j", "jobs",
+		&argparse.Options{
+			Required: false,
+			Help:     "Number of Packages built concurrently. Each job uses its own docker container " +
+			"with port following the port option (port, port + 1, ...)",
+			Default:  1,
+		},
+	)
+	cmd.BuildPackageArgs.Resume = cmd.buildPackageParser.Flag("", "resume",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Resume previous interrupted or failed build for the image and the same target " +
+			"(all or name with build-deps flags). Packages completed in the previous build are " +
+			"skipped without starting a container",
+		},
+	)
+	cmd.BuildPackageArgs.CacheDir = cmd.buildPackageParser.String("", "cache-dir",
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Directory of the build cache. Install files of a Package built with the same " +
+			"inputs are restored from the cache instead of building the Package",
+		},
+	)
+	cmd.BuildPackageArgs.RemoteCache = cmd.buildPackageParser.String("", "remote-cache",
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "URL of the remote build cache on HTTP server. Install files are downloaded by GET " +
+			"and uploaded by PUT request as <url>/<cache key>.tar.gz",
+		},
+	)
+	cmd.BuildPackageArgs.Executor = cmd.buildPackageParser.Selector("", "executor",
+		executor.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Executor which runs commands in the docker container. If not set, Executor " +
+			"from image.json of the image is used, else ssh",
+		},
+	)
+	cmd.BuildPackageArgs.StaleImage = cmd.buildPackageParser.Selector("", "stale-image",
+		[]string{staleImageWarn, staleImageFail, staleImageIgnore},
+		&argparse.Options{
+			Required: false,
+			Default:  staleImageWarn,
+			Help:     "What to do when the image definition (Dockerfile and files it copies) has " +
+			"changed since the image was built",
+		},
+	)
+	cmd.BuildPackageArgs.KeepFailed = cmd.buildPackageParser.Flag("", "keep-failed",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Do not remove the container when the build fails inside it. The command to " +
+			"connect to the container is printed",
+		},
+	)
+	cmd.BuildPackageArgs.KeepSuperseded = cmd.buildPackageParser.Selector("", "keep-superseded",
+		[]string{keepSupersededTrue, keepSupersededFalse},
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Keep older versions of Packages/Apps in Context in Package Repository. They are " +
+			"reported as superseded instead of errors. Overrides KeepSuperseded of " +
+			"bap_repository.json in Package Repository",
+		},
+	)
+	cmd.BuildPackageArgs.SingleCommit = cmd.buildPackageParser.Flag("", "single-commit",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Commit all built Packages to Package Repository in one commit at the end of the " +
+			"build. If any build fails, the Package Repository is reset to the state before the " +
+			"build. Supported only by git-lfs repository-type",
+		},
+	)
+	cmd.BuildPackageArgs.GitMirrorDir = cmd.buildPackageParser.String("", "git-mirror-dir",
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Directory of the git mirror cache. Bare mirrors of the Package repositories are " +
+			"kept there and used as reference of the clone, so only new objects are downloaded. " +
+			"Offline build keeps checkouts of the repositories there, the user cache directory is used " +
+			"if not set",
+		},
+	)
+	cmd.BuildPackageArgs.Offline = cmd.buildPackageParser.Flag("", "offline",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Clone the git repositories on the host and run the build containers without " +
+			"network access. Requires docker-exec executor",
+		},
+	)
+	cmd.BuildPackageArgs.DryRun = cmd.buildPackageParser.Flag("", "dry-run",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Only print the build plan. No container is started and the output-dir is not touched",
+		},
+	)
+	cmd.BuildPackageArgs.PlanFormat = cmd.buildPackageParser.Selector("", "plan-format",
+		[]string{outputFormatTable, outputFormatJSON},
+		&argparse.Options{
+			Required: false,
+			Default:  outputFormatTable,
+			Help:     "Format of the build plan printed with dry-run flag",
+		},
+	)
 	cmd.BuildPackageArgs.Name = cmd.buildPackageParser.String("", "name",
 		&argparse.Options{
 			Required: false,
@@ -162,7 +487,17 @@ func (cmd *CmdLineArgs) InitFlags() {
 	cmd.BuildPackageArgs.OutputDir = cmd.buildPackageParser.String("", "output-dir",
 		&argparse.Options{
 			Required: true,
-			Help:     "Directory where to store built Package",
+			Help:     "Directory where to store built Package, <bucket>[/<prefix>] for s3 " +
+			"repository-type",
+		},
+	)
+	cmd.BuildPackageArgs.RepositoryType = cmd.buildPackageParser.Selector("", "repository-type",
+		repository.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  repository.TypeGitLFS,
+			Help:     "Type of the Package Repository. The S3 endpoint and credentials are read from " +
+			"AWS_ENDPOINT_URL, AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
 		},
 	)
 	cmd.BuildPackageArgs.DockerImageName = cmd.buildPackageParser.String("", "image-name",
@@ -193,7 +528,17 @@ func (cmd *CmdLineArgs) InitFlags() {
 	cmd.BuildAppArgs.OutputDir = cmd.buildAppParser.String("", "output-dir",
 		&argparse.Options{
 			Required: true,
-			Help:     "Directory where to store built Apps",
+			Help:     "Directory where to store built Apps, <bucket>[/<prefix>] for s3 " +
+			"repository-type",
+		},
+	)
+	cmd.BuildAppArgs.RepositoryType = cmd.buildAppParser.Selector("", "repository-type",
+		repository.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  repository.TypeGitLFS,
+			Help:     "Type of the Package Repository. The S3 endpoint and credentials are read from " +
+			"AWS_ENDPOINT_URL, AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
 		},
 	)
 	cmd.BuildAppArgs.DockerImageName = cmd.buildAppParser.String("", "image-name",
@@ -221,6 +566,84 @@ func (cmd *CmdLineArgs) InitFlags() {
 			Default:  constants.DefaultSSHPort,
 		},
 	)
+	cmd.BuildAppArgs.Executor = cmd.buildAppParser.Selector("", "executor",
+		executor.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Executor which runs commands in the docker container. If not set, Executor " +
+			"from image.json of the image is used, else ssh",
+		},
+	)
+	cmd.BuildAppArgs.StaleImage = cmd.buildAppParser.Selector("", "stale-image",
+		[]string{staleImageWarn, staleImageFail, staleImageIgnore},
+		&argparse.Options{
+			Required: false,
+			Default:  staleImageWarn,
+			Help:     "What to do when the image definition (Dockerfile and files it copies) has " +
+			"changed since the image was built",
+		},
+	)
+	cmd.BuildAppArgs.KeepFailed = cmd.buildAppParser.Flag("", "keep-failed",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Do not remove the container when the build fails inside it. The command to " +
+			"connect to the container is printed",
+		},
+	)
+	cmd.BuildAppArgs.KeepSuperseded = cmd.buildAppParser.Selector("", "keep-superseded",
+		[]string{keepSupersededTrue, keepSupersededFalse},
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Keep older versions of Packages/Apps in Context in Package Repository. They are " +
+			"reported as superseded instead of errors. Overrides KeepSuperseded of " +
+			"bap_repository.json in Package Repository",
+		},
+	)
+	cmd.BuildAppArgs.SingleCommit = cmd.buildAppParser.Flag("", "single-commit",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Commit all built Apps to Package Repository in one commit at the end of the " +
+			"build. If any build fails, the Package Repository is reset to the state before the " +
+			"build. Supported only by git-lfs repository-type",
+		},
+	)
+	cmd.BuildAppArgs.GitMirrorDir = cmd.buildAppParser.String("", "git-mirror-dir",
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Directory of the git mirror cache. Bare mirrors of the Package repositories are " +
+			"kept there and used as reference of the clone, so only new objects are downloaded. " +
+			"Offline build keeps checkouts of the repositories there, the user cache directory is used " +
+			"if not set",
+		},
+	)
+	cmd.BuildAppArgs.Offline = cmd.buildAppParser.Flag("", "offline",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Clone the git repositories on the host and run the build containers without " +
+			"network access. Requires docker-exec executor",
+		},
+	)
+	cmd.BuildAppArgs.DryRun = cmd.buildAppParser.Flag("", "dry-run",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Only print the build plan. No container is started and the output-dir is not touched",
+		},
+	)
+	cmd.BuildAppArgs.PlanFormat = cmd.buildAppParser.Selector("", "plan-format",
+		[]string{outputFormatTable, outputFormatJSON},
+		&argparse.Options{
+			Required: false,
+			Default:  outputFormatTable,
+			Help:     "Format of the build plan printed with dry-run flag",
+		},
+	)
 
 	cmd.buildImageParser = cmd.parser.NewCommand("build-image", "Build Docker image")
 	cmd.BuildImagesArgs.All = cmd.buildImageParser.Flag("", "all",
@@ -237,6 +660,22 @@ func (cmd *CmdLineArgs) InitFlags() {
 			Help:     "Name of the docker image to build",
 		},
 	)
+	cmd.BuildImagesArgs.WithDeps = cmd.buildImageParser.Flag("", "with-deps",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Build the image with all images of the context it is built from (referenced by " +
+			"FROM instruction of the Dockerfile) in the correct order",
+		},
+	)
+	cmd.BuildImagesArgs.OnlyStale = cmd.buildImageParser.Flag("", "only-stale",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Build only images which do not exist or which definition (Dockerfile and files " +
+			"it copies) has changed since they were built",
+		},
+	)
 
 	cmd.createSysrootParser = cmd.parser.NewCommand("create-sysroot", "Create Sysroot")
 	cmd.CreateSysrootArgs.Sysroot = cmd.createSysrootParser.String("", "sysroot-dir",
@@ -248,7 +687,17 @@ func (cmd *CmdLineArgs) InitFlags() {
 	cmd.CreateSysrootArgs.Repo = cmd.createSysrootParser.String("", "git-lfs",
 		&argparse.Options{
 			Required: true,
-			Help:     "Git Lfs directory where Packages are stored",
+			Help:     "Package Repository where Packages are stored, <bucket>[/<prefix>] for s3 " +
+			"repository-type",
+		},
+	)
+	cmd.CreateSysrootArgs.RepositoryType = cmd.createSysrootParser.Selector("", "repository-type",
+		repository.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  repository.TypeGitLFS,
+			Help:     "Type of the Package Repository. The S3 endpoint and credentials are read from " +
+			"AWS_ENDPOINT_URL, AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
 		},
 	)
 	cmd.CreateSysrootArgs.ImageName = cmd.createSysrootParser.String("", "image-name",
@@ -265,6 +714,225 @@ func (cmd *CmdLineArgs) InitFlags() {
 			Default:  constants.DefaultSSHPort,
 		},
 	)
+	cmd.CreateSysrootArgs.KeepSuperseded = cmd.createSysrootParser.Selector("", "keep-superseded",
+		[]string{keepSupersededTrue, keepSupersededFalse},
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Keep older versions of Packages/Apps in Context in Package Repository. They are " +
+			"reported as superseded instead of errors. Overrides KeepSuperseded of " +
+			"bap_repository.json in Package Repository",
+		},
+	)
+	cmd.CreateSysrootArgs.Executor = cmd.createSysrootParser.Selector("", "executor",
+		executor.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Executor which runs commands in the docker container. If not set, Executor " +
+			"from image.json of the image is used, else ssh",
+		},
+	)
+
+	cmd.graphParser = cmd.parser.NewCommand("graph", "Create Package dependency graph")
+	cmd.GraphArgs.Name = cmd.graphParser.String("", "name",
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Name of the Package. Only the Package with its dependencies is in the graph",
+		},
+	)
+	cmd.GraphArgs.Reverse = cmd.graphParser.Flag("", "reverse",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Used with name option. Only the Package and Packages which depends on it " +
+			"recursively (with their dependencies) are in the graph",
+		},
+	)
+	cmd.GraphArgs.Format = cmd.graphParser.Selector("", "format",
+		[]string{graph.FormatDOT, graph.FormatJSON, graph.FormatMermaid},
+		&argparse.Options{
+			Required: false,
+			Default:  graph.FormatDOT,
+			Help:     "Output format of the graph",
+		},
+	)
+	cmd.GraphArgs.OutputFile = cmd.graphParser.String("", "output",
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "File where the graph will be written. If not set, stdout is used",
+		},
+	)
+	cmd.GraphArgs.ShowBuildTypes = cmd.graphParser.Flag("", "show-build-types",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Mark debug/release Configs on each Package",
+		},
+	)
+	cmd.GraphArgs.ShowImages = cmd.graphParser.Flag("", "show-images",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Mark supported images (DockerMatrix) on each Package",
+		},
+	)
+
+	cmd.verifyRepoParser = cmd.parser.NewCommand("verify-repo", "Verify archives in Package Repository")
+	cmd.VerifyRepoArgs.Repo = cmd.verifyRepoParser.String("", "git-lfs",
+		&argparse.Options{
+			Required: true,
+			Help:     "Package Repository where Packages are stored, <bucket>[/<prefix>] for s3 " +
+			"repository-type",
+		},
+	)
+	cmd.VerifyRepoArgs.RepositoryType = cmd.verifyRepoParser.Selector("", "repository-type",
+		repository.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  repository.TypeGitLFS,
+			Help:     "Type of the Package Repository. The S3 endpoint and credentials are read from " +
+			"AWS_ENDPOINT_URL, AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+		},
+	)
+	cmd.VerifyRepoArgs.KeepSuperseded = cmd.verifyRepoParser.Selector("", "keep-superseded",
+		[]string{keepSupersededTrue, keepSupersededFalse},
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Keep older versions of Packages/Apps in Context in Package Repository. They are " +
+			"reported as superseded instead of errors. Overrides KeepSuperseded of " +
+			"bap_repository.json in Package Repository",
+		},
+	)
+	cmd.VerifyRepoArgs.Format = cmd.verifyRepoParser.Selector("", "format",
+		[]string{outputFormatTable, outputFormatJSON},
+		&argparse.Options{
+			Required: false,
+			Default:  outputFormatTable,
+			Help:     "Format of the verification report",
+		},
+	)
+
+	cmd.mirrorParser = cmd.parser.NewCommand("mirror", "Manage git mirror cache")
+	cmd.MirrorArgs.GitMirrorDir = cmd.mirrorParser.String("", "git-mirror-dir",
+		&argparse.Options{
+			Required: true,
+			Validate: checkForEmpty,
+			Help:     "Directory of the git mirror cache",
+		},
+	)
+	cmd.mirrorListParser = cmd.mirrorParser.NewCommand("list", "List git mirrors")
+	cmd.mirrorPruneParser = cmd.mirrorParser.NewCommand("prune", "Remove git mirrors of repositories not used by the context")
+	cmd.MirrorArgs.PruneDryRun = cmd.mirrorPruneParser.Flag("", "dry-run",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Only print the git mirrors which would be removed",
+		},
+	)
+	cmd.mirrorRefreshParser = cmd.mirrorParser.NewCommand("refresh", "Create or update git mirrors of all repositories used by the context")
+
+	cmd.repoParser = cmd.parser.NewCommand("repo", "Manage Package Repository")
+	cmd.RepoArgs.Repo = cmd.repoParser.String("", "git-lfs",
+		&argparse.Options{
+			Required: true,
+			Help:     "Package Repository where Packages are stored, <bucket>[/<prefix>] for s3 " +
+			"repository-type",
+		},
+	)
+	cmd.RepoArgs.RepositoryType = cmd.repoParser.Selector("", "repository-type",
+		repository.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  repository.TypeGitLFS,
+			Help:     "Type of the Package Repository. The S3 endpoint and credentials are read from " +
+			"AWS_ENDPOINT_URL, AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+		},
+	)
+	cmd.repoPruneParser = cmd.repoParser.NewCommand("prune", "Remove archives of Packages/Apps and versions which are not in the context")
+	cmd.RepoArgs.PruneDryRun = cmd.repoPruneParser.Flag("", "dry-run",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Only print the files which would be removed",
+		},
+	)
+	cmd.RepoArgs.PruneKeep = cmd.repoPruneParser.Int("", "keep",
+		&argparse.Options{
+			Required: false,
+			Default:  0,
+			Help:     "Number of newest versions (by VersionTag) which are kept for each Package/App " +
+			"although they are not in the context",
+		},
+	)
+	cmd.RepoArgs.PruneImageName = cmd.repoPruneParser.String("", "image-name",
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Name of docker image which platform string is pruned. If not set, all " +
+			"platforms are pruned",
+		},
+	)
+	cmd.RepoArgs.PrunePort = cmd.repoPruneParser.Int("p", "port",
+		&argparse.Options{
+			Required: false,
+			Help:     "Host port for docker container ssh bind",
+			Default:  constants.DefaultSSHPort,
+		},
+	)
+	cmd.RepoArgs.PruneExecutor = cmd.repoPruneParser.Selector("", "executor",
+		executor.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Executor which runs commands in the docker container. If not set, Executor " +
+			"from image.json of the image is used, else ssh",
+		},
+	)
+	cmd.repoSetupLFSParser = cmd.repoParser.NewCommand("setup-lfs", "Install Git LFS to the git-lfs Package Repository and track archives by it")
+
+	cmd.shellParser = cmd.parser.NewCommand("shell", "Run interactive shell in the image prepared for build")
+	cmd.ShellArgs.ImageName = cmd.shellParser.String("", "image-name",
+		&argparse.Options{
+			Required: true,
+			Validate: checkForEmpty,
+			Help:     "Name of docker image to run",
+		},
+	)
+	cmd.ShellArgs.Package = cmd.shellParser.String("", "package",
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Name of the Package which git repository is cloned in the container. The " +
+			"environment variables of the Package Config are set in the shell",
+		},
+	)
+	cmd.ShellArgs.Debug = cmd.shellParser.Flag("", "debug",
+		&argparse.Options{
+			Required: false,
+			Default:  false,
+			Help:     "Use debug Config of the Package and debug sysroot instead of release",
+		},
+	)
+	cmd.ShellArgs.Port = cmd.shellParser.Int("p", "port",
+		&argparse.Options{
+			Required: false,
+			Help:     "Host port for docker container ssh bind",
+			Default:  constants.DefaultSSHPort,
+		},
+	)
+	cmd.ShellArgs.Executor = cmd.shellParser.Selector("", "executor",
+		executor.Types(),
+		&argparse.Options{
+			Required: false,
+			Default:  "",
+			Help:     "Executor which runs commands in the docker container. If not set, Executor " +
+			"from image.json of the image is used, else ssh",
+		},
+	)
 }
 
 // checkForEmpty
@@ -292,7 +960,26 @@ func (cmd *CmdLineArgs) ParseArgs(args []string) error {
 	cmd.BuildPackage = cmd.buildPackageParser.Happened()
 	cmd.BuildApp = cmd.buildAppParser.Happened()
 	cmd.CreateSysroot = cmd.createSysrootParser.Happened()
+	cmd.Graph = cmd.graphParser.Happened()
+	cmd.VerifyRepo = cmd.verifyRepoParser.Happened()
+	cmd.Mirror = cmd.mirrorParser.Happened()
+	cmd.MirrorArgs.List = cmd.mirrorListParser.Happened()
+	cmd.MirrorArgs.Prune = cmd.mirrorPruneParser.Happened()
+	cmd.MirrorArgs.Refresh = cmd.mirrorRefreshParser.Happened()
+	cmd.Repo = cmd.repoParser.Happened()
+	cmd.RepoArgs.Prune = cmd.repoPruneParser.Happened()
+	cmd.RepoArgs.SetupLFS = cmd.repoSetupLFSParser.Happened()
+	cmd.Shell = cmd.shellParser.Happened()
 
+	if *cmd.BuildImagesArgs.All && *cmd.BuildImagesArgs.WithDeps {
+		return fmt.Errorf("all and with-deps flags at the same time")
+	}
+	if cmd.BuildPackage && *cmd.BuildPackageArgs.Resume && *cmd.BuildPackageArgs.SingleCommit {
+		return fmt.Errorf("resume and single-commit flags at the same time")
+	}
+	if cmd.BuildPackage && *cmd.BuildPackageArgs.Jobs < 1 {
+		return fmt.Errorf("jobs must be at least 1")
+	}
 	if *cmd.BuildPackageArgs.All {
 		if *cmd.BuildPackageArgs.BuildDeps {
 			return fmt.Errorf("all and build-deps flags at the same time")
@@ -308,6 +995,15 @@ func (cmd *CmdLineArgs) ParseArgs(args []string) error {
 			return fmt.Errorf("build-deps-on and build-deps-on-recursive flags at the same time")
 		}
 	}
+	if cmd.Graph && *cmd.GraphArgs.Reverse && *cmd.GraphArgs.Name == "" {
+		return fmt.Errorf("reverse flag without name option")
+	}
+	if cmd.RepoArgs.SetupLFS && *cmd.RepoArgs.RepositoryType != repository.TypeGitLFS {
+		return fmt.Errorf("setup-lfs is supported only by %s repository-type", repository.TypeGitLFS)
+	}
+	if cmd.RepoArgs.Prune && *cmd.RepoArgs.PruneKeep < 0 {
+		return fmt.Errorf("keep must not be negative")
+	}
 
 	return nil
 }
@@ -6,9 +6,19 @@ import (
 	"github.com/bacpack-system/packager/internal/context"
 	"github.com/bacpack-system/packager/internal/packager_error"
 	"github.com/bacpack-system/packager/internal/prerequisites"
+	"fmt"
 	"path"
 )
 
+const (
+	// Stale image is reported by warning
+	staleImageWarn = "warn"
+	// Stale image is reported by error and the build is not started
+	staleImageFail = "fail"
+	// Stale images are not checked
+	staleImageIgnore = "ignore"
+)
+
 // BuildDockerImage
 // process Docker mode of cmd line
 //
@@ -22,27 +32,41 @@ func BuildDockerImage(cmdLine *BuildImageCmdLineArgs, contextPath string) error
 		logger.Error("Context consistency error - %s", err)
 		return packager_error.ContextErr
 	}
-	buildAll := cmdLine.All
-	if *buildAll {
-		return buildAllDockerImages(contextManager)
-	}
-
-	dockerfilePath, err := contextManager.GetImageDockerfilePath(*cmdLine.Name)
-	if err != nil {
-		return err
+	var imageNames []string
+	if *cmdLine.All || *cmdLine.WithDeps {
+		imageName := *cmdLine.Name
+		if *cmdLine.All {
+			imageName = ""
+		}
+		imageNames, err = contextManager.GetImagesBuildOrder(imageName)
+		if err != nil {
+			log.GetLogger().Error("Context consistency error - %s", err)
+			return packager_error.ContextErr
+		}
+	} else {
+		baseImages, err := contextManager.GetImageBaseImages(*cmdLine.Name)
+		if err != nil {
+			return err
+		}
+		for _, baseImage := range baseImages {
+			log.GetLogger().Info("Image %s is built from %s image of the Context, use --with-deps to build it too", *cmdLine.Name, baseImage)
+		}
+		imageNames = []string{*cmdLine.Name}
 	}
-	return buildSingleDockerImage(*cmdLine.Name, dockerfilePath, contextPath)
-}
-
-// buildAllDockerImages
-// builds all docker images in the given contextPath.
-// It returns nil if everything is ok, or not nil in case of error
-//
-func buildAllDockerImages(contextManager context.ContextManager) error {
-	dockerfilePathList := contextManager.GetAllImagesDockerfilePaths()
 
-	for imageName, dockerfilePath := range dockerfilePathList {
-		err := buildSingleDockerImage(imageName, dockerfilePath, contextManager.ContextPath)
+	for _, imageName := range imageNames {
+		if *cmdLine.OnlyStale {
+			stale, reason, err := getImageStaleness(&contextManager, imageName)
+			if err != nil {
+				return err
+			}
+			if !stale {
+				log.GetLogger().Info("Image %s is up to date - skipping build", imageName)
+				continue
+			}
+			log.GetLogger().Info("Image %s is stale - %s", imageName, reason)
+		}
+		err = buildSingleDockerImage(imageName, &contextManager)
 		if err != nil {
 			return err
 		}
@@ -51,21 +75,33 @@ func buildAllDockerImages(contextManager context.ContextManager) error {
 }
 
 // buildSingleDockerImage
-// builds a single docker image specified by an image name and a path to Dockerfile.
+// builds a single docker image specified by an image name. The image is labeled by hash of its
+// definition, so stale images can be detected.
 //
-func buildSingleDockerImage(imageName string, dockerfilePath string, contextPath string) error {
+func buildSingleDockerImage(imageName string, contextManager *context.ContextManager) error {
 	logger := log.GetLogger()
+	dockerfilePath, err := contextManager.GetImageDockerfilePath(imageName)
+	if err != nil {
+		return err
+	}
+	contextHash, err := contextManager.GetImageContextHash(imageName)
+	if err != nil {
+		return err
+	}
 	dockerfileDir := path.Dir(dockerfilePath)
 	dockerBuild := docker.DockerBuild{
 		DockerfileDir: dockerfileDir,
 		Tag:           imageName,
-		Context:       contextPath,
+		Context:       contextManager.ContextPath,
+		Labels:        map[string]string{
+			docker.ImageContextHashLabel: contextHash,
+		},
 	}
 	logger.Info("Build Docker Image: %s", imageName)
 
 	// Building image does not require any handler when SIGINT is received. 'docker build' creates
 	// image after all steps from Dockerfile are successfully executed.
-	err := dockerBuild.Build()
+	err = dockerBuild.Build()
 	if err != nil {
 		logger.ErrorIndent("Can't build image - %s", err)
 		return packager_error.BuildErr
@@ -73,3 +109,49 @@ func buildSingleDockerImage(imageName string, dockerfilePath string, contextPath
 	logger.InfoIndent("Build OK")
 	return nil
 }
+
+// getImageStaleness
+// Returns true if the image must be rebuilt, because its definition in the Context has changed
+// since it was built (or it was not built at all), with the reason. Else returns false.
+func getImageStaleness(contextManager *context.ContextManager, imageName string) (bool, string, error) {
+	contextHash, err := contextManager.GetImageContextHash(imageName)
+	if err != nil {
+		return false, "", err
+	}
+	dockerImage := docker.DockerImage{ImageName: imageName}
+	label, err := dockerImage.GetLabel(docker.ImageContextHashLabel)
+	if err != nil {
+		return true, fmt.Sprintf("cannot read image labels, the image may not exist (%s)", err), nil
+	}
+	if label == "" {
+		return true, "the image was built without hash of its definition", nil
+	}
+	if label != contextHash {
+		return true, "the image definition in the Context has changed since the image was built", nil
+	}
+	return false, "", nil
+}
+
+// checkImageStaleness
+// Checks if the image is stale by getImageStaleness. If stale, prints a warning or returns error
+// depending on policy (staleImageWarn, staleImageFail). With staleImageIgnore nothing is checked.
+func checkImageStaleness(contextManager *context.ContextManager, imageName string, policy string) error {
+	if policy == staleImageIgnore {
+		return nil
+	}
+	logger := log.GetLogger()
+	logger.Info("Checking if %s image is up to date", imageName)
+	stale, reason, err := getImageStaleness(contextManager, imageName)
+	if err != nil {
+		return err
+	}
+	if !stale {
+		return nil
+	}
+	if policy == staleImageFail {
+		logger.Error("Image %s is stale - %s. Rebuild it by build-image command", imageName, reason)
+		return packager_error.ContextErr
+	}
+	logger.Warn("Image %s is stale - %s. Rebuild it by build-image command", imageName, reason)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/config"
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/graph"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/packager_error"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"fmt"
+	"os"
+)
+
+// CreateDependencyGraph
+// Process Graph mode of the program. Writes Package dependency graph in requested format to the
+// output file or to the stdout.
+func CreateDependencyGraph(cmdLine *GraphCmdLineArgs, contextPath string) error {
+	contextManager := context.ContextManager{
+		ContextPath: contextPath,
+		ForPackage: true,
+	}
+	err := prerequisites.Initialize(&contextManager)
+	if err != nil {
+		logger := log.GetLogger()
+		logger.Error("Context consistency error - %s", err)
+		return packager_error.ContextErr
+	}
+
+	configs, err := getGraphConfigs(cmdLine, &contextManager)
+	if err != nil {
+		return err
+	}
+
+	dependencyGraph, err := graph.CreateGraph(configs, graph.GraphOptions{
+		ShowBuildTypes: *cmdLine.ShowBuildTypes,
+		ShowImages:     *cmdLine.ShowImages,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *cmdLine.OutputFile == "" {
+		return dependencyGraph.Write(os.Stdout, *cmdLine.Format)
+	}
+	file, err := os.Create(*cmdLine.OutputFile)
+	if err != nil {
+		return fmt.Errorf("cannot create output file - %w", err)
+	}
+	defer file.Close()
+	return dependencyGraph.Write(file, *cmdLine.Format)
+}
+
+// getGraphConfigs
+// Returns Configs which will be in the graph. If no Package name is given, all Package Configs are
+// returned. Else the Package with its dependencies or the Package with Packages which depends on it
+// (with reverse option) are returned.
+func getGraphConfigs(cmdLine *GraphCmdLineArgs, contextManager *context.ContextManager) ([]config.Config, error) {
+	packageName := *cmdLine.Name
+	if packageName == "" {
+		var configs []config.Config
+		for _, configsArray := range contextManager.GetAllConfigsMap() {
+			configs = append(configs, configsArray...)
+		}
+		return configs, nil
+	}
+	if !*cmdLine.Reverse {
+		return contextManager.GetPackageWithDepsConfigs(packageName)
+	}
+
+	packageConfigs, err := contextManager.GetPackageConfigs(packageName)
+	if err != nil {
+		return []config.Config{}, err
+	}
+	depsOnConfigs, err := contextManager.GetPackageWithDepsOnConfigs(packageName, true)
+	if err != nil {
+		return []config.Config{}, err
+	}
+	return append(packageConfigs, depsOnConfigs...), nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/build"
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/git_mirror"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/packager_error"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// Directory in the user cache directory used as git mirror cache of offline build, if no
+	// directory is set
+	defaultGitMirrorDirName = "bap-builder-git"
+)
+
+// ManageMirrors
+// Process Mirror mode of the program. Lists, prunes or refreshes git mirrors in the git mirror
+// cache directory.
+func ManageMirrors(cmdLine *MirrorCmdLineArgs, contextPath string) error {
+	mirrorCache, err := prerequisites.CreateAndInitialize[git_mirror.MirrorCache](*cmdLine.GitMirrorDir)
+	if err != nil {
+		return err
+	}
+	if cmdLine.List {
+		mirrors, err := mirrorCache.List()
+		if err != nil {
+			return err
+		}
+		return printMirrors(os.Stdout, mirrors)
+	}
+
+	contextManager := context.ContextManager{
+		ContextPath: contextPath,
+		ForPackage: true,
+	}
+	err = prerequisites.Initialize(&contextManager)
+	if err != nil {
+		logger := log.GetLogger()
+		logger.Error("Context consistency error - %s", err)
+		return packager_error.ContextErr
+	}
+	contextUris := getContextGitUris(&contextManager)
+
+	if cmdLine.Prune {
+		return pruneMirrors(mirrorCache, contextUris, *cmdLine.PruneDryRun)
+	}
+	if cmdLine.Refresh {
+		return refreshMirrors(mirrorCache, contextUris)
+	}
+	return nil
+}
+
+// getMirrorCache
+// Returns git mirror cache in mirrorDir. If mirrorDir is empty, returns nil, so the git mirrors are
+// not used. Offline build needs the cache for the checkouts of the repositories, so if offline is
+// true and mirrorDir is empty, the cache in the user cache directory is used.
+func getMirrorCache(mirrorDir string, offline bool) (*git_mirror.MirrorCache, error) {
+	if mirrorDir == "" && offline {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get user cache directory for git mirror cache - %w", err)
+		}
+		mirrorDir = filepath.Join(userCacheDir, defaultGitMirrorDirName)
+	}
+	if mirrorDir == "" {
+		return nil, nil
+	}
+	mirrorCache, err := prerequisites.CreateAndInitialize[git_mirror.MirrorCache](mirrorDir)
+	if err != nil {
+		return nil, err
+	}
+	log.GetLogger().Info("Using git mirror cache %s", mirrorDir)
+	return mirrorCache, nil
+}
+
+// setMirrorCache
+// Sets mirrorCache to all buildConfigs.
+func setMirrorCache(buildConfigs []build.Build, mirrorCache *git_mirror.MirrorCache) {
+	for i := range buildConfigs {
+		buildConfigs[i].MirrorCache = mirrorCache
+	}
+}
+
+// getContextGitUris
+// Returns sorted Git URIs of all Package and App Configs in the Context without duplicates.
+func getContextGitUris(contextManager *context.ContextManager) []string {
+	configs := append(contextManager.GetAllPackageConfigsArray(nil), contextManager.GetAllAppConfigsArray(nil)...)
+	uris := []string{}
+	for _, config := range configs {
+		if config.Git.URI != "" && !slices.Contains(uris, config.Git.URI) {
+			uris = append(uris, config.Git.URI)
+		}
+	}
+	slices.Sort(uris)
+	return uris
+}
+
+// pruneMirrors
+// Removes mirrors of repositories which are not used by any Config in the Context. If dryRun is
+// true, the mirrors are only printed.
+func pruneMirrors(mirrorCache *git_mirror.MirrorCache, contextUris []string, dryRun bool) error {
+	mirrors, err := mirrorCache.List()
+	if err != nil {
+		return err
+	}
+	logger := log.GetLogger()
+	count := 0
+	for _, mirror := range mirrors {
+		if slices.Contains(contextUris, mirror.URI) {
+			continue
+		}
+		count++
+		if dryRun {
+			logger.Info("Would remove git mirror of %s (%s)", mirror.URI, mirror.Path)
+			continue
+		}
+		logger.Info("Removing git mirror of %s (%s)", mirror.URI, mirror.Path)
+		err = mirrorCache.Remove(mirror)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Info("%d git mirrors not used by the Context", count)
+	return nil
+}
+
+// refreshMirrors
+// Creates or updates mirrors of all repositories used by the Context. All mirrors are tried, the
+// error is returned if any of them fails.
+func refreshMirrors(mirrorCache *git_mirror.MirrorCache, contextUris []string) error {
+	logger := log.GetLogger()
+	failed := 0
+	for _, uri := range contextUris {
+		logger.Info("Refreshing git mirror of %s", uri)
+		_, err := mirrorCache.Update(uri, nil)
+		if err != nil {
+			logger.Error("Cannot refresh git mirror of %s - %s", uri, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d git mirrors cannot be refreshed", failed, len(contextUris))
+	}
+	return nil
+}
+
+// printMirrors
+// Prints mirrors to writer as a table.
+func printMirrors(writer io.Writer, mirrors []git_mirror.Mirror) error {
+	tableWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tableWriter, "URI\tUPDATED\tPATH")
+	for _, mirror := range mirrors {
+		fmt.Fprintf(tableWriter, "%s\t%s\t%s\n", mirror.URI, mirror.UpdateTime.Format(time.DateTime), mirror.Path)
+	}
+	return tableWriter.Flush()
+}
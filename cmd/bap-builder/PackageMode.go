@@ -2,10 +2,14 @@ package main
 
 import (
 	"github.com/bacpack-system/packager/internal/build"
+	"github.com/bacpack-system/packager/internal/build_cache"
+	"github.com/bacpack-system/packager/internal/build_session"
 	"github.com/bacpack-system/packager/internal/config"
 	"github.com/bacpack-system/packager/internal/constants"
 	"github.com/bacpack-system/packager/internal/context"
 	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/executor"
+	"github.com/bacpack-system/packager/internal/git_mirror"
 	"github.com/bacpack-system/packager/internal/log"
 	"github.com/bacpack-system/packager/internal/bacpack_package"
 	"github.com/bacpack-system/packager/internal/prerequisites"
@@ -14,9 +18,12 @@ import (
 	"github.com/bacpack-system/packager/internal/ssh"
 	"github.com/bacpack-system/packager/internal/sysroot"
 	"github.com/bacpack-system/packager/internal/packager_error"
+	"errors"
 	"fmt"
-	"strconv"
+	"io/fs"
+	"os"
 	"slices"
+	"sync/atomic"
 )
 
 type buildDepList struct {
@@ -27,7 +34,7 @@ func removeDuplicates(configList *[]config.Config) []config.Config {
 	var newConfigList []config.Config
 	packageMap := make(map[string]bool)
 	for _, cconfig := range *configList {
-		packageName := cconfig.Package.Name + ":" + strconv.FormatBool(cconfig.Package.IsDebug)
+		packageName := getConfigKey(cconfig.Package.Name, cconfig.Package.IsDebug)
 		exist, _ := packageMap[packageName]
 		if exist {
 			continue
@@ -97,18 +104,25 @@ func (list *buildDepList) sortDependencies(rootName string, dependsMap *map[stri
 }
 
 // performPreBuildChecks
-// Performs Git lfs and sysroot consistency checks. This should be called before builds.
+// Performs image staleness, Package Repository and sysroot consistency checks. This should be
+// called before builds.
 func performPreBuildChecks(
-	repo           *repository.GitLFSRepository,
+	repo           repository.Repository,
 	contextManager *context.ContextManager,
 	platformString *bacpack_package.PlatformString,
 	imageName      string,
+	staleImage     string,
+	keepSuperseded bool,
 ) error {
+	err := checkImageStaleness(contextManager, imageName, staleImage)
+	if err != nil {
+		return err
+	}
 	logger := log.GetLogger()
-	logger.Info("Checking Git Lfs directory consistency")
-	err := repo.CheckGitLfsConsistency(contextManager, platformString, imageName)
+	logger.Info("Checking Package Repository consistency")
+	err = repo.CheckConsistency(contextManager, platformString, imageName, keepSuperseded)
 	if err != nil {
-		logger.Error("Git Lfs consistency error - %s", err)
+		logger.Error("Package Repository consistency error - %s", err)
 		return packager_error.GitLfsErr
 	}
 	logger.Info("Checking Sysroot directory consistency")
@@ -122,28 +136,33 @@ func performPreBuildChecks(
 // BuildPackage
 // process Package mode of the program
 func BuildPackage(cmdLine *BuildPackageCmdLineArgs, contextPath string) error {
-	platformString, err := determinePlatformString(*cmdLine.DockerImageName, uint16(*cmdLine.Port))
-	if err != nil {
-		return err
-	}
-	repo := repository.GitLFSRepository{
-		GitRepoPath: *cmdLine.OutputDir,
-	}
-	err = prerequisites.Initialize(&repo)
-	if err != nil {
-		return err
-	}
 	contextManager := context.ContextManager{
 		ContextPath: contextPath,
 		ForPackage: true,
 	}
-	err = prerequisites.Initialize(&contextManager)
+	err := prerequisites.Initialize(&contextManager)
 	if err != nil {
 		logger := log.GetLogger()
 		logger.Error("Context consistency error - %s", err)
 		return packager_error.ContextErr
 	}
-	err = performPreBuildChecks(&repo, &contextManager, platformString, *cmdLine.DockerImageName)
+	imageConfig := getImageConfig(*cmdLine.Executor, *cmdLine.Offline, &contextManager, *cmdLine.DockerImageName)
+	platformString, err := getPlatformString(*cmdLine.DockerImageName, uint16(*cmdLine.Port), *cmdLine.DryRun, imageConfig.Executor)
+	if err != nil {
+		return err
+	}
+	if *cmdLine.DryRun {
+		return printPackageBuildPlan(cmdLine, &contextManager, platformString)
+	}
+	session, err := prepareBuildSession(*cmdLine.DockerImageName, platformString, getSessionTarget(cmdLine), *cmdLine.Resume)
+	if err != nil {
+		return err
+	}
+	repo, err := repository.CreateRepository(*cmdLine.RepositoryType, *cmdLine.OutputDir)
+	if err != nil {
+		return err
+	}
+	err = performPreBuildChecks(repo, &contextManager, platformString, *cmdLine.DockerImageName, *cmdLine.StaleImage, getKeepSuperseded(*cmdLine.KeepSuperseded, repo))
 	if err != nil {
 		return err
 	}
@@ -151,11 +170,34 @@ func BuildPackage(cmdLine *BuildPackageCmdLineArgs, contextPath string) error {
 	handleRemover := process.SignalHandlerAddHandler(repo.RestoreAllChanges)
 	defer handleRemover()
 
+	buildCache, err := getBuildCache(*cmdLine.CacheDir, *cmdLine.RemoteCache)
+	if err != nil {
+		return err
+	}
+	mirrorCache, err := getMirrorCache(*cmdLine.GitMirrorDir, imageConfig.Offline)
+	if err != nil {
+		return err
+	}
+
+	var transaction *sessionTransaction
+	if *cmdLine.SingleCommit {
+		transaction, err = beginSessionTransaction(repo, session)
+		if err != nil {
+			return err
+		}
+		rollbackRemover := process.SignalHandlerAddHandler(transaction.Rollback)
+		defer rollbackRemover()
+	}
+
 	if *cmdLine.All {
-		return buildAllPackages(cmdLine, &contextManager, platformString, repo)
+		err = buildAllPackages(cmdLine, &contextManager, platformString, repo, session, buildCache, mirrorCache, imageConfig)
 	} else {
-		return buildSinglePackage(cmdLine, &contextManager, platformString, repo)
+		err = buildSinglePackage(cmdLine, &contextManager, platformString, repo, session, buildCache, mirrorCache, imageConfig)
+	}
+	if err != nil || transaction == nil {
+		return err
 	}
+	return transaction.Commit(*cmdLine.DockerImageName, platformString)
 }
 
 // buildAllPackages
@@ -165,36 +207,64 @@ func buildAllPackages(
 	cmdLine        *BuildPackageCmdLineArgs,
 	contextManager *context.ContextManager,
 	platformString *bacpack_package.PlatformString,
-	repo           repository.GitLFSRepository,
+	repo           repository.Repository,
+	session        *build_session.BuildSession,
+	buildCache     *build_cache.BuildCache,
+	mirrorCache    *git_mirror.MirrorCache,
+	imageConfig    config.ImageConfig,
 ) error {
-	configMap := contextManager.GetAllConfigsMap()
-
-	depsList := buildDepList{}
-	configList, err := depsList.TopologicalSort(configMap)
+	configList, err := getAllPackagesConfigs(contextManager)
 	if err != nil {
 		return err
 	}
+	if *cmdLine.Resume {
+		configList, err = getUnfinishedConfigs(configList, session, platformString)
+		if err != nil {
+			return err
+		}
+		if len(configList) == 0 {
+			log.GetLogger().Info("All Packages are already built in the resumed build session")
+			return session.Reset()
+		}
+	}
 
 	count := int32(0)
-	for _, config := range configList {
+	scheduler := buildScheduler{
+		Configs:  configList,
+		Jobs:     *cmdLine.Jobs,
+		BasePort: uint16(*cmdLine.Port),
+	}
+	err = runSessionBuilds(&scheduler, session, func(config config.Config, port uint16) error {
 		buildConfigs, err := config.GetBuildStructure(
 			*cmdLine.DockerImageName,
 			platformString,
-			uint16(*cmdLine.Port),
+			port,
 			false,
 			"",
+			imageConfig,
 		)
 		if err != nil {
 			return err
 		}
 		if len(buildConfigs) == 0 {
-			continue
+			return nil
+		}
+		atomic.AddInt32(&count, 1)
+		err = setBuildCache(buildConfigs, config, contextManager, platformString, repo, buildCache)
+		if err != nil {
+			return err
 		}
-		count++
-		err = buildAndCopyPackage(&buildConfigs, platformString, repo, constants.PackageDirName)
+		setMirrorCache(buildConfigs, mirrorCache)
+		setKeepFailed(buildConfigs, *cmdLine.KeepFailed)
+		dependencies := getManifestDependencies(config, contextManager)
+		err = buildAndCopyPackage(&buildConfigs, platformString, repo, constants.PackageDirName, dependencies)
 		if err != nil {
 			return fmt.Errorf("cannot build package '%s' - %w", config.Package.Name, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 	if count == 0 {
 		return fmt.Errorf("no Packages to build for %s image", *cmdLine.DockerImageName)
@@ -294,8 +364,72 @@ func buildSinglePackage(
 	cmdLine        *BuildPackageCmdLineArgs,
 	contextManager *context.ContextManager,
 	platformString *bacpack_package.PlatformString,
-	repo           repository.GitLFSRepository,
+	repo           repository.Repository,
+	session        *build_session.BuildSession,
+	buildCache     *build_cache.BuildCache,
+	mirrorCache    *git_mirror.MirrorCache,
+	imageConfig    config.ImageConfig,
 ) error {
+	configList, err := getSinglePackageConfigs(cmdLine, contextManager, platformString)
+	if err != nil {
+		return err
+	}
+	if *cmdLine.Resume {
+		configList, err = getUnfinishedConfigs(configList, session, platformString)
+		if err != nil {
+			return err
+		}
+	}
+	scheduler := buildScheduler{
+		Configs:  configList,
+		Jobs:     *cmdLine.Jobs,
+		BasePort: uint16(*cmdLine.Port),
+	}
+	return runSessionBuilds(&scheduler, session, func(config config.Config, port uint16) error {
+		buildConfigs, err := config.GetBuildStructure(
+			*cmdLine.DockerImageName,
+			platformString,
+			port,
+			false,
+			"",
+			imageConfig,
+		)
+		if err != nil {
+			return err
+		}
+		err = setBuildCache(buildConfigs, config, contextManager, platformString, repo, buildCache)
+		if err != nil {
+			return err
+		}
+		setMirrorCache(buildConfigs, mirrorCache)
+		setKeepFailed(buildConfigs, *cmdLine.KeepFailed)
+		dependencies := getManifestDependencies(config, contextManager)
+		err = buildAndCopyPackage(&buildConfigs, platformString, repo, constants.PackageDirName, dependencies)
+		if err != nil {
+			return fmt.Errorf("cannot build package '%s' - %w", config.Package.Name, err)
+		}
+		return nil
+	})
+}
+
+// getAllPackagesConfigs
+// Returns topologically sorted Configs of all Packages in context.
+func getAllPackagesConfigs(contextManager *context.ContextManager) ([]config.Config, error) {
+	configMap := contextManager.GetAllConfigsMap()
+
+	depsList := buildDepList{}
+	return depsList.TopologicalSort(configMap)
+}
+
+// getSinglePackageConfigs
+// Returns sorted Configs to build for single Package specified by name in cmdLine (with its
+// dependencies or Packages which depends on it, based on cmdLine flags). Returns error if any of
+// the Configs does not support the image.
+func getSinglePackageConfigs(
+	cmdLine        *BuildPackageCmdLineArgs,
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+) ([]config.Config, error) {
 	packageName := *cmdLine.Name
 	var err error
 	var configList []config.Config
@@ -306,31 +440,52 @@ func buildSinglePackage(
 		configList, err = prepareConfigsNoBuildDeps(packageName, contextManager, platformString, constants.PackageDirName)
 	}
 	if err != nil {
-		return err
+		return []config.Config{}, err
 	}
 	if len(configList) == 0 {
-		return fmt.Errorf("nothing to build")
+		return []config.Config{}, fmt.Errorf("nothing to build")
 	}
-	for _, config := range configList {
-		if !slices.Contains(config.DockerMatrix.ImageNames, *cmdLine.DockerImageName) {
-			return fmt.Errorf("'%s' does not support %s image", config.Package.Name, *cmdLine.DockerImageName)
+	for _, cfg := range configList {
+		if !slices.Contains(cfg.DockerMatrix.ImageNames, *cmdLine.DockerImageName) {
+			return []config.Config{}, fmt.Errorf("'%s' does not support %s image", cfg.Package.Name, *cmdLine.DockerImageName)
 		}
-		buildConfigs, err := config.GetBuildStructure(
-			*cmdLine.DockerImageName,
-			platformString,
-			uint16(*cmdLine.Port),
-			false,
-			"",
-		)
+	}
+	return configList, nil
+}
+
+// printPackageBuildPlan
+// Prints build plan of Packages specified by cmdLine to stdout. With resume flag, Packages
+// completed in the build session are not in the plan. Nothing is built.
+func printPackageBuildPlan(
+	cmdLine        *BuildPackageCmdLineArgs,
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+) error {
+	var configList []config.Config
+	var err error
+	if *cmdLine.All {
+		configList, err = getAllPackagesConfigs(contextManager)
+	} else {
+		configList, err = getSinglePackageConfigs(cmdLine, contextManager, platformString)
+	}
+	if err != nil {
+		return err
+	}
+	if *cmdLine.Resume {
+		session, err := prepareBuildSession(*cmdLine.DockerImageName, platformString, getSessionTarget(cmdLine), true)
 		if err != nil {
 			return err
 		}
-		err = buildAndCopyPackage(&buildConfigs, platformString, repo, constants.PackageDirName)
+		configList, err = getUnfinishedConfigs(configList, session, platformString)
 		if err != nil {
-			return fmt.Errorf("cannot build package '%s' - %w", config.Package.Name, err)
+			return err
 		}
 	}
-	return nil
+	plan, err := createBuildPlan(configList, *cmdLine.DockerImageName, platformString)
+	if err != nil {
+		return err
+	}
+	return plan.Print(os.Stdout, *cmdLine.PlanFormat)
 }
 
 // addConfigsToDefsMap
@@ -348,12 +503,13 @@ func addConfigsToDefsMap(defsMap *context.ConfigMapType, packageConfigs []config
 
 // buildAndCopyPackage
 // Builds single Package or App (depends on packageOrApp), takes care of every step of build for
-// single package.
+// single package. The dependencies are stored in the manifest next to the archive.
 func buildAndCopyPackage(
 	build          *[]build.Build,
 	platformString *bacpack_package.PlatformString,
-	repo           repository.GitLFSRepository,
+	repo           repository.Repository,
 	packageOrApp   string,
+	dependencies   []repository.ManifestDependency,
 ) error {
 	var err error
 	var removeHandler func()
@@ -384,8 +540,9 @@ func buildAndCopyPackage(
 				break
 			}
 			
-			logger.InfoIndent("Copying to Git repository")
-			err = repo.CopyToRepository(*buildConfig.Package, buildConfig.GetLocalInstallDirPath(), packageOrApp)
+			logger.InfoIndent("Copying to Package Repository")
+			provenance := getProvenance(&buildConfig, dependencies)
+			err = repo.CopyToRepository(*buildConfig.Package, buildConfig.GetLocalInstallDirPath(), packageOrApp, provenance)
 			if err != nil {
 				break
 			}
@@ -401,9 +558,138 @@ func buildAndCopyPackage(
 	return err
 }
 
+// setKeepFailed
+// Sets keepFailed to all buildConfigs.
+func setKeepFailed(buildConfigs []build.Build, keepFailed bool) {
+	for i := range buildConfigs {
+		buildConfigs[i].KeepFailed = keepFailed
+	}
+}
+
+// getBuildCache
+// Returns build cache in cacheDir with remote cache on remoteUrl. Returns nil if both cacheDir and
+// remoteUrl are empty.
+func getBuildCache(cacheDir string, remoteUrl string) (*build_cache.BuildCache, error) {
+	if cacheDir == "" && remoteUrl == "" {
+		return nil, nil
+	}
+	buildCache, err := prerequisites.CreateAndInitialize[build_cache.BuildCache](cacheDir, remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+	logger := log.GetLogger()
+	if cacheDir != "" {
+		logger.Info("Using build cache %s", cacheDir)
+	}
+	if remoteUrl != "" {
+		logger.Info("Using remote build cache %s", buildCache.Remote.GetRedactedUrl())
+	}
+	return buildCache, nil
+}
+
+// setBuildCache
+// Sets buildCache and cache key inputs of cfg to all buildConfigs. The dependencies are
+// identified by SHA-256 of their archives in Package Repository. If the archive of a dependency
+// is not in Package Repository, its SHA-256 is empty. Does nothing if buildCache is nil, so the
+// cache key is not computed when no build cache is used.
+func setBuildCache(
+	buildConfigs   []build.Build,
+	cfg            config.Config,
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+	repo           repository.Repository,
+	buildCache     *build_cache.BuildCache,
+) error {
+	if buildCache == nil {
+		return nil
+	}
+	cacheKeyConfig, err := cfg.GetCacheKeyConfig()
+	if err != nil {
+		return fmt.Errorf("cannot create build cache key of '%s' - %w", cfg.Package.Name, err)
+	}
+	dependencyHashes := []build_cache.DependencyHash{}
+	for _, dependency := range getManifestDependencies(cfg, contextManager) {
+		dependencyHash := build_cache.DependencyHash{
+			Name: dependency.Name,
+		}
+		dependencyConfigs, err := contextManager.GetPackageConfigs(dependency.Name)
+		if err != nil {
+			return err
+		}
+		for _, dependencyConfig := range dependencyConfigs {
+			if dependencyConfig.Package.IsDebug != cfg.Package.IsDebug {
+				continue
+			}
+			dependencyPackage := dependencyConfig.Package
+			dependencyPackage.PlatformString = *platformString
+			dependencyHash.ArchiveSha256, err = repo.GetArchiveSha256(dependencyPackage, constants.PackageDirName)
+			if errors.Is(err, fs.ErrNotExist) {
+				dependencyHash.ArchiveSha256 = ""
+			} else if err != nil {
+				return err
+			}
+			break
+		}
+		dependencyHashes = append(dependencyHashes, dependencyHash)
+	}
+
+	for i := range buildConfigs {
+		buildConfigs[i].BuildCache = buildCache
+		buildConfigs[i].CacheKeyInputs = &build_cache.KeyInputs{
+			Config:       cacheKeyConfig,
+			Dependencies: dependencyHashes,
+		}
+	}
+	return nil
+}
+
+// getManifestDependencies
+// Returns dependencies of cfg with VersionTag of the dependency Config with the same build type.
+// The VersionTag is empty if the dependency is not in the Context.
+func getManifestDependencies(cfg config.Config, contextManager *context.ContextManager) []repository.ManifestDependency {
+	dependencies := []repository.ManifestDependency{}
+	for _, dependencyName := range cfg.DependsOn {
+		dependency := repository.ManifestDependency{
+			Name: dependencyName,
+		}
+		dependencyConfigs, err := contextManager.GetPackageConfigs(dependencyName)
+		if err == nil {
+			for _, dependencyConfig := range dependencyConfigs {
+				if dependencyConfig.Package.IsDebug == cfg.Package.IsDebug {
+					dependency.VersionTag = dependencyConfig.Package.VersionTag
+					break
+				}
+			}
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies
+}
+
+// getProvenance
+// Returns provenance of the performed build for the manifest. If the image ID cannot be
+// determined, a warning is printed and the ID is left empty.
+func getProvenance(buildConfig *build.Build, dependencies []repository.ManifestDependency) repository.Provenance {
+	provenance := repository.Provenance{
+		GitUri:        buildConfig.BuiltPackage.GitUri,
+		GitCommitHash: buildConfig.BuiltPackage.GitCommitHash,
+		ImageName:     buildConfig.Docker.ImageName,
+		DependsOn:     dependencies,
+	}
+	dockerImage := docker.DockerImage(*buildConfig.Docker)
+	imageId, err := dockerImage.GetImageId()
+	if err != nil {
+		log.GetLogger().WarnIndent("Cannot determine image ID for manifest - %s", err)
+	} else {
+		provenance.ImageId = imageId
+	}
+	return provenance
+}
+
 // determinePlatformString
-// Will construct platform string suitable for sysroot.
-func determinePlatformString(dockerImageName string, dockerPort uint16) (*bacpack_package.PlatformString, error) {
+// Will construct platform string suitable for sysroot. The commands in the container are run by
+// Executor of executorType.
+func determinePlatformString(dockerImageName string, dockerPort uint16, executorType string) (*bacpack_package.PlatformString, error) {
 	defaultDocker, err := prerequisites.CreateAndInitialize[docker.Docker](dockerImageName, dockerPort)
 	if err != nil {
 		return nil, err
@@ -414,16 +700,39 @@ func determinePlatformString(dockerImageName string, dockerPort uint16) (*bacpac
 	if err != nil {
 		return nil, err
 	}
-	sshCreds.Port = uint16(defaultDocker.Port)
+	containerExecutor, err := executor.CreateExecutor(executorType, defaultDocker, sshCreds)
+	if err != nil {
+		return nil, err
+	}
 
 	platformString := bacpack_package.PlatformString{
 		Mode: bacpack_package.ModeAuto,
 	}
 
-	err = prerequisites.Initialize[bacpack_package.PlatformString](&platformString, sshCreds, defaultDocker)
+	err = prerequisites.Initialize[bacpack_package.PlatformString](&platformString, containerExecutor, defaultDocker)
 	return &platformString, err
 }
 
+// getImageConfig
+// Returns Image config of the image. Executor set on command line has priority over Executor
+// from Image config. If none is set, Executor is empty, which means SSH Executor. If offline is
+// true, the offline build is enabled regardless of the Image config.
+func getImageConfig(
+	cmdLineExecutor string,
+	offline         bool,
+	contextManager  *context.ContextManager,
+	imageName       string,
+) config.ImageConfig {
+	imageConfig := contextManager.GetImageConfig(imageName)
+	if cmdLineExecutor != "" {
+		imageConfig.Executor = cmdLineExecutor
+	}
+	if offline {
+		imageConfig.Offline = true
+	}
+	return imageConfig
+}
+
 // checkSysrootDirs
 // Checks if sysroot release and debug directories are empty. If not, prints a warning.
 func checkSysrootDirs(platformString *bacpack_package.PlatformString) (error) {
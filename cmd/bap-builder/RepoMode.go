@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/packager_error"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"github.com/bacpack-system/packager/internal/process"
+	"github.com/bacpack-system/packager/internal/repository"
+)
+
+// ManageRepository
+// Process Repository mode of the program. Prunes archives which are not in the Context from
+// Package Repository or sets up Git LFS in Package Repository.
+func ManageRepository(cmdLine *RepoCmdLineArgs, contextPath string) error {
+	logger := log.GetLogger()
+	if cmdLine.SetupLFS {
+		// The repository cannot be created before the setup, Git LFS is its prerequisite
+		err := repository.SetupGitLFS(*cmdLine.Repo)
+		if err != nil {
+			return err
+		}
+		logger.Info("Git LFS is set up in Package Repository %s", *cmdLine.Repo)
+		return nil
+	}
+
+	repo, err := repository.CreateRepository(*cmdLine.RepositoryType, *cmdLine.Repo)
+	if err != nil {
+		return err
+	}
+	contextManager := context.ContextManager{
+		ContextPath: contextPath,
+		ForPackage: true,
+	}
+	err = prerequisites.Initialize(&contextManager)
+	if err != nil {
+		logger.Error("Context consistency error - %s", err)
+		return packager_error.ContextErr
+	}
+
+	if cmdLine.Prune {
+		return pruneRepository(cmdLine, repo, &contextManager)
+	}
+	return nil
+}
+
+// getKeepSuperseded
+// Returns true if older versions of Packages/Apps in Context are kept in repo. The keepSuperseded
+// option value overrides the repository config, if it is set.
+func getKeepSuperseded(keepSuperseded string, repo repository.Repository) bool {
+	if keepSuperseded == "" {
+		return repo.GetConfig().KeepSuperseded
+	}
+	return keepSuperseded == keepSupersededTrue
+}
+
+// pruneRepository
+// Removes archives which are not in the Context from repo for platform string of the image from
+// cmdLine or for all platforms if the image is not set.
+func pruneRepository(cmdLine *RepoCmdLineArgs, repo repository.Repository, contextManager *context.ContextManager) error {
+	var platformString *bacpack_package.PlatformString
+	var err error
+	if *cmdLine.PruneImageName != "" {
+		imageConfig := getImageConfig(*cmdLine.PruneExecutor, false, contextManager, *cmdLine.PruneImageName)
+		platformString, err = determinePlatformString(*cmdLine.PruneImageName, uint16(*cmdLine.PrunePort), imageConfig.Executor)
+		if err != nil {
+			return err
+		}
+	}
+
+	handleRemover := process.SignalHandlerAddHandler(repo.RestoreAllChanges)
+	defer handleRemover()
+
+	logger := log.GetLogger()
+	dryRun := *cmdLine.PruneDryRun
+	paths, err := repo.Prune(contextManager, platformString, *cmdLine.PruneKeep, dryRun)
+	for _, filePath := range paths {
+		if dryRun {
+			logger.Info("Would remove %s", filePath)
+		} else {
+			logger.Info("Removing %s", filePath)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("%d files not in the Context", len(paths))
+	return nil
+}
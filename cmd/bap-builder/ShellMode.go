@@ -0,0 +1,108 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/build"
+	"github.com/bacpack-system/packager/internal/config"
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/executor"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/packager_error"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"github.com/bacpack-system/packager/internal/sysroot"
+	"fmt"
+)
+
+// StartShell
+// Process Shell mode of the program. Starts the image with the sysroot mounted the same way as
+// for the build, clones the Package (if set) and runs interactive shell in the container, so the
+// build failures can be reproduced.
+func StartShell(cmdLine *ShellCmdLineArgs, contextPath string) error {
+	contextManager := context.ContextManager{
+		ContextPath: contextPath,
+		ForPackage: true,
+	}
+	err := prerequisites.Initialize(&contextManager)
+	if err != nil {
+		logger := log.GetLogger()
+		logger.Error("Context consistency error - %s", err)
+		return packager_error.ContextErr
+	}
+	imageConfig := getImageConfig(*cmdLine.Executor, false, &contextManager, *cmdLine.ImageName)
+	platformString, err := getPlatformString(*cmdLine.ImageName, uint16(*cmdLine.Port), false, imageConfig.Executor)
+	if err != nil {
+		return err
+	}
+
+	shellBuild, err := getShellBuild(cmdLine, &contextManager, platformString, imageConfig)
+	if err != nil {
+		return err
+	}
+	sysroot := sysroot.Sysroot{
+		IsDebug:        *cmdLine.Debug,
+		PlatformString: platformString,
+	}
+	err = prerequisites.Initialize(&sysroot)
+	if err != nil {
+		return err
+	}
+	shellBuild.SetSysroot(&sysroot)
+	return shellBuild.RunShell()
+}
+
+// getShellBuild
+// Returns Build of the Package in cmdLine for the image. If no Package is set, returns Build
+// without Package, so nothing is cloned in the container.
+func getShellBuild(
+	cmdLine        *ShellCmdLineArgs,
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+	imageConfig    config.ImageConfig,
+) (*build.Build, error) {
+	if *cmdLine.Package == "" {
+		shellBuild, err := prerequisites.CreateAndInitialize[build.Build](*cmdLine.ImageName, uint16(*cmdLine.Port))
+		if err != nil {
+			return nil, err
+		}
+		shellBuild.Docker.Resources = imageConfig.Resources
+		shellBuild.Executor, err = executor.CreateExecutor(imageConfig.Executor, shellBuild.Docker, shellBuild.SSHCredentials)
+		if err != nil {
+			return nil, err
+		}
+		return shellBuild, nil
+	}
+
+	configs, err := contextManager.GetPackageConfigs(*cmdLine.Package)
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		if config.Package.IsDebug != *cmdLine.Debug {
+			continue
+		}
+		buildConfigs, err := config.GetBuildStructure(
+			*cmdLine.ImageName,
+			platformString,
+			uint16(*cmdLine.Port),
+			false,
+			"",
+			imageConfig,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(buildConfigs) == 0 {
+			return nil, fmt.Errorf("'%s' does not support %s image", *cmdLine.Package, *cmdLine.ImageName)
+		}
+		buildConfigs[0].MirrorCache, err = getMirrorCache("", imageConfig.Offline)
+		if err != nil {
+			return nil, err
+		}
+		return &buildConfigs[0], nil
+	}
+	buildType := "release"
+	if *cmdLine.Debug {
+		buildType = "debug"
+	}
+	return nil, fmt.Errorf("'%s' has no %s Config", *cmdLine.Package, buildType)
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/bacpack-system/packager/internal/prerequisites"
 	"github.com/bacpack-system/packager/internal/repository"
 	"github.com/bacpack-system/packager/internal/packager_error"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 
@@ -22,7 +24,7 @@ const (
 )
 
 // CreateSysroot
-// Creates new sysroot based on Context and Packages in Git Lfs.
+// Creates new sysroot based on Context and Packages in Package Repository.
 func CreateSysroot(cmdLine *CreateSysrootCmdLineArgs, contextPath string) error {
 	dirEmpty, err := isDirEmpty(*cmdLine.Sysroot)
 	if err != nil {
@@ -32,14 +34,7 @@ func CreateSysroot(cmdLine *CreateSysrootCmdLineArgs, contextPath string) error
 		return fmt.Errorf("%w - given sysroot directory is not empty", packager_error.CreatingSysrootErr)
 	}
 
-	repo := repository.GitLFSRepository{
-		GitRepoPath: *cmdLine.Repo,
-	}
-	err = prerequisites.Initialize(&repo)
-	if err != nil {
-		return err
-	}
-	platformString, err := determinePlatformString(*cmdLine.ImageName, uint16(*cmdLine.Port))
+	repo, err := repository.CreateRepository(*cmdLine.RepositoryType, *cmdLine.Repo)
 	if err != nil {
 		return err
 	}
@@ -54,8 +49,13 @@ func CreateSysroot(cmdLine *CreateSysrootCmdLineArgs, contextPath string) error
 		logger.Error("Context consistency error - %s", err)
 		return packager_error.ContextErr
 	}
-	logger.Info("Checking Git Lfs directory consistency")
-	err = repo.CheckGitLfsConsistency(&contextManager, platformString, *cmdLine.ImageName)
+	imageConfig := getImageConfig(*cmdLine.Executor, false, &contextManager, *cmdLine.ImageName)
+	platformString, err := determinePlatformString(*cmdLine.ImageName, uint16(*cmdLine.Port), imageConfig.Executor)
+	if err != nil {
+		return err
+	}
+	logger.Info("Checking Package Repository consistency")
+	err = repo.CheckConsistency(&contextManager, platformString, *cmdLine.ImageName, getKeepSuperseded(*cmdLine.KeepSuperseded, repo))
 	if err != nil {
 		return packager_error.GitLfsErr
 	}
@@ -65,7 +65,7 @@ func CreateSysroot(cmdLine *CreateSysrootCmdLineArgs, contextPath string) error
 	}
 
 	logger.Info("Creating sysroot directory from packages")
-	err = unzipAllPackagesToDir(packages, &repo, *cmdLine.Sysroot)
+	err = unzipAllPackagesToDir(packages, repo, *cmdLine.Sysroot)
 	if err != nil {
 		return fmt.Errorf("%w - %s", packager_error.CreatingSysrootErr, err)
 	}
@@ -74,13 +74,22 @@ func CreateSysroot(cmdLine *CreateSysrootCmdLineArgs, contextPath string) error
 }
 
 // unzipAllPackagesToDir
-// Unzips all given Packages in repo to specified dirPath.
-func unzipAllPackagesToDir(packages []bacpack_package.Package, repo *repository.GitLFSRepository, dirPath string) error {
+// Unzips all given Packages in repo to specified dirPath. The Packages which are not in repo are
+// skipped.
+func unzipAllPackagesToDir(packages []bacpack_package.Package, repo repository.Repository, dirPath string) error {
+	tmpDir, err := os.MkdirTemp("", "bap-sysroot-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
 	anyPackageCopied := false
 	for _, pack := range packages {
-		packPath := path.Join(repo.CreatePath(pack, constants.PackageDirName), pack.GetFullPackageName() + bacpack_package.ZipExt)
-		_, err := os.Stat(packPath)
-		if err == nil { // Package exists in Git Lfs
+		packPath, err := repo.FetchArchive(pack, constants.PackageDirName, tmpDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err == nil { // Package exists in Package Repository
 			var sysrootPath string
 			if pack.IsDebug {
 				sysrootPath = path.Join(dirPath, DebugPath)
@@ -101,7 +110,7 @@ func unzipAllPackagesToDir(packages []bacpack_package.Package, repo *repository.
 		}
 	}
 	if !anyPackageCopied {
-		return fmt.Errorf("no package from Context is in Git Lfs, so nothing copied to sysroot")
+		return fmt.Errorf("no package from Context is in Package Repository, so nothing copied to sysroot")
 	}
 
 	return nil
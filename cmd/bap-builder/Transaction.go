@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/build_session"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/repository"
+	"github.com/bacpack-system/packager/internal/sysroot"
+	"fmt"
+	"sync/atomic"
+)
+
+// packagerVersion version of the packager stored in the transaction commits, it is set by
+// -ldflags "-X main.packagerVersion=<version>" during the build
+var packagerVersion = "dev"
+
+// sessionTransaction
+// Transaction of Package Repository for whole build session. All Packages/Apps built in the
+// session are committed at once by Commit, else they are rolled back by Rollback.
+type sessionTransaction struct {
+	repo      repository.TransactionalRepository
+	session   *build_session.BuildSession
+	committed atomic.Bool
+}
+
+// beginSessionTransaction
+// Begins transaction of repo and of sysroots, so Packages copied to sysroots in the session are
+// removed on rollback. The session is reset on rollback, it can be nil. Returns error if repo does
+// not support transactions.
+func beginSessionTransaction(repo repository.Repository, session *build_session.BuildSession) (*sessionTransaction, error) {
+	transactionalRepo, ok := repo.(repository.TransactionalRepository)
+	if !ok {
+		return nil, fmt.Errorf("single commit is supported only by %s Package Repository", repository.TypeGitLFS)
+	}
+	err := transactionalRepo.BeginTransaction()
+	if err != nil {
+		return nil, err
+	}
+	sysroot.BeginTransaction()
+	return &sessionTransaction{repo: transactionalRepo, session: session}, nil
+}
+
+// Commit
+// Commits all Packages/Apps built for imageName in the transaction.
+func (transaction *sessionTransaction) Commit(imageName string, platformString *bacpack_package.PlatformString) error {
+	err := transaction.repo.CommitTransaction(repository.TransactionInfo{
+		ImageName:       imageName,
+		PlatformString:  platformString.Serialize(),
+		PackagerVersion: packagerVersion,
+	})
+	if err != nil {
+		return err
+	}
+	sysroot.CommitTransaction()
+	transaction.committed.Store(true)
+	log.GetLogger().Info("Built Packages/Apps committed to Package Repository")
+	return nil
+}
+
+// Rollback
+// Rolls back the transaction if it is not committed. Packages copied to sysroots in the session are
+// removed from them and the build session is reset, because its completed Packages are not in
+// Package Repository anymore and they must be built again by the next build.
+func (transaction *sessionTransaction) Rollback() error {
+	if transaction.committed.Load() {
+		return nil
+	}
+	log.GetLogger().Warn("Rolling back Package Repository to the state before the build")
+	err := transaction.repo.RollbackTransaction()
+	if err != nil {
+		return err
+	}
+	err = sysroot.RollbackTransaction()
+	if err != nil {
+		return err
+	}
+	if transaction.session != nil {
+		return transaction.session.Reset()
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/packager_error"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"github.com/bacpack-system/packager/internal/repository"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// verificationReport
+// Result of the Package Repository verification.
+type verificationReport struct {
+	Issues []repository.VerificationIssue
+}
+
+// VerifyRepository
+// Process Verify repository mode of the program. Verifies all archives in Package Repository and
+// prints found issues. Returns error if any issue is found.
+func VerifyRepository(cmdLine *VerifyRepoCmdLineArgs, contextPath string) error {
+	repo, err := repository.CreateRepository(*cmdLine.RepositoryType, *cmdLine.Repo)
+	if err != nil {
+		return err
+	}
+	logger := log.GetLogger()
+	contextManager := context.ContextManager{
+		ContextPath: contextPath,
+		ForPackage: true,
+	}
+	err = prerequisites.Initialize(&contextManager)
+	if err != nil {
+		logger.Error("Context consistency error - %s", err)
+		return packager_error.ContextErr
+	}
+
+	logger.Info("Verifying Package Repository %s", *cmdLine.Repo)
+	issues, err := repo.Verify(&contextManager, getKeepSuperseded(*cmdLine.KeepSuperseded, repo))
+	if err != nil {
+		return err
+	}
+	report := verificationReport{
+		Issues: issues,
+	}
+	if report.Issues == nil {
+		report.Issues = []repository.VerificationIssue{}
+	}
+	err = report.Print(os.Stdout, *cmdLine.Format)
+	if err != nil {
+		return err
+	}
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Type != repository.IssueSuperseded {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%w - %d issues found in Package Repository", packager_error.GitLfsErr, errorCount)
+	}
+	logger.Info("Package Repository is valid")
+	return nil
+}
+
+// Print
+// Prints the report to writer in given format (table, json).
+func (report *verificationReport) Print(writer io.Writer, format string) error {
+	switch format {
+	case outputFormatTable:
+		if len(report.Issues) == 0 {
+			return nil
+		}
+		tableWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tableWriter, "TYPE\tPATH\tMESSAGE")
+		for _, issue := range report.Issues {
+			fmt.Fprintf(tableWriter, "%s\t%s\t%s\n", issue.Type, issue.Path, issue.Message)
+		}
+		return tableWriter.Flush()
+	case outputFormatJSON:
+		bytes, err := json.MarshalIndent(report, "", outputIndent)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(append(bytes, '\n'))
+		return err
+	}
+	return fmt.Errorf("unsupported report format '%s'", format)
+}
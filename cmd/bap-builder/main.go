@@ -1,9 +1,11 @@
 package main
 
 import (
+	"github.com/bacpack-system/packager/internal/docker"
 	"github.com/bacpack-system/packager/internal/log"
 	"github.com/bacpack-system/packager/internal/prerequisites"
 	"github.com/bacpack-system/packager/internal/process"
+	"github.com/bacpack-system/packager/internal/ssh"
 	"github.com/bacpack-system/packager/internal/packager_error"
 	"os"
 	"time"
@@ -24,6 +26,12 @@ func main() {
 		logger.Error("Can't parse cmd line arguments - %s", err)
 		os.Exit(packager_error.CMD_LINE_ERROR)
 	}
+	err = docker.SetRuntime(*args.ContainerRuntime)
+	if err != nil {
+		logger.Error("Can't set container runtime - %s", err)
+		os.Exit(packager_error.CMD_LINE_ERROR)
+	}
+	ssh.SetPasswordAuthentication(*args.SSHPasswordAuth)
 	process.SignalHandlerRegisterSignal(syscall.SIGINT)
 
 	if args.BuildImage {
@@ -59,6 +67,46 @@ func main() {
 		}
 		return
 	}
+	if args.Graph {
+		err = CreateDependencyGraph(&args.GraphArgs, *args.Context)
+		if err != nil {
+			logger.Error("Failed to create dependency graph: %s", err)
+			os.Exit(packager_error.GetReturnCode(err))
+		}
+		return
+	}
+	if args.VerifyRepo {
+		err = VerifyRepository(&args.VerifyRepoArgs, *args.Context)
+		if err != nil {
+			logger.Error("Package Repository verification failed: %s", err)
+			os.Exit(packager_error.GetReturnCode(err))
+		}
+		return
+	}
+	if args.Mirror {
+		err = ManageMirrors(&args.MirrorArgs, *args.Context)
+		if err != nil {
+			logger.Error("Failed to manage git mirrors: %s", err)
+			os.Exit(packager_error.GetReturnCode(err))
+		}
+		return
+	}
+	if args.Repo {
+		err = ManageRepository(&args.RepoArgs, *args.Context)
+		if err != nil {
+			logger.Error("Failed to manage Package Repository: %s", err)
+			os.Exit(packager_error.GetReturnCode(err))
+		}
+		return
+	}
+	if args.Shell {
+		err = StartShell(&args.ShellArgs, *args.Context)
+		if err != nil {
+			logger.Error("Failed to run shell: %s", err)
+			os.Exit(packager_error.GetReturnCode(err))
+		}
+		return
+	}
 
 	return
 }
@@ -42,7 +42,7 @@ case $ARCHITECTURE in
     ;;
 esac
 
-CGO_ENABLED=0 GOOS=${SYSTEM} GOARCH=${GO_ARCH} go build -a -o "${INSTALL_DIR}/bin/" ./...
+CGO_ENABLED=0 GOOS=${SYSTEM} GOARCH=${GO_ARCH} go build -a -ldflags "-X main.packagerVersion=${VERSION}" -o "${INSTALL_DIR}/bin/" ./...
 
 if [ -d "resources" ]; then
   mkdir -p "$INSTALL_DIR/etc/"
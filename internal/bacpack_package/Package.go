@@ -18,6 +18,18 @@ const (
 	stringSeparator = "_"
 )
 
+var versionTagRegexp *regexp.Regexp = regexp.MustCompilePOSIX("^v[0-9]+\\.[0-9]+\\.[0-9]+$")
+
+// PackageNameParts
+// Parts of the full Package name (without extension) - <ShortName>_<VersionTag>_<PlatformString>.
+// The Package flags (IsLibrary, IsDebug, IsDevLib) cannot be unambiguously parsed from the short
+// name, so the short name should be compared with GetShortPackageName of known Packages.
+type PackageNameParts struct {
+	ShortName      string
+	VersionTag     string
+	PlatformString string
+}
+
 // Package enables us to easily create a package
 type Package struct {
 	// Base name of the package
@@ -58,8 +70,7 @@ func (packg *Package) CheckPrerequisites(*prerequisites.Args) error {
 		return fmt.Errorf("IsDevLib is true but IsLibrary is false")
 	}
 
-	versionTagRegex, _ := regexp.CompilePOSIX("^v[0-9]+\\.[0-9]+\\.[0-9]+$")
-	if !versionTagRegex.MatchString(packg.VersionTag) {
+	if !versionTagRegexp.MatchString(packg.VersionTag) {
 		return fmt.Errorf("VersionTag %s is not valid version tag", packg.VersionTag)
 	}
 	if packg.Name == "" {
@@ -119,6 +130,36 @@ func (packg *Package) GetFullPackageName() string {
 	return strings.Join(packageName, stringSeparator)
 }
 
+// ParseFullPackageName
+// Parses full Package name created by GetFullPackageName (with or without zip extension) into its
+// parts. Returns error if any part is missing or the VersionTag is not valid.
+func ParseFullPackageName(fullName string) (PackageNameParts, error) {
+	name := strings.TrimSuffix(fullName, ZipExt)
+	platformSeparatorIndex := strings.LastIndex(name, stringSeparator)
+	if platformSeparatorIndex < 0 {
+		return PackageNameParts{}, fmt.Errorf("'%s' does not contain VersionTag and platform string", fullName)
+	}
+	versionSeparatorIndex := strings.LastIndex(name[:platformSeparatorIndex], stringSeparator)
+	if versionSeparatorIndex < 0 {
+		return PackageNameParts{}, fmt.Errorf("'%s' does not contain VersionTag", fullName)
+	}
+	parts := PackageNameParts{
+		ShortName:      name[:versionSeparatorIndex],
+		VersionTag:     name[versionSeparatorIndex + 1:platformSeparatorIndex],
+		PlatformString: name[platformSeparatorIndex + 1:],
+	}
+	if parts.ShortName == "" {
+		return PackageNameParts{}, fmt.Errorf("'%s' has empty Package name", fullName)
+	}
+	if !versionTagRegexp.MatchString(parts.VersionTag) {
+		return PackageNameParts{}, fmt.Errorf("'%s' has invalid VersionTag %s", fullName, parts.VersionTag)
+	}
+	if strings.Count(parts.PlatformString, "-") < 2 {
+		return PackageNameParts{}, fmt.Errorf("'%s' has invalid platform string %s", fullName, parts.PlatformString)
+	}
+	return parts, nil
+}
+
 func createZIPArchive(sourceDir string, archivePath string) error {
 	var files []string
 	var err error
@@ -3,10 +3,10 @@ package bacpack_package
 import (
 	"github.com/acobaugh/osrelease"
 	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/executor"
 	"github.com/bacpack-system/packager/internal/log"
 	"github.com/bacpack-system/packager/internal/prerequisites"
 	"github.com/bacpack-system/packager/internal/process"
-	"github.com/bacpack-system/packager/internal/ssh"
 	"fmt"
 	"os"
 	"regexp"
@@ -47,8 +47,8 @@ type PlatformStringExplicit struct {
 }
 
 type platformStringInitArgs struct {
-	Credentials *ssh.SSHCredentials
-	Docker      *docker.Docker
+	Executor executor.Executor
+	Docker   *docker.Docker
 }
 
 func (pstr *PlatformString) FillDefault(args *prerequisites.Args) error {
@@ -72,7 +72,7 @@ func (pstr *PlatformString) FillDynamic(args *prerequisites.Args) error {
 		}
 		var argsStruct platformStringInitArgs
 		prerequisites.GetArgs(args, &argsStruct)
-		err := pstr.determinePlatformString(*argsStruct.Credentials, argsStruct.Docker)
+		err := pstr.determinePlatformString(argsStruct.Executor, argsStruct.Docker)
 		if err != nil {
 			return err
 		}
@@ -113,7 +113,7 @@ func (pstr *PlatformString) CheckPrerequisites(args *prerequisites.Args) error {
 // determinePlatformString
 // Computes platform string for ModeAuto.
 // If the PlatformString is in ModeExplicit the panic raise.
-func (pstr *PlatformString) determinePlatformString(credentials ssh.SSHCredentials, dock *docker.Docker) error {
+func (pstr *PlatformString) determinePlatformString(containerExecutor executor.Executor, dock *docker.Docker) error {
 	if pstr.Mode == ModeExplicit {
 		panic(fmt.Errorf("cannot determine PlatformString for explicit mode"))
 	}
@@ -133,7 +133,6 @@ func (pstr *PlatformString) determinePlatformString(credentials ssh.SSHCredentia
 	if err != nil {
 		return err
 	}
-	credentials.Port = dock.Port
 
 	distroName, distroRelease := getDistroIdAndReleaseFromDockerContainer(dock)
 	if distroName == "" || distroRelease == "" {
@@ -144,7 +143,7 @@ func (pstr *PlatformString) determinePlatformString(credentials ssh.SSHCredentia
 	pstr.String.DistroRelease = distroRelease
 	switch pstr.Mode {
 	case ModeAuto:
-		pstr.String.Machine = getSystemArchitecture(credentials)
+		pstr.String.Machine = getSystemArchitecture(containerExecutor)
 	default:
 		panic(fmt.Errorf("unsupported PlatformStringMode"))
 	}
@@ -161,13 +160,8 @@ func (pstr *PlatformString) Serialize() string {
 	return pstr.String.Machine + "-" + pstr.String.DistroName + "-" + pstr.String.DistroRelease
 }
 
-func runShellCommandOverSSH(credentials ssh.SSHCredentials, command string) string {
-	var err error
-	commandSsh := ssh.Command{
-		Command: command,
-	}
-
-	commandStdOut, err := commandSsh.RunCommandOverSSH(credentials)
+func runShellCommand(containerExecutor executor.Executor, command string) string {
+	commandStdOut, err := containerExecutor.RunCommand(command)
 	if err != nil {
 		panic(fmt.Errorf("cannot run command '%s' - %w", command, err))
 	}
@@ -182,8 +176,8 @@ func stripNewline(str string) string {
 	return regexp.FindString(str)
 }
 
-func getSystemArchitecture(credentials ssh.SSHCredentials) string {
-	machineUname := runShellCommandOverSSH(credentials, "uname -m")
+func getSystemArchitecture(containerExecutor executor.Executor) string {
+	machineUname := runShellCommand(containerExecutor, "uname -m")
 	machine := strings.ToLower(stripNewline(machineUname))
 	machine = strings.Replace(machine, "_", "-", -1)
 	return machine
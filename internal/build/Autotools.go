@@ -0,0 +1,98 @@
+package build
+
+import (
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Autotools
+// Represents Autotools (configure script) build system. Its main task is to create a configure
+// command line. The build itself is performed by GNUMake.
+type Autotools struct {
+	BuildSystem  *BuildSystem `json:"-"`
+	// Options passed to configure script as --option=value (or --option if the value is empty)
+	Options      map[string]string
+	// ConfigureDir directory with configure script (or configure.ac), relative to the Git root
+	ConfigureDir string
+	// Autoreconf if true, autoreconf is run before configure to generate the configure script
+	Autoreconf   bool
+}
+
+func (autotools *Autotools) FillDefault(*prerequisites.Args) error {
+	autotools.ConfigureDir = "." + string(os.PathSeparator)
+	autotools.Options = map[string]string{}
+	autotools.Autoreconf = false
+	return nil
+}
+
+func (autotools *Autotools) FillDynamic(*prerequisites.Args) error {
+	return nil
+}
+
+func (autotools *Autotools) CheckPrerequisites(*prerequisites.Args) error {
+	for key := range autotools.Options {
+		if !validateOptionName(key) {
+			return fmt.Errorf("invalid Autotools option: %s", key)
+		}
+	}
+	if autotools.BuildSystem == nil {
+		return fmt.Errorf("BuildSystem is required for Autotools")
+	}
+	_, found := autotools.Options["prefix"]
+	if found {
+		return fmt.Errorf("do not specify prefix option")
+	}
+	return nil
+}
+
+// ConstructCMDLine
+// Returns commands which change the directory to ConfigureDir, optionally run autoreconf and run
+// configure script. Packages from PrefixPath are made available through PKG_CONFIG_PATH, CPPFLAGS
+// and LDFLAGS passed to the configure script.
+func (autotools *Autotools) ConstructCMDLine() []string {
+	configureDir := path.Join(autotools.BuildSystem.SourceDir, autotools.ConfigureDir)
+	commands := []string{"cd " + escapeDefineValue(configureDir)}
+	if autotools.Autoreconf {
+		commands = append(commands, "autoreconf --install --force")
+	}
+
+	var cmdConfigure []string
+	cmdConfigure = append(cmdConfigure, "./configure")
+	cmdConfigure = append(cmdConfigure, "--prefix=" + escapeDefineValue(autotools.BuildSystem.InstallPrefix))
+	for key, value := range autotools.Options {
+		if value == "" {
+			cmdConfigure = append(cmdConfigure, "--" + key)
+		} else {
+			cmdConfigure = append(cmdConfigure, "--" + key + "=" + escapeDefineValue(value))
+		}
+	}
+	cmdConfigure = append(cmdConfigure, autotools.getPrefixPathVariables()...)
+	commands = append(commands, strings.Join(cmdConfigure, " "))
+	return commands
+}
+
+// getPrefixPathVariables
+// Returns configure variables which add PrefixPath to search paths of pkg-config, preprocessor and
+// linker. Values of these variables from environment are preserved.
+func (autotools *Autotools) getPrefixPathVariables() []string {
+	prefixPath := autotools.BuildSystem.PrefixPath
+	if prefixPath == "" {
+		return []string{}
+	}
+	pkgConfigPath := strings.Join([]string{
+		path.Join(prefixPath, "lib", "pkgconfig"),
+		path.Join(prefixPath, "lib64", "pkgconfig"),
+		path.Join(prefixPath, "share", "pkgconfig"),
+		"$PKG_CONFIG_PATH",
+	}, ":")
+	cppFlags := "-I" + path.Join(prefixPath, "include") + " $CPPFLAGS"
+	ldFlags := "-L" + path.Join(prefixPath, "lib") + " -L" + path.Join(prefixPath, "lib64") + " $LDFLAGS"
+	return []string{
+		escapeDefineValue("PKG_CONFIG_PATH=" + pkgConfigPath),
+		escapeDefineValue("CPPFLAGS=" + cppFlags),
+		escapeDefineValue("LDFLAGS=" + ldFlags),
+	}
+}
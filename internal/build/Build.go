@@ -1,9 +1,12 @@
 package build
 
 import (
+	"github.com/bacpack-system/packager/internal/build_cache"
 	"github.com/bacpack-system/packager/internal/constants"
 	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/executor"
 	"github.com/bacpack-system/packager/internal/git"
+	"github.com/bacpack-system/packager/internal/git_mirror"
 	"github.com/bacpack-system/packager/internal/log"
 	"github.com/bacpack-system/packager/internal/bacpack_package"
 	"github.com/bacpack-system/packager/internal/prerequisites"
@@ -11,6 +14,7 @@ import (
 	"github.com/bacpack-system/packager/internal/ssh"
 	"github.com/bacpack-system/packager/internal/sysroot"
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -18,6 +22,7 @@ import (
 	"regexp"
 	"time"
 	"strconv"
+	"strings"
 )
 
 type Build struct {
@@ -26,10 +31,34 @@ type Build struct {
 	Git            *git.Git
 	BuildSystem    *BuildSystem
 	SSHCredentials *ssh.SSHCredentials
+	// Executor runs commands in the container, SSH Executor is used by default
+	Executor       executor.Executor
 	Package        *bacpack_package.Package
 	BuiltPackage   *sysroot.BuiltPackage
 	UseLocalRepo   bool
+	// Offline if true, the repository is checked out on the host and mounted read-only to the
+	// container which runs without network access. Requires docker exec Executor and MirrorCache.
+	Offline        bool
+	// CacheKeyInputs inputs of the build cache key, the Git commit hash and image ID are filled
+	// during the build. If nil, the cache key is not computed.
+	CacheKeyInputs *build_cache.KeyInputs
+	// BuildCache cache of install trees. If nil, the install tree is not restored nor stored.
+	BuildCache     *build_cache.BuildCache
+	// MirrorCache cache of git mirrors used as reference of the clone and of the checkouts used in
+	// offline build. If nil, the repository is cloned without mirror.
+	MirrorCache    *git_mirror.MirrorCache
+	// KeepFailed if true, the container is not removed when the build fails inside it, so the
+	// failure can be inspected in the container
+	KeepFailed     bool
 	sysroot        *sysroot.Sysroot
+	// hostSourceDir checkout of the repository in MirrorCache mounted in offline build
+	hostSourceDir  string
+	// unlockHostSource unlocks hostSourceDir, nil if it is not locked
+	unlockHostSource func()
+	// mirrorPath path to the git mirror of the repository on the host, empty if not available
+	mirrorPath     string
+	// keepContainer if true, the container is not removed after the build
+	keepContainer  bool
 }
 
 type buildInitArgs struct {
@@ -62,6 +91,12 @@ func (build *Build) FillDefault(args *prerequisites.Args) error {
 		}
 		build.SSHCredentials.Port = build.Docker.Port
 	}
+	if build.Executor == nil {
+		build.Executor, err = executor.CreateExecutor(executor.TypeSSH, build.Docker, build.SSHCredentials)
+		if err != nil {
+			return err
+		}
+	}
 	if build.BuildSystem == nil {
 		build.BuildSystem, err = prerequisites.CreateAndInitialize[BuildSystem]()
 		if err != nil {
@@ -96,17 +131,26 @@ func (build *Build) CheckPrerequisites(*prerequisites.Args) error {
 	if _, err := os.Stat(copyDir); !os.IsNotExist(err) {
 		return fmt.Errorf("package directory exist. Please delete it: %s", copyDir)
 	}
+	if build.Offline && !build.Docker.WithoutSSH {
+		return fmt.Errorf("offline build requires %s executor, the container without network cannot be reached by SSH", executor.TypeDockerExec)
+	}
 
 	return nil
 }
 
 // performPreBuildTasks
 // Downloads Package files for build in docker container. Clones the repository and updates all
-// submodules.
+// submodules. In offline build the repository checked out on the host is copied instead and the
+// checkout is unlocked after the copy.
 func (build *Build) performPreBuildTasks(shellEvaluator *ssh.ShellEvaluator) error {
+	defer build.unlockHostSourceDir()
 	gitClone := git.GitClone{Git: *build.Git}
+	if build.mirrorPath != "" {
+		gitClone.Reference = dockerGitMirrorDirConst
+	}
 	gitCheckout := git.GitCheckout{Git: *build.Git}
 	gitSubmoduleUpdate := git.GitSubmoduleUpdate{Git: *build.Git}
+	sourceCopy := SourceCopy{SourceDir: dockerGitSourceDirConst, DestDir: dockerGitCloneDirConst}
 	startupScript, err := prerequisites.CreateAndInitialize[StartupScript]()
 	if err != nil {
 		return err
@@ -129,19 +173,95 @@ func (build *Build) performPreBuildTasks(shellEvaluator *ssh.ShellEvaluator) err
 			&gitSubmoduleUpdate,
 		},
 	}
+	if build.Offline {
+		preparePackageChain.Chain = []CMDLineInterface{
+			build.Env,
+			&sourceCopy,
+		}
+	}
 
 	shellEvaluator.PreparingCommands = startupChain.GenerateCommands()
 	shellEvaluator.Commands = preparePackageChain.GenerateCommands()
 
-	err = shellEvaluator.RunOverSSH(*build.SSHCredentials)
+	err = build.Executor.Run(shellEvaluator)
 	if err != nil {
 		logger := log.GetLogger()
-		logger.Error("Failed to clone or checkout git repository, check the log file, is the git URI and revision correct?")
+		if build.Offline {
+			logger.Error("Failed to copy git repository checked out on host, check the log file")
+		} else {
+			logger.Error("Failed to clone or checkout git repository, check the log file, is the git URI and revision correct?")
+		}
 		return err
 	}
 
 	return nil
 }
+
+// cloneOnHost
+// Checks out the repository with submodules on the host in MirrorCache and mounts the checkout
+// read-only to the container. The checkout is reused by later builds of the repository. Disables
+// network of the container. Output of git is written to logWriter.
+func (build *Build) cloneOnHost(logWriter io.Writer) error {
+	if build.MirrorCache == nil {
+		return fmt.Errorf("offline build requires git mirror cache")
+	}
+	var err error
+	build.hostSourceDir, build.unlockHostSource, err = build.MirrorCache.Checkout(build.Git.URI, build.Git.Revision, logWriter)
+	if err != nil {
+		log.GetLogger().Error("Failed to clone or checkout git repository on host, is the git URI and revision correct?")
+		return err
+	}
+	err = build.Docker.SetReadOnlyVolume(build.hostSourceDir, dockerGitSourceDirConst)
+	if err != nil {
+		return err
+	}
+	build.Docker.NetworkDisabled = true
+	return nil
+}
+
+// updateMirror
+// Creates or updates the git mirror of the repository in MirrorCache. In offline build the mirror
+// is used by the clone on the host, else it is mounted read-only to the container. If the mirror
+// cannot be updated, the outdated mirror is used or the repository is cloned without mirror.
+func (build *Build) updateMirror(logWriter io.Writer) error {
+	logger := log.GetLogger()
+	mirrorPath, err := build.MirrorCache.Update(build.Git.URI, logWriter)
+	if err != nil {
+		if !build.MirrorCache.Contains(build.Git.URI) {
+			logger.WarnIndent("Cannot create git mirror, the repository is cloned without it - %s", err)
+			return nil
+		}
+		logger.WarnIndent("Cannot update git mirror, outdated mirror is used - %s", err)
+	}
+	build.mirrorPath = mirrorPath
+	if build.Offline {
+		return nil
+	}
+	return build.Docker.SetReadOnlyVolume(mirrorPath, dockerGitMirrorDirConst)
+}
+
+// unlockHostSourceDir
+// Unlocks the checkout of the repository on the host, so it can be updated by other builds. Does
+// nothing if the checkout is not locked.
+func (build *Build) unlockHostSourceDir() {
+	if build.unlockHostSource == nil {
+		return
+	}
+	build.unlockHostSource()
+	build.unlockHostSource = nil
+}
+
+// removeHostSource
+// Unlocks and unmounts the checkout of the repository on the host in offline build. The checkout
+// is kept in MirrorCache.
+func (build *Build) removeHostSource() {
+	build.unlockHostSourceDir()
+	if build.hostSourceDir == "" {
+		return
+	}
+	delete(build.Docker.ReadOnlyVolumes, build.hostSourceDir)
+	build.hostSourceDir = ""
+}
 // prepareForBuild
 // Prepares some fields of Build struct for build and makes pre build checks.
 func (build *Build) prepareForBuild() error {
@@ -158,16 +278,22 @@ func (build *Build) prepareForBuild() error {
 	build.BuildSystem.SourceDir = dockerGitCloneDirConst
 	build.BuildSystem.InstallPrefix = constants.DockerInstallDirConst
 
-	if build.sysroot != nil {
-		build.sysroot.CreateSysrootDir()
-		sysPath := build.sysroot.GetSysrootPath()
-		err = build.Docker.SetVolume(sysPath, "/sysroot")
-		if err != nil {
-			return err
-		}
-		build.BuildSystem.PrefixPath = "/sysroot"
-	}
+	return build.mountSysroot()
+}
 
+// mountSysroot
+// Mounts the sysroot directory to the container, if the sysroot is set.
+func (build *Build) mountSysroot() error {
+	if build.sysroot == nil {
+		return nil
+	}
+	build.sysroot.CreateSysrootDir()
+	sysPath := build.sysroot.GetSysrootPath()
+	err := build.Docker.SetVolume(sysPath, dockerSysrootDirConst)
+	if err != nil {
+		return err
+	}
+	build.BuildSystem.PrefixPath = dockerSysrootDirConst
 	return nil
 }
 
@@ -198,8 +324,27 @@ func (build *Build) RunBuild() (error, bool) { // Long function - it is hard to
 		shellEvaluator.StdOut = file
 	}
 
+	if build.MirrorCache != nil {
+		logger.InfoIndent("Updating git mirror of Package repository")
+		err = build.updateMirror(shellEvaluator.StdOut)
+		if err != nil {
+			return err, false
+		}
+	}
+	if build.Offline {
+		logger.InfoIndent("Checking out Package git repository on host")
+		defer build.removeHostSource()
+		err = build.cloneOnHost(shellEvaluator.StdOut)
+		if err != nil {
+			return err, false
+		}
+	}
+
 	dockerRun := (*docker.DockerRun)(build.Docker)
 	removeHandler := process.SignalHandlerAddHandler(func() error {
+		if build.keepContainer {
+			return nil
+		}
 		// Waiting for docker run command to get container id
 		time.Sleep(300 * time.Millisecond)
 		return build.stopAndRemoveContainer()
@@ -214,7 +359,11 @@ func (build *Build) RunBuild() (error, bool) { // Long function - it is hard to
 	}
 	build.SSHCredentials.Port = build.Docker.Port
 
-	logger.InfoIndent("Cloning Package git repository inside docker container")
+	if build.Offline {
+		logger.InfoIndent("Copying Package git repository inside docker container")
+	} else {
+		logger.InfoIndent("Cloning Package git repository inside docker container")
+	}
 
 	err = build.performPreBuildTasks(&shellEvaluator)
 	if err != nil {
@@ -226,11 +375,27 @@ func (build *Build) RunBuild() (error, bool) { // Long function - it is hard to
 		return fmt.Errorf("can't get git commit hash from container - %w", err), false
 	}
 	build.BuiltPackage.DirName = build.sysroot.GetDirNameInSysroot()
+	err = build.computeCacheKey()
+	if err != nil {
+		return err, false
+	}
 
 	if build.sysroot.IsPackageInSysroot(*build.BuiltPackage) {
 		logger.InfoIndent("Package already built in sysroot - skipping build")
 		return nil, false
 	}
+	if build.isInBuildCache() {
+		logger.InfoIndent("Restoring install files from build cache")
+		err = build.BuildCache.Restore(build.BuiltPackage.CacheKey, build.GetLocalInstallDirPath())
+		if err == nil {
+			return nil, true
+		}
+		logger.WarnIndent("Cannot restore install files from build cache, the Package will be built - %s", err)
+		err = build.CleanUp()
+		if err != nil {
+			return err, false
+		}
+	}
 	startupScript, err := prerequisites.CreateAndInitialize[StartupScript]()
 	if err != nil {
 		return err, false
@@ -253,8 +418,14 @@ func (build *Build) RunBuild() (error, bool) { // Long function - it is hard to
 
 	logger.InfoIndent("Running build inside container")
 
-	err = shellEvaluator.RunOverSSH(*build.SSHCredentials)
+	err = build.Executor.Run(&shellEvaluator)
 	if err != nil {
+		if build.KeepFailed {
+			build.keepFailedContainer()
+		}
+		if build.Offline {
+			return fmt.Errorf("build failed inside docker container without network access, check the log file if the build tried to download anything"), false
+		}
 		return fmt.Errorf("build failed inside docker container, check the log file"), false
 	}
 
@@ -265,9 +436,105 @@ func (build *Build) RunBuild() (error, bool) { // Long function - it is hard to
 		return fmt.Errorf("can't download files from container to local directory"), false
 	}
 
+	if build.BuildCache != nil && build.BuiltPackage.CacheKey != "" {
+		logger.InfoIndent("Storing install files to build cache")
+		err = build.BuildCache.Store(build.BuiltPackage.CacheKey, build.GetLocalInstallDirPath())
+		if err != nil {
+			logger.WarnIndent("Cannot store install files to build cache - %s", err)
+		}
+	}
+
 	return nil, true
 }
 
+// RunShell
+// Creates a Docker container with the sysroot mounted the same way as for the build, clones the
+// Package repository in it (if Git URI is set) and runs interactive shell in the repository
+// directory. The container is stopped and removed when the shell exits.
+func (build *Build) RunShell() error {
+	build.Git.ClonePath = dockerGitCloneDirConst
+	err := build.mountSysroot()
+	if err != nil {
+		return err
+	}
+	withPackage := build.Git.URI != ""
+
+	logger := log.GetLogger()
+	shellEvaluator := ssh.ShellEvaluator{
+		Commands: []string{},
+		StdOut:   os.Stdout,
+	}
+	if withPackage && build.Offline {
+		logger.Info("Checking out Package git repository on host")
+		defer build.removeHostSource()
+		err = build.cloneOnHost(os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+
+	dockerRun := (*docker.DockerRun)(build.Docker)
+	removeHandler := process.SignalHandlerAddHandler(func() error {
+		// Waiting for docker run command to get container id
+		time.Sleep(300 * time.Millisecond)
+		return build.stopAndRemoveContainer()
+	})
+	defer removeHandler()
+
+	logger.Info("Starting docker container")
+	err = dockerRun.Run()
+	if err != nil {
+		return err
+	}
+	build.SSHCredentials.Port = build.Docker.Port
+
+	workDir := string(filepath.Separator)
+	if withPackage {
+		logger.Info("Cloning Package git repository inside docker container")
+		err = build.performPreBuildTasks(&shellEvaluator)
+		if err != nil {
+			return err
+		}
+		workDir = dockerGitCloneDirConst
+	}
+
+	logger.Info("Starting shell in container, the container is removed when the shell exits")
+	dockerExec := (*docker.DockerExec)(build.Docker)
+	err = dockerExec.ExecInteractive(shellCommand, workDir, build.Env.Env)
+	if err != nil {
+		// Exit code of the shell is exit code of the last command run by user
+		logger.Warn("Shell exited with error - %s", err)
+	}
+	return nil
+}
+
+// computeCacheKey
+// Fills Git commit hash and image ID to CacheKeyInputs and sets the computed cache key to
+// BuiltPackage. Does nothing if BuildCache or CacheKeyInputs is nil.
+func (build *Build) computeCacheKey() error {
+	if build.BuildCache == nil || build.CacheKeyInputs == nil {
+		return nil
+	}
+	dockerImage := (*docker.DockerImage)(build.Docker)
+	imageId, err := dockerImage.GetImageId()
+	if err != nil {
+		return err
+	}
+	build.CacheKeyInputs.GitCommitHash = build.BuiltPackage.GitCommitHash
+	build.CacheKeyInputs.ImageId = imageId
+	build.BuiltPackage.CacheKey, err = build.CacheKeyInputs.ComputeKey()
+	if err != nil {
+		return fmt.Errorf("can't compute build cache key - %w", err)
+	}
+	return nil
+}
+
+// isInBuildCache
+// Returns true if the install tree of the Package is in build cache, else false.
+func (build *Build) isInBuildCache() bool {
+	return build.BuildCache != nil && build.BuiltPackage.CacheKey != "" && build.BuildCache.Contains(build.BuiltPackage.CacheKey)
+}
+
 func (build *Build) SetSysroot(sysroot *sysroot.Sysroot) {
 	build.sysroot = sysroot
 }
@@ -286,6 +553,23 @@ func (build *Build) GetLocalInstallDirPath() string {
 	return copyBaseDir
 }
 
+// keepFailedContainer
+// Keeps the container of the failed build running and prints how to connect to it.
+func (build *Build) keepFailedContainer() {
+	build.keepContainer = true
+	logger := log.GetLogger()
+	dockerExec := (*docker.DockerExec)(build.Docker)
+	logger.WarnIndent("Keeping container %s of the failed build, the sources are in %s", build.Docker.GetContainerId(), dockerGitCloneDirConst)
+	cmdLine := dockerExec.GetInteractiveCmdLine(shellCommand, dockerGitCloneDirConst, build.Env.Env)
+	for i, arg := range cmdLine {
+		if strings.ContainsAny(arg, " \t\"'") {
+			cmdLine[i] = strconv.Quote(arg)
+		}
+	}
+	logger.WarnIndent("Connect to the container by: %s", strings.Join(cmdLine, " "))
+	logger.WarnIndent("Remove the container by: %s rm -f %s", docker.GetRuntime().GetExecutablePath(), build.Docker.GetContainerId())
+}
+
 func (build *Build) stopAndRemoveContainer() error {
 	var err error
 
@@ -327,12 +611,7 @@ func (build *Build) downloadInstalledFiles() error {
 		}
 	}
 
-	sftpClient := ssh.SFTP{
-		RemoteDir:      constants.DockerInstallDirConst,
-		EmptyLocalDir:  copyDir,
-		SSHCredentials: build.SSHCredentials,
-	}
-
+	var logWriter io.Writer
 	packTarLogger := log.GetLogger().CreateContextLogger(build.Docker.ImageName, build.Package.GetShortPackageName(), log.TarContext)
 	if packTarLogger != nil {
 		logFile, err := packTarLogger.GetFile()
@@ -341,29 +620,27 @@ func (build *Build) downloadInstalledFiles() error {
 		}
 		defer logFile.Close()
 
-		sftpClient.LogWriter = logFile
+		logWriter = logFile
 	}
 
-	err = sftpClient.DownloadDirectory()
+	err = build.Executor.DownloadDirectory(constants.DockerInstallDirConst, copyDir, logWriter)
 	return err
 }
 
 func (build *Build) getGitCommitHash() (string, error) {
-	pipeReader, pipeWriter := io.Pipe()
-	defer pipeReader.Close()
-	defer pipeWriter.Close()
+	var output bytes.Buffer
 	gitGetHash := git.GitGetHash{Git: *build.Git}
 	shellEvaluator := ssh.ShellEvaluator{
 		Commands: gitGetHash.ConstructCMDLine(),
-		StdOut:   pipeWriter,
+		StdOut:   &output,
 	}
 
-	err := shellEvaluator.RunOverSSH(*build.SSHCredentials)
+	err := build.Executor.Run(&shellEvaluator)
 	if err != nil {
 		return "", err
 	}
 
-	buf := bufio.NewReader(pipeReader)
+	buf := bufio.NewReader(&output)
 	var line string
 
 	for {
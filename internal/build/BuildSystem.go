@@ -12,9 +12,13 @@ type BuildSystem struct {
 	SourceDir     string
 	InstallPrefix string
 	PrefixPath    string
+	// Jobs number of parallel build jobs, if 0 the default of the build tool is used
+	Jobs          int
 	CMake         *CMake
 	GNUMake       *GNUMake
 	Meson         *Meson
+	Autotools     *Autotools
+	Script        *Script
 }
 
 var optionRegexp *regexp.Regexp = regexp.MustCompilePOSIX("^[0-9a-zA-Z-]+$")
@@ -27,7 +31,7 @@ func (buildSystem *BuildSystem) FillDefault(args *prerequisites.Args) error {
 
 func (buildSystem *BuildSystem) FillDynamic(*prerequisites.Args) error {
 	var err error
-	if buildSystem.CMake != nil {
+	if buildSystem.CMake != nil || buildSystem.Autotools != nil {
 		buildSystem.GNUMake, err = prerequisites.CreateAndInitialize[GNUMake]()
 		if err != nil {
 			return err
@@ -38,12 +42,16 @@ func (buildSystem *BuildSystem) FillDynamic(*prerequisites.Args) error {
 }
 
 func (buildSystem *BuildSystem) CheckPrerequisites(*prerequisites.Args) error {
-	if buildSystem.CMake != nil && buildSystem.Meson != nil {
+	if buildSystem.getBuildSystemsCount() > 1 {
 		return fmt.Errorf("more than one build system specified")
 	} else if buildSystem.CMake != nil {
 		return buildSystem.CMake.CheckPrerequisites(nil)
 	} else if buildSystem.Meson != nil {
 		return buildSystem.Meson.CheckPrerequisites(nil)
+	} else if buildSystem.Autotools != nil {
+		return buildSystem.Autotools.CheckPrerequisites(nil)
+	} else if buildSystem.Script != nil {
+		return buildSystem.Script.CheckPrerequisites(nil)
 	}
 	return nil
 }
@@ -58,11 +66,27 @@ func (buildSystem *BuildSystem) ConstructCMDLine() []string {
 		return commands
 	} else if buildSystem.Meson != nil {
 		return buildSystem.Meson.ConstructCMDLine()
+	} else if buildSystem.Autotools != nil {
+		commands := buildSystem.Autotools.ConstructCMDLine()
+		commands = append(commands, buildSystem.GNUMake.ConstructCMDLine()...)
+		return commands
+	} else if buildSystem.Script != nil {
+		return buildSystem.Script.ConstructCMDLine()
 	} else {
 		return []string{}
 	}
 }
 
+// SetJobs
+// Sets number of parallel build jobs for all build tools. If jobs is 0, the default of the build
+// tool is used.
+func (buildSystem *BuildSystem) SetJobs(jobs int) {
+	buildSystem.Jobs = jobs
+	if buildSystem.GNUMake != nil {
+		buildSystem.GNUMake.Jobs = jobs
+	}
+}
+
 // UpdateBuildSystemPointers updates pointers to BuildSystem in all build system specific structs
 func (buildSystem *BuildSystem) UpdateBuildSystemPointers() {
 	if buildSystem.CMake != nil {
@@ -71,6 +95,30 @@ func (buildSystem *BuildSystem) UpdateBuildSystemPointers() {
 	if buildSystem.Meson != nil {
 		buildSystem.Meson.BuildSystem = buildSystem
 	}
+	if buildSystem.Autotools != nil {
+		buildSystem.Autotools.BuildSystem = buildSystem
+	}
+	if buildSystem.Script != nil {
+		buildSystem.Script.BuildSystem = buildSystem
+	}
+}
+
+// getBuildSystemsCount returns number of specified build systems
+func (buildSystem *BuildSystem) getBuildSystemsCount() int {
+	count := 0
+	if buildSystem.CMake != nil {
+		count++
+	}
+	if buildSystem.Meson != nil {
+		count++
+	}
+	if buildSystem.Autotools != nil {
+		count++
+	}
+	if buildSystem.Script != nil {
+		count++
+	}
+	return count
 }
 
 func escapeDefineValue(varValue string) string {
@@ -12,7 +12,7 @@ import (
 // CMake
 // Represents CMake build system. Its main task is to create a CMake command line.
 type CMake struct {
-	BuildSystem  *BuildSystem
+	BuildSystem  *BuildSystem `json:"-"`
 	Defines      map[string]string
 	CMakeListDir string
 }
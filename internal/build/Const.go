@@ -7,6 +7,15 @@ import (
 const (
 	// Where to clone a git repository on the remote machine
 	dockerGitCloneDirConst = string(filepath.Separator) + "git"
+	// Where the sysroot directory is mounted
+	dockerSysrootDirConst = string(filepath.Separator) + "sysroot"
+	// Where the repository checked out on the host is mounted read-only in offline build
+	dockerGitSourceDirConst = string(filepath.Separator) + "git-source"
+	// Where the git mirror of the repository is mounted read-only
+	dockerGitMirrorDirConst = string(filepath.Separator) + "git-mirror"
 	// Where to copy file from remote machine before the package is created
 	localInstallDirNameConst = string(filepath.Separator) + "localInstall"
 )
+
+// Interactive shell started in the container
+var shellCommand = []string{"bash", "-l"}
@@ -7,11 +7,15 @@ import (
 )
 
 const (
+	// Default number of make jobs
 	makeJobsCount = 10
 )
 
 // GNUMake cmd line interface for standard GNU Make utility
-type GNUMake struct {}
+type GNUMake struct {
+	// Jobs number of make jobs, if 0 makeJobsCount is used
+	Jobs int
+}
 
 func (make *GNUMake) FillDefault(*prerequisites.Args) error {
 	return nil
@@ -26,7 +30,11 @@ func (make *GNUMake) CheckPrerequisites(*prerequisites.Args) error {
 }
 
 func (make *GNUMake) ConstructCMDLine() []string {
-	cmdBuild := []string{"make", "-j", strconv.Itoa(makeJobsCount)}
+	jobs := make.Jobs
+	if jobs == 0 {
+		jobs = makeJobsCount
+	}
+	cmdBuild := []string{"make", "-j", strconv.Itoa(jobs)}
 	cmdInstall := []string{"make", "install"}
 	return []string{
 		strings.Join(cmdBuild, " "),
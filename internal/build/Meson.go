@@ -3,6 +3,7 @@ package build
 import (
 	"github.com/bacpack-system/packager/internal/prerequisites"
 	"fmt"
+	"strconv"
 	"strings"
 	"regexp"
 )
@@ -16,7 +17,7 @@ var mesonDefineRegexp *regexp.Regexp = regexp.MustCompilePOSIX("^[0-9a-zA-Z.:_-]
 // Meson
 // Represents Meson build system. Its main task is to create a Meson command line.
 type Meson struct {
-	BuildSystem *BuildSystem
+	BuildSystem *BuildSystem `json:"-"`
 	Options      map[string]string
 	Defines      map[string]string
 }
@@ -73,9 +74,17 @@ func (meson *Meson) ConstructCMDLine() []string {
 	cmdSetup = append(cmdSetup, meson.BuildSystem.SourceDir)
 
 	cmdInstall := []string{"meson", "install", "-C", mesonBuildDirConst}
-	
+
+	if meson.BuildSystem.Jobs == 0 {
+		return []string{
+			strings.Join(cmdSetup, " "),
+			strings.Join(cmdInstall, " "),
+		}
+	}
+	cmdCompile := []string{"meson", "compile", "-C", mesonBuildDirConst, "-j", strconv.Itoa(meson.BuildSystem.Jobs)}
 	return []string{
 		strings.Join(cmdSetup, " "),
+		strings.Join(cmdCompile, " "),
 		strings.Join(cmdInstall, " "),
 	}
 }
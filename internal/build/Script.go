@@ -0,0 +1,67 @@
+package build
+
+import (
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"fmt"
+	"strings"
+)
+
+// Script
+// Represents build system with hand-written commands for projects which do not fit any supported
+// build system. The commands are run in the source directory in the order Configure, Build,
+// Install. INSTALL_PREFIX, PREFIX_PATH and SOURCE_DIR environment variables are exported from
+// BuildSystem before the commands are run.
+type Script struct {
+	BuildSystem *BuildSystem `json:"-"`
+	// Configure commands which prepare the build
+	Configure   []string
+	// Build commands which build the project
+	Build       []string
+	// Install commands which install the project to INSTALL_PREFIX
+	Install     []string
+}
+
+func (script *Script) FillDefault(*prerequisites.Args) error {
+	script.Configure = []string{}
+	script.Build = []string{}
+	script.Install = []string{}
+	return nil
+}
+
+func (script *Script) FillDynamic(*prerequisites.Args) error {
+	return nil
+}
+
+func (script *Script) CheckPrerequisites(*prerequisites.Args) error {
+	if script.BuildSystem == nil {
+		return fmt.Errorf("BuildSystem is required for Script")
+	}
+	if len(script.Install) == 0 {
+		return fmt.Errorf("at least one Script Install command must be specified")
+	}
+	for _, command := range script.getCommands() {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("empty Script command")
+		}
+	}
+	return nil
+}
+
+func (script *Script) ConstructCMDLine() []string {
+	commands := []string{
+		"export INSTALL_PREFIX=" + escapeDefineValue(script.BuildSystem.InstallPrefix),
+		"export PREFIX_PATH=" + escapeDefineValue(script.BuildSystem.PrefixPath),
+		"export SOURCE_DIR=" + escapeDefineValue(script.BuildSystem.SourceDir),
+		"cd \"$SOURCE_DIR\"",
+	}
+	return append(commands, script.getCommands()...)
+}
+
+// getCommands returns Configure, Build and Install commands in this order
+func (script *Script) getCommands() []string {
+	var commands []string
+	commands = append(commands, script.Configure...)
+	commands = append(commands, script.Build...)
+	commands = append(commands, script.Install...)
+	return commands
+}
@@ -0,0 +1,22 @@
+package build
+
+import (
+	"fmt"
+)
+
+// SourceCopy copies the read-only mounted repository to a writable directory in the container,
+// so the build can write to the source tree. The owner of the files is not preserved, git refuses
+// to work in repository owned by other user.
+type SourceCopy struct {
+	// SourceDir directory with the mounted repository
+	SourceDir string
+	// DestDir directory where the repository is copied
+	DestDir string
+}
+
+func (sourceCopy *SourceCopy) ConstructCMDLine() []string {
+	return []string{
+		fmt.Sprintf("mkdir -p \"%s\"", sourceCopy.DestDir),
+		fmt.Sprintf("cp -R --preserve=mode,timestamps \"%s/.\" \"%s\"", sourceCopy.SourceDir, sourceCopy.DestDir),
+	}
+}
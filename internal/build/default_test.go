@@ -0,0 +1,201 @@
+package build
+
+import (
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"strings"
+	"testing"
+)
+
+const (
+	sourceDir     = "/git"
+	installPrefix = "/INSTALL"
+	prefixPath    = "/sysroot"
+)
+
+func initBuildSystem(buildSystem *BuildSystem) error {
+	err := prerequisites.Initialize(buildSystem)
+	if err != nil {
+		return err
+	}
+	buildSystem.SourceDir = sourceDir
+	buildSystem.InstallPrefix = installPrefix
+	buildSystem.PrefixPath = prefixPath
+	return nil
+}
+
+func TestAutotoolsConstructCMDLine(t *testing.T) {
+	buildSystem := BuildSystem{
+		Autotools: &Autotools{
+			ConfigureDir: "src",
+			Options: map[string]string{
+				"enable-shared": "",
+				"with-zlib":     "/usr",
+			},
+		},
+	}
+	err := initBuildSystem(&buildSystem)
+	if err != nil {
+		t.Fatalf("BuildSystem initialization failed - %s", err)
+	}
+	if buildSystem.GNUMake == nil {
+		t.Fatal("GNUMake is not created for Autotools")
+	}
+
+	commands := buildSystem.ConstructCMDLine()
+	if len(commands) != 4 {
+		t.Fatalf("wrong number of commands - %v", commands)
+	}
+	if commands[0] != "cd \"/git/src\"" {
+		t.Errorf("wrong configure directory command - %s", commands[0])
+	}
+	configure := commands[1]
+	expectedParts := []string{
+		"./configure ",
+		"--prefix=\"/INSTALL\"",
+		"--enable-shared",
+		"--with-zlib=\"/usr\"",
+		"\"PKG_CONFIG_PATH=/sysroot/lib/pkgconfig:",
+		"\"CPPFLAGS=-I/sysroot/include $CPPFLAGS\"",
+		"\"LDFLAGS=-L/sysroot/lib -L/sysroot/lib64 $LDFLAGS\"",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(configure, part) {
+			t.Errorf("configure command '%s' does not contain '%s'", configure, part)
+		}
+	}
+	if !strings.HasPrefix(commands[2], "make -j") || commands[3] != "make install" {
+		t.Errorf("wrong make commands - %v", commands[2:])
+	}
+}
+
+func TestBuildSystemJobs(t *testing.T) {
+	buildSystem := BuildSystem{
+		Autotools: &Autotools{},
+	}
+	err := initBuildSystem(&buildSystem)
+	if err != nil {
+		t.Fatalf("BuildSystem initialization failed - %s", err)
+	}
+	buildSystem.SetJobs(4)
+	commands := buildSystem.ConstructCMDLine()
+	if commands[len(commands) - 2] != "make -j 4" {
+		t.Errorf("wrong make command - %s", commands[len(commands) - 2])
+	}
+
+	buildSystem = BuildSystem{
+		Meson: &Meson{},
+	}
+	err = initBuildSystem(&buildSystem)
+	if err != nil {
+		t.Fatalf("BuildSystem initialization failed - %s", err)
+	}
+	if len(buildSystem.ConstructCMDLine()) != 2 {
+		t.Error("Meson compile command is used without jobs")
+	}
+	buildSystem.SetJobs(4)
+	commands = buildSystem.ConstructCMDLine()
+	if len(commands) != 3 || commands[1] != "meson compile -C build -j 4" {
+		t.Errorf("wrong Meson commands - %v", commands)
+	}
+}
+
+func TestAutotoolsAutoreconf(t *testing.T) {
+	buildSystem := BuildSystem{
+		Autotools: &Autotools{
+			Autoreconf: true,
+		},
+	}
+	err := initBuildSystem(&buildSystem)
+	if err != nil {
+		t.Fatalf("BuildSystem initialization failed - %s", err)
+	}
+	buildSystem.PrefixPath = ""
+
+	commands := buildSystem.ConstructCMDLine()
+	if len(commands) != 5 {
+		t.Fatalf("wrong number of commands - %v", commands)
+	}
+	if commands[0] != "cd \"/git\"" || commands[1] != "autoreconf --install --force" {
+		t.Errorf("wrong autoreconf commands - %v", commands[:2])
+	}
+	if strings.Contains(commands[2], "PKG_CONFIG_PATH") {
+		t.Errorf("prefix path variables are set without PrefixPath - %s", commands[2])
+	}
+}
+
+func TestAutotoolsInvalidOptions(t *testing.T) {
+	invalidOptions := []map[string]string{
+		{"prefix": "/usr"},
+		{"enable shared": ""},
+		{"with_zlib": "/usr"},
+	}
+	for _, options := range invalidOptions {
+		buildSystem := BuildSystem{
+			Autotools: &Autotools{
+				Options: options,
+			},
+		}
+		err := prerequisites.Initialize(&buildSystem)
+		if err == nil {
+			t.Errorf("invalid options %v accepted", options)
+		}
+	}
+}
+
+func TestMoreBuildSystems(t *testing.T) {
+	buildSystem := BuildSystem{
+		CMake:     &CMake{},
+		Autotools: &Autotools{},
+	}
+	err := prerequisites.Initialize(&buildSystem)
+	if err == nil {
+		t.Error("more than one build system accepted")
+	}
+}
+
+func TestScriptConstructCMDLine(t *testing.T) {
+	buildSystem := BuildSystem{
+		Script: &Script{
+			Configure: []string{"./Configure --prefix=$INSTALL_PREFIX"},
+			Build:     []string{"make -j 4"},
+			Install:   []string{"make install_sw"},
+		},
+	}
+	err := initBuildSystem(&buildSystem)
+	if err != nil {
+		t.Fatalf("BuildSystem initialization failed - %s", err)
+	}
+	if buildSystem.GNUMake != nil {
+		t.Error("GNUMake is created for Script")
+	}
+
+	commands := buildSystem.ConstructCMDLine()
+	expectedCommands := []string{
+		"export INSTALL_PREFIX=\"/INSTALL\"",
+		"export PREFIX_PATH=\"/sysroot\"",
+		"export SOURCE_DIR=\"/git\"",
+		"cd \"$SOURCE_DIR\"",
+		"./Configure --prefix=$INSTALL_PREFIX",
+		"make -j 4",
+		"make install_sw",
+	}
+	if strings.Join(commands, "\n") != strings.Join(expectedCommands, "\n") {
+		t.Errorf("wrong commands - %v", commands)
+	}
+}
+
+func TestScriptInvalid(t *testing.T) {
+	invalidScripts := []Script{
+		{Build: []string{"make"}},
+		{Build: []string{" "}, Install: []string{"make install"}},
+	}
+	for _, script := range invalidScripts {
+		buildSystem := BuildSystem{
+			Script: &script,
+		}
+		err := prerequisites.Initialize(&buildSystem)
+		if err == nil {
+			t.Errorf("invalid Script %v accepted", script)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package build_cache stores install trees of built Packages addressed by a key computed from all
+// build inputs, so an identical build can be restored instead of compiled again. The install trees
+// are stored in a local directory and optionally shared through an HTTP server.
+package build_cache
+
+import (
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"github.com/otiai10/copy"
+)
+
+const (
+	tmpDirPattern = ".tmp-*"
+)
+
+// BuildCache
+// Cache of install trees of built Packages. The local cache stores each install tree in
+// <CacheDir>/<key> directory. It is safe to use it from multiple goroutines and processes, the
+// install tree is stored to a temporary directory and then renamed. Optionally the install trees
+// are shared through the RemoteCache. At least one of the local and remote cache must be set.
+type BuildCache struct {
+	// CacheDir path to the local cache directory, it is created if it does not exist. If empty,
+	// only the remote cache is used.
+	CacheDir string
+	// Remote cache on HTTP server, if nil only the local cache is used
+	Remote   *RemoteCache
+}
+
+type buildCacheInitArgs struct {
+	CacheDir  string
+	RemoteUrl string
+}
+
+func (cache *BuildCache) FillDefault(*prerequisites.Args) error {
+	return nil
+}
+
+func (cache *BuildCache) FillDynamic(args *prerequisites.Args) error {
+	if !prerequisites.IsEmpty(args) {
+		var argsStruct buildCacheInitArgs
+		prerequisites.GetArgs(args, &argsStruct)
+		cache.CacheDir = argsStruct.CacheDir
+		if argsStruct.RemoteUrl != "" {
+			cache.Remote = &RemoteCache{
+				Url: argsStruct.RemoteUrl,
+			}
+		}
+	}
+	return nil
+}
+
+func (cache *BuildCache) CheckPrerequisites(*prerequisites.Args) error {
+	if cache.CacheDir == "" && cache.Remote == nil {
+		return fmt.Errorf("build cache directory or remote cache URL must be set")
+	}
+	if cache.Remote != nil && !strings.HasPrefix(cache.Remote.Url, "http://") && !strings.HasPrefix(cache.Remote.Url, "https://") {
+		return fmt.Errorf("remote cache URL '%s' must start with http:// or https://", cache.Remote.Url)
+	}
+	if cache.CacheDir == "" {
+		return nil
+	}
+	err := os.MkdirAll(cache.CacheDir, 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create build cache directory '%s' - %w", cache.CacheDir, err)
+	}
+	return nil
+}
+
+// Contains
+// Returns true if install tree for the key is in the local or remote cache, else false. If the
+// remote cache cannot be reached, a warning is printed and the key is considered missing.
+func (cache *BuildCache) Contains(key string) bool {
+	if cache.containsLocal(key) {
+		return true
+	}
+	if cache.Remote == nil {
+		return false
+	}
+	found, err := cache.Remote.Contains(key)
+	if err != nil {
+		log.GetLogger().WarnIndent("Cannot check remote build cache - %s", err)
+		return false
+	}
+	return found
+}
+
+// Restore
+// Copies install tree for the key from the cache to destDir. If the key is only in the remote
+// cache, it is downloaded and also stored to the local cache.
+func (cache *BuildCache) Restore(key string, destDir string) error {
+	if cache.containsLocal(key) {
+		err := copy.Copy(cache.getEntryPath(key), destDir, getCopyOptions())
+		if err != nil {
+			return fmt.Errorf("cannot restore %s from build cache - %w", key, err)
+		}
+		return nil
+	}
+	if cache.Remote == nil {
+		return fmt.Errorf("%s is not in build cache", key)
+	}
+	err := cache.Remote.Download(key, destDir)
+	if err != nil {
+		return fmt.Errorf("cannot download %s from remote build cache - %w", key, err)
+	}
+	if cache.CacheDir != "" {
+		err = cache.storeLocal(key, destDir)
+		if err != nil {
+			log.GetLogger().WarnIndent("Cannot store downloaded install files to local build cache - %s", err)
+		}
+	}
+	return nil
+}
+
+// Store
+// Copies install tree from sourceDir to the local cache and uploads it to the remote cache under
+// the key. If the key is already in the cache, nothing is done.
+func (cache *BuildCache) Store(key string, sourceDir string) error {
+	if cache.CacheDir != "" {
+		err := cache.storeLocal(key, sourceDir)
+		if err != nil {
+			return err
+		}
+	}
+	if cache.Remote == nil {
+		return nil
+	}
+	found, err := cache.Remote.Contains(key)
+	if err != nil {
+		return fmt.Errorf("cannot check remote build cache - %w", err)
+	}
+	if found {
+		return nil
+	}
+	err = cache.Remote.Upload(key, sourceDir)
+	if err != nil {
+		return fmt.Errorf("cannot upload %s to remote build cache - %w", key, err)
+	}
+	return nil
+}
+
+// containsLocal
+// Returns true if install tree for the key is in the local cache, else false.
+func (cache *BuildCache) containsLocal(key string) bool {
+	if cache.CacheDir == "" {
+		return false
+	}
+	info, err := os.Stat(cache.getEntryPath(key))
+	return err == nil && info.IsDir()
+}
+
+// storeLocal
+// Copies install tree from sourceDir to the local cache under the key. If the key is already in
+// the local cache, nothing is done.
+func (cache *BuildCache) storeLocal(key string, sourceDir string) error {
+	if cache.containsLocal(key) {
+		return nil
+	}
+	tmpDir, err := os.MkdirTemp(cache.CacheDir, tmpDirPattern)
+	if err != nil {
+		return fmt.Errorf("cannot create temporary directory in build cache - %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = copy.Copy(sourceDir, tmpDir, getCopyOptions())
+	if err != nil {
+		return fmt.Errorf("cannot store %s to build cache - %w", key, err)
+	}
+	err = os.Rename(tmpDir, cache.getEntryPath(key))
+	if err != nil && !cache.containsLocal(key) {
+		return fmt.Errorf("cannot store %s to build cache - %w", key, err)
+	}
+	return nil
+}
+
+// getEntryPath
+// Returns path to the install tree for the key.
+func (cache *BuildCache) getEntryPath(key string) string {
+	return filepath.Join(cache.CacheDir, key)
+}
+
+// getCopyOptions
+// Returns options for copying install trees, the symlinks are copied as symlinks.
+func getCopyOptions() copy.Options {
+	return copy.Options{
+		OnSymlink: func(string) copy.SymlinkAction {
+			return copy.Shallow
+		},
+		PreserveTimes: true,
+	}
+}
@@ -0,0 +1,47 @@
+package build_cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// DependencyHash
+// Identifies built dependency of the Package by hash of its archive.
+type DependencyHash struct {
+	Name          string
+	ArchiveSha256 string
+}
+
+// KeyInputs
+// All inputs of the Package build which affect the build result. Two builds with the same inputs
+// produce the same install tree, so the install tree can be restored from the cache.
+type KeyInputs struct {
+	// Config build system options, environment variables, Git and Package settings in JSON form
+	Config        json.RawMessage
+	// GitCommitHash commit hash of the built Package
+	GitCommitHash string
+	// ImageId ID of the docker image used for the build
+	ImageId       string
+	// Dependencies hashes of the dependency archives
+	Dependencies  []DependencyHash
+}
+
+// ComputeKey
+// Returns cache key for the inputs as hex encoded SHA-256 of the inputs in JSON form. The
+// GitCommitHash and ImageId must be filled.
+func (inputs *KeyInputs) ComputeKey() (string, error) {
+	if inputs.GitCommitHash == "" {
+		return "", fmt.Errorf("cannot compute cache key without Git commit hash")
+	}
+	if inputs.ImageId == "" {
+		return "", fmt.Errorf("cannot compute cache key without image ID")
+	}
+	bytes, err := json.Marshal(inputs)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(bytes)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,263 @@
+package build_cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	remoteEntryExt = ".tar.gz"
+	// DefaultRemoteTimeout default Timeout of the RemoteCache
+	DefaultRemoteTimeout = 60 * time.Second
+)
+
+// RemoteCache
+// Build cache on HTTP server. The install tree for the key is stored as gzipped tar at
+// <Url>/<key>.tar.gz. It is downloaded by GET and uploaded by PUT request, HEAD request is used
+// to check presence of the key. The Url may contain user and password for Basic authentication.
+// Each request is cancelled if the server does not respond or no data is transferred for Timeout,
+// so an unresponsive server cannot block the build.
+type RemoteCache struct {
+	Url     string
+	// Timeout of the server response and of each data transfer pause, DefaultRemoteTimeout if zero
+	Timeout time.Duration
+	client  http.Client
+}
+
+// Contains
+// Returns true if install tree for the key is on the server, else false.
+func (remote *RemoteCache) Contains(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.getTimeout())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, remote.getEntryUrl(key), nil)
+	if err != nil {
+		return false, err
+	}
+	response, err := remote.client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected HTTP status %s", response.Status)
+}
+
+// Download
+// Downloads install tree for the key from the server and extracts it to destDir.
+func (remote *RemoteCache) Download(key string, destDir string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, remote.getEntryUrl(key), nil)
+	if err != nil {
+		return err
+	}
+	idleTimer := time.AfterFunc(remote.getTimeout(), cancel)
+	defer idleTimer.Stop()
+	response, err := remote.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status %s", response.Status)
+	}
+	return extractTarGz(&idleTimeoutReader{reader: response.Body, timer: idleTimer, timeout: remote.getTimeout()}, destDir)
+}
+
+// Upload
+// Uploads install tree from sourceDir to the server under the key.
+func (remote *RemoteCache) Upload(key string, sourceDir string) error {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(createTarGz(sourceDir, pipeWriter))
+	}()
+	defer pipeReader.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	idleTimer := time.AfterFunc(remote.getTimeout(), cancel)
+	defer idleTimer.Stop()
+	body := &idleTimeoutReader{reader: pipeReader, timer: idleTimer, timeout: remote.getTimeout()}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, remote.getEntryUrl(key), body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/gzip")
+	response, err := remote.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected HTTP status %s", response.Status)
+	}
+	return nil
+}
+
+// GetRedactedUrl
+// Returns Url with the password replaced by "xxxxx", so it can be printed.
+func (remote *RemoteCache) GetRedactedUrl() string {
+	parsedUrl, err := url.Parse(remote.Url)
+	if err != nil {
+		return remote.Url
+	}
+	return parsedUrl.Redacted()
+}
+
+// getTimeout
+// Returns Timeout or DefaultRemoteTimeout if Timeout is not set.
+func (remote *RemoteCache) getTimeout() time.Duration {
+	if remote.Timeout <= 0 {
+		return DefaultRemoteTimeout
+	}
+	return remote.Timeout
+}
+
+// idleTimeoutReader
+// Restarts the timer with timeout after each read of data from reader. The timer cancels the
+// request when the transfer is stalled.
+type idleTimeoutReader struct {
+	reader  io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (idleReader *idleTimeoutReader) Read(buffer []byte) (int, error) {
+	n, err := idleReader.reader.Read(buffer)
+	if n > 0 {
+		idleReader.timer.Reset(idleReader.timeout)
+	}
+	return n, err
+}
+
+// getEntryUrl
+// Returns URL of the install tree for the key.
+func (remote *RemoteCache) getEntryUrl(key string) string {
+	return strings.TrimSuffix(remote.Url, "/") + "/" + key + remoteEntryExt
+}
+
+// createTarGz
+// Writes all files, directories and symlinks in sourceDir to writer as gzipped tar.
+func createTarGz(sourceDir string, writer io.Writer) error {
+	gzipWriter := gzip.NewWriter(writer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	err := filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		linkTarget := ""
+		if info.Mode() & os.ModeSymlink != 0 {
+			linkTarget, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, linkTarget)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		err = tarWriter.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = tarWriter.Close()
+	if err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// extractTarGz
+// Extracts gzipped tar from reader to destDir. Returns error if any file would be extracted
+// outside of destDir or any symlink points outside of destDir.
+func extractTarGz(reader io.Reader, destDir string) error {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(header.Name) {
+			return fmt.Errorf("invalid path %s in archive", header.Name)
+		}
+		path := filepath.Join(destDir, header.Name)
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, header.FileInfo().Mode().Perm())
+		case tar.TypeSymlink:
+			if !filepath.IsLocal(filepath.Join(filepath.Dir(header.Name), header.Linkname)) {
+				return fmt.Errorf("symlink %s in archive points outside of the archive", header.Name)
+			}
+			err = os.Symlink(header.Linkname, path)
+		case tar.TypeReg:
+			err = extractFile(tarReader, path, header.FileInfo().Mode().Perm())
+		default:
+			err = fmt.Errorf("unsupported type of %s in archive", header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractFile
+// Writes content of reader to a new file on path.
+func extractFile(reader io.Reader, path string, mode fs.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE | os.O_WRONLY | os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, reader)
+	return err
+}
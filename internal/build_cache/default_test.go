@@ -0,0 +1,281 @@
+package build_cache
+
+import (
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	cacheDir = "test_build_cache"
+	installDir = "test_install"
+	restoreDir = "test_restore"
+	fileName = "lib/libpack.so"
+	fileContent = "content"
+)
+
+func getKeyInputs() KeyInputs {
+	return KeyInputs{
+		Config:        []byte(`{"Build":{"CMake":{"Defines":{"BUILD_SHARED_LIBS":"ON"}}}}`),
+		GitCommitHash: "0123456789abcdef",
+		ImageId:       "sha256:image",
+		Dependencies:  []DependencyHash{{Name: "dep", ArchiveSha256: "hash"}},
+	}
+}
+
+func TestComputeKey(t *testing.T) {
+	inputs := getKeyInputs()
+	key, err := inputs.ComputeKey()
+	if err != nil {
+		t.Fatalf("ComputeKey failed - %s", err)
+	}
+	sameInputs := getKeyInputs()
+	sameKey, err := sameInputs.ComputeKey()
+	if err != nil {
+		t.Fatalf("ComputeKey failed - %s", err)
+	}
+	if key != sameKey {
+		t.Error("same inputs have different keys")
+	}
+
+	changes := []func(inputs *KeyInputs){
+		func(inputs *KeyInputs) { inputs.Config = []byte(`{"Build":{"CMake":{"Defines":{"BUILD_SHARED_LIBS":"OFF"}}}}`) },
+		func(inputs *KeyInputs) { inputs.GitCommitHash = "fedcba9876543210" },
+		func(inputs *KeyInputs) { inputs.ImageId = "sha256:other" },
+		func(inputs *KeyInputs) { inputs.Dependencies[0].ArchiveSha256 = "other" },
+	}
+	for i, change := range changes {
+		changedInputs := getKeyInputs()
+		change(&changedInputs)
+		changedKey, err := changedInputs.ComputeKey()
+		if err != nil {
+			t.Fatalf("ComputeKey failed - %s", err)
+		}
+		if changedKey == key {
+			t.Errorf("change %d of inputs does not change the key", i)
+		}
+	}
+}
+
+func TestComputeKeyMissingInputs(t *testing.T) {
+	inputs := getKeyInputs()
+	inputs.ImageId = ""
+	_, err := inputs.ComputeKey()
+	if err == nil {
+		t.Error("key computed without image ID")
+	}
+}
+
+func TestStoreAndRestore(t *testing.T) {
+	defer os.RemoveAll(cacheDir)
+	defer os.RemoveAll(installDir)
+	defer os.RemoveAll(restoreDir)
+
+	cache, err := prerequisites.CreateAndInitialize[BuildCache](cacheDir, "")
+	if err != nil {
+		t.Fatalf("build cache initialization failed - %s", err)
+	}
+	err = os.MkdirAll(filepath.Join(installDir, filepath.Dir(fileName)), 0755)
+	if err != nil {
+		t.Fatalf("can't create install dir - %s", err)
+	}
+	err = os.WriteFile(filepath.Join(installDir, fileName), []byte(fileContent), 0644)
+	if err != nil {
+		t.Fatalf("can't create file - %s", err)
+	}
+
+	if cache.Contains("key") {
+		t.Error("empty cache contains key")
+	}
+	err = cache.Store("key", installDir)
+	if err != nil {
+		t.Fatalf("Store failed - %s", err)
+	}
+	if !cache.Contains("key") {
+		t.Error("cache does not contain stored key")
+	}
+	err = cache.Store("key", installDir)
+	if err != nil {
+		t.Errorf("Store of already stored key failed - %s", err)
+	}
+
+	err = cache.Restore("key", restoreDir)
+	if err != nil {
+		t.Fatalf("Restore failed - %s", err)
+	}
+	content, err := os.ReadFile(filepath.Join(restoreDir, fileName))
+	if err != nil || string(content) != fileContent {
+		t.Error("restored file differs")
+	}
+	err = cache.Restore("other", restoreDir)
+	if err == nil {
+		t.Error("Restore of missing key succeeded")
+	}
+}
+
+func TestInitializeEmpty(t *testing.T) {
+	var cache BuildCache
+	err := prerequisites.Initialize(&cache)
+	if err == nil {
+		t.Error("build cache without CacheDir initialized")
+	}
+}
+
+// createRemoteServer
+// Returns HTTP server which stores uploaded entries in memory.
+func createRemoteServer() *httptest.Server {
+	var lock sync.Mutex
+	entries := make(map[string][]byte)
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch request.Method {
+		case http.MethodPut:
+			content, err := io.ReadAll(request.Body)
+			if err != nil {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			entries[request.URL.Path] = content
+			writer.WriteHeader(http.StatusCreated)
+		case http.MethodGet, http.MethodHead:
+			content, found := entries[request.URL.Path]
+			if !found {
+				writer.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writer.WriteHeader(http.StatusOK)
+			if request.Method == http.MethodGet {
+				writer.Write(content)
+			}
+		default:
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+func TestRemoteStoreAndRestore(t *testing.T) {
+	defer os.RemoveAll(cacheDir)
+	defer os.RemoveAll(installDir)
+	defer os.RemoveAll(restoreDir)
+	server := createRemoteServer()
+	defer server.Close()
+
+	err := os.MkdirAll(filepath.Join(installDir, filepath.Dir(fileName)), 0755)
+	if err != nil {
+		t.Fatalf("can't create install dir - %s", err)
+	}
+	err = os.WriteFile(filepath.Join(installDir, fileName), []byte(fileContent), 0644)
+	if err != nil {
+		t.Fatalf("can't create file - %s", err)
+	}
+	err = os.Symlink(filepath.Base(fileName), filepath.Join(installDir, fileName + ".1"))
+	if err != nil {
+		t.Fatalf("can't create symlink - %s", err)
+	}
+
+	remoteOnlyCache, err := prerequisites.CreateAndInitialize[BuildCache]("", server.URL)
+	if err != nil {
+		t.Fatalf("build cache initialization failed - %s", err)
+	}
+	if remoteOnlyCache.Contains("key") {
+		t.Error("empty remote cache contains key")
+	}
+	err = remoteOnlyCache.Store("key", installDir)
+	if err != nil {
+		t.Fatalf("Store failed - %s", err)
+	}
+
+	cache, err := prerequisites.CreateAndInitialize[BuildCache](cacheDir, server.URL + "/")
+	if err != nil {
+		t.Fatalf("build cache initialization failed - %s", err)
+	}
+	if !cache.Contains("key") {
+		t.Fatal("cache does not contain key stored in remote cache")
+	}
+	err = cache.Restore("key", restoreDir)
+	if err != nil {
+		t.Fatalf("Restore failed - %s", err)
+	}
+	content, err := os.ReadFile(filepath.Join(restoreDir, fileName + ".1"))
+	if err != nil || string(content) != fileContent {
+		t.Error("restored file differs")
+	}
+	if !cache.containsLocal("key") {
+		t.Error("downloaded install files are not stored in local cache")
+	}
+}
+
+func TestRemoteTimeout(t *testing.T) {
+	defer os.RemoveAll(restoreDir)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodGet {
+			// Response with stalled transfer of the content
+			writer.WriteHeader(http.StatusOK)
+			writer.Write([]byte{0x1f})
+			writer.(http.Flusher).Flush()
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cache := BuildCache{
+		Remote: &RemoteCache{
+			Url:     server.URL,
+			Timeout: 100 * time.Millisecond,
+		},
+	}
+	start := time.Now()
+	if cache.Contains("key") {
+		t.Error("not responding remote cache contains key")
+	}
+	err := cache.Restore("key", restoreDir)
+	if err == nil {
+		t.Error("Restore from stalled remote cache succeeded")
+	}
+	if time.Since(start) > 5 * time.Second {
+		t.Error("requests to not responding remote cache are not cancelled")
+	}
+}
+
+func TestInitializeInvalidRemoteUrl(t *testing.T) {
+	_, err := prerequisites.CreateAndInitialize[BuildCache]("", "ftp://server")
+	if err == nil {
+		t.Error("build cache with invalid remote URL initialized")
+	}
+}
+
+func TestExtractOutsideOfDir(t *testing.T) {
+	defer os.RemoveAll(restoreDir)
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	err := tarWriter.WriteHeader(&tar.Header{
+		Name:     "../outside",
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Size:     int64(len(fileContent)),
+	})
+	if err != nil {
+		t.Fatalf("can't write tar header - %s", err)
+	}
+	tarWriter.Write([]byte(fileContent))
+	tarWriter.Close()
+	gzipWriter.Close()
+
+	err = extractTarGz(&buffer, restoreDir)
+	if err == nil {
+		t.Error("file outside of destination directory extracted")
+	}
+}
@@ -0,0 +1,203 @@
+// Package build_session persists progress of Package builds, so an interrupted or failed build
+// can be resumed without building already completed Packages again.
+package build_session
+
+import (
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+const (
+	buildSessionFileName = "build_session.json"
+	indent               = "\x20\x20\x20\x20" // four spaces
+)
+
+// sessionFileLock serializes access to build_session.json, which is shared by all BuildSessions.
+var sessionFileLock sync.Mutex
+
+// SessionPackage
+// Identifies one Package Config in build session.
+type SessionPackage struct {
+	Name    string
+	IsDebug bool
+}
+
+// BuildSession
+// Represents state of Package builds for one image, platform string and build target. The state is
+// stored in build_session.json file in the working directory together with sessions for other
+// images and targets. It is safe to mark Packages from multiple goroutines.
+type BuildSession struct {
+	// ImageName name of the docker image the Packages are built for
+	ImageName      string
+	// PlatformString serialized platform string of the image
+	PlatformString string
+	// Target identifies Packages built in the session (e.g. all Packages or single Package), the
+	// builds of different targets do not affect each other sessions
+	Target         string
+	// Completed Packages which are built and stored in sysroot and Package Repository
+	Completed      []SessionPackage
+	// Failed Packages which build failed
+	Failed         []SessionPackage
+}
+
+func (session *BuildSession) FillDefault(*prerequisites.Args) error {
+	return nil
+}
+
+// FillDynamic
+// Loads the session state for ImageName, PlatformString and Target from build session file. If
+// there is no saved session, the session is empty.
+func (session *BuildSession) FillDynamic(*prerequisites.Args) error {
+	if session.ImageName == "" || session.PlatformString == "" {
+		return nil
+	}
+	sessionFileLock.Lock()
+	defer sessionFileLock.Unlock()
+	sessions, err := readSessions()
+	if err != nil {
+		return err
+	}
+	session.Completed = []SessionPackage{}
+	session.Failed = []SessionPackage{}
+	index := session.findIn(sessions)
+	if index >= 0 {
+		session.Completed = sessions[index].Completed
+		session.Failed = sessions[index].Failed
+	}
+	return nil
+}
+
+func (session *BuildSession) CheckPrerequisites(*prerequisites.Args) error {
+	if session.ImageName == "" {
+		return fmt.Errorf("build session ImageName cannot be empty")
+	}
+	if session.PlatformString == "" {
+		return fmt.Errorf("build session PlatformString cannot be empty")
+	}
+	return nil
+}
+
+// IsCompleted
+// Returns true if the Package is completed in the session.
+func (session *BuildSession) IsCompleted(packageName string, isDebug bool) bool {
+	sessionFileLock.Lock()
+	defer sessionFileLock.Unlock()
+	return slices.Contains(session.Completed, SessionPackage{Name: packageName, IsDebug: isDebug})
+}
+
+// IsEmpty
+// Returns true if no Package is completed or failed in the session.
+func (session *BuildSession) IsEmpty() bool {
+	sessionFileLock.Lock()
+	defer sessionFileLock.Unlock()
+	return len(session.Completed) == 0 && len(session.Failed) == 0
+}
+
+// MarkCompleted
+// Marks the Package as completed and saves the session.
+func (session *BuildSession) MarkCompleted(packageName string, isDebug bool) error {
+	sessionFileLock.Lock()
+	defer sessionFileLock.Unlock()
+	pack := SessionPackage{Name: packageName, IsDebug: isDebug}
+	session.Failed = slices.DeleteFunc(session.Failed, func(p SessionPackage) bool { return p == pack })
+	if !slices.Contains(session.Completed, pack) {
+		session.Completed = append(session.Completed, pack)
+	}
+	return session.save()
+}
+
+// MarkFailed
+// Marks the Package as failed and saves the session.
+func (session *BuildSession) MarkFailed(packageName string, isDebug bool) error {
+	sessionFileLock.Lock()
+	defer sessionFileLock.Unlock()
+	pack := SessionPackage{Name: packageName, IsDebug: isDebug}
+	session.Completed = slices.DeleteFunc(session.Completed, func(p SessionPackage) bool { return p == pack })
+	if !slices.Contains(session.Failed, pack) {
+		session.Failed = append(session.Failed, pack)
+	}
+	return session.save()
+}
+
+// Reset
+// Clears all completed and failed Packages and removes the session from build session file.
+func (session *BuildSession) Reset() error {
+	sessionFileLock.Lock()
+	defer sessionFileLock.Unlock()
+	session.Completed = []SessionPackage{}
+	session.Failed = []SessionPackage{}
+	sessions, err := readSessions()
+	if err != nil {
+		return err
+	}
+	index := session.findIn(sessions)
+	if index < 0 {
+		return nil
+	}
+	sessions = slices.Delete(sessions, index, index + 1)
+	return writeSessions(sessions)
+}
+
+// save
+// Saves the session to build session file. Sessions for other images and targets are preserved.
+func (session *BuildSession) save() error {
+	sessions, err := readSessions()
+	if err != nil {
+		return err
+	}
+	index := session.findIn(sessions)
+	if index < 0 {
+		sessions = append(sessions, *session)
+	} else {
+		sessions[index] = *session
+	}
+	return writeSessions(sessions)
+}
+
+// findIn
+// Returns index of the session with the same ImageName, PlatformString and Target in sessions or
+// -1.
+func (session *BuildSession) findIn(sessions []BuildSession) int {
+	return slices.IndexFunc(sessions, func(s BuildSession) bool {
+		return s.ImageName == session.ImageName && s.PlatformString == session.PlatformString && s.Target == session.Target
+	})
+}
+
+// readSessions
+// Reads all sessions from build session file. If the file does not exist, no sessions are
+// returned.
+func readSessions() ([]BuildSession, error) {
+	var sessions []BuildSession
+	bytes, err := os.ReadFile(buildSessionFileName)
+	if os.IsNotExist(err) {
+		return sessions, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read build session file - %w", err)
+	}
+	err = json.Unmarshal(bytes, &sessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse build session file - %w", err)
+	}
+	return sessions, nil
+}
+
+// writeSessions
+// Writes sessions to build session file. If there are no sessions, the file is removed.
+func writeSessions(sessions []BuildSession) error {
+	if len(sessions) == 0 {
+		err := os.Remove(buildSessionFileName)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove build session file - %w", err)
+		}
+		return nil
+	}
+	bytes, err := json.MarshalIndent(sessions, "", indent)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(buildSessionFileName, bytes, 0644)
+}
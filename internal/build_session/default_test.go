@@ -0,0 +1,158 @@
+package build_session
+
+import (
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"os"
+	"testing"
+)
+
+const (
+	Image1Name = "image1"
+	Image2Name = "image2"
+	PlatformString = "machine-distro-1.0"
+	Pack1Name = "pack1"
+	Pack2Name = "pack2"
+	AllTarget = "all"
+)
+
+func initSession(imageName string) (*BuildSession, error) {
+	session := BuildSession{
+		ImageName:      imageName,
+		PlatformString: PlatformString,
+	}
+	err := prerequisites.Initialize(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func TestInitializeEmpty(t *testing.T) {
+	var session BuildSession
+	err := prerequisites.Initialize(&session)
+	if err == nil {
+		t.Error("session without ImageName and PlatformString initialized")
+	}
+}
+
+func TestMarkAndLoad(t *testing.T) {
+	session, err := initSession(Image1Name)
+	if err != nil {
+		t.Fatalf("session initialization failed - %s", err)
+	}
+	if !session.IsEmpty() {
+		t.Error("new session is not empty")
+	}
+	err = session.MarkCompleted(Pack1Name, false)
+	if err != nil {
+		t.Fatalf("MarkCompleted failed - %s", err)
+	}
+	err = session.MarkFailed(Pack2Name, true)
+	if err != nil {
+		t.Fatalf("MarkFailed failed - %s", err)
+	}
+
+	loadedSession, err := initSession(Image1Name)
+	if err != nil {
+		t.Fatalf("session initialization failed - %s", err)
+	}
+	if !loadedSession.IsCompleted(Pack1Name, false) {
+		t.Errorf("%s is not completed in loaded session", Pack1Name)
+	}
+	if loadedSession.IsCompleted(Pack1Name, true) || loadedSession.IsCompleted(Pack2Name, true) {
+		t.Error("not completed Package is completed in loaded session")
+	}
+	if len(loadedSession.Failed) != 1 {
+		t.Errorf("wrong number of failed Packages - %d", len(loadedSession.Failed))
+	}
+
+	otherSession, err := initSession(Image2Name)
+	if err != nil {
+		t.Fatalf("session initialization failed - %s", err)
+	}
+	if !otherSession.IsEmpty() {
+		t.Error("session for other image is not empty")
+	}
+
+	err = loadedSession.MarkCompleted(Pack2Name, true)
+	if err != nil {
+		t.Fatalf("MarkCompleted failed - %s", err)
+	}
+	if len(loadedSession.Failed) != 0 || !loadedSession.IsCompleted(Pack2Name, true) {
+		t.Error("failed Package is not moved to completed")
+	}
+
+	err = loadedSession.Reset()
+	if err != nil {
+		t.Fatalf("Reset failed - %s", err)
+	}
+	_, err = os.Stat(buildSessionFileName)
+	if !os.IsNotExist(err) {
+		t.Error("build session file exists after reset of the last session")
+	}
+}
+
+func TestResetOtherTarget(t *testing.T) {
+	// Failed build of all Packages
+	allSession := BuildSession{
+		ImageName:      Image1Name,
+		PlatformString: PlatformString,
+		Target:         AllTarget,
+	}
+	err := prerequisites.Initialize(&allSession)
+	if err != nil {
+		t.Fatalf("session initialization failed - %s", err)
+	}
+	err = allSession.MarkCompleted(Pack1Name, false)
+	if err != nil {
+		t.Fatalf("MarkCompleted failed - %s", err)
+	}
+	err = allSession.MarkFailed(Pack2Name, false)
+	if err != nil {
+		t.Fatalf("MarkFailed failed - %s", err)
+	}
+
+	// Successful build of single Package without resume
+	singleSession := BuildSession{
+		ImageName:      Image1Name,
+		PlatformString: PlatformString,
+		Target:         Pack2Name,
+	}
+	err = prerequisites.Initialize(&singleSession)
+	if err != nil {
+		t.Fatalf("session initialization failed - %s", err)
+	}
+	if !singleSession.IsEmpty() {
+		t.Error("session of other target is not empty")
+	}
+	err = singleSession.Reset()
+	if err != nil {
+		t.Fatalf("Reset failed - %s", err)
+	}
+	err = singleSession.MarkCompleted(Pack2Name, false)
+	if err != nil {
+		t.Fatalf("MarkCompleted failed - %s", err)
+	}
+	err = singleSession.Reset()
+	if err != nil {
+		t.Fatalf("Reset failed - %s", err)
+	}
+
+	// Resume of the build of all Packages
+	resumedSession := BuildSession{
+		ImageName:      Image1Name,
+		PlatformString: PlatformString,
+		Target:         AllTarget,
+	}
+	err = prerequisites.Initialize(&resumedSession)
+	if err != nil {
+		t.Fatalf("session initialization failed - %s", err)
+	}
+	if !resumedSession.IsCompleted(Pack1Name, false) || len(resumedSession.Failed) != 1 {
+		t.Error("session of all Packages is lost after build of single Package")
+	}
+	err = resumedSession.Reset()
+	if err != nil {
+		t.Fatalf("Reset failed - %s", err)
+	}
+}
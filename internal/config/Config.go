@@ -4,12 +4,14 @@ import (
 	"github.com/bacpack-system/packager/internal/build"
 	"github.com/bacpack-system/packager/internal/constants"
 	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/executor"
 	"github.com/bacpack-system/packager/internal/git"
 	"github.com/bacpack-system/packager/internal/bacpack_package"
 	"github.com/bacpack-system/packager/internal/prerequisites"
 	"github.com/bacpack-system/packager/internal/sysroot"
 	"github.com/bacpack-system/packager/internal/ssh"
 	"encoding/json"
+	"fmt"
 	"os"
 	"bytes"
 )
@@ -18,8 +20,10 @@ import (
 // It stores configuration for given build system
 // (CMake, autoconf, ...)
 type Build struct {
-	CMake *build.CMake
-	Meson *build.Meson
+	CMake     *build.CMake
+	Meson     *build.Meson
+	Autotools *build.Autotools
+	Script    *build.Script
 }
 
 type DockerMatrix struct {
@@ -35,6 +39,8 @@ type Config struct {
 	Package      bacpack_package.Package
 	DockerMatrix DockerMatrix
 	DependsOn    []string
+	// Resources overrides resource limits of the Image for the Package
+	Resources    *docker.Resources `json:",omitempty"`
 	BuildSystem  build.BuildSystem `json:"-"`
 }
 
@@ -74,9 +80,17 @@ func (config *Config) LoadJSONConfig(configPath string) error {
 }
 
 func (config *Config) initConfig() error {
+	if config.Resources != nil {
+		err := config.Resources.CheckResources()
+		if err != nil {
+			return fmt.Errorf("invalid Resources - %w", err)
+		}
+	}
 	config.BuildSystem = build.BuildSystem{
-		CMake: config.Build.CMake,
-		Meson: config.Build.Meson,
+		CMake:     config.Build.CMake,
+		Meson:     config.Build.Meson,
+		Autotools: config.Build.Autotools,
+		Script:    config.Build.Script,
 	}
 	err := prerequisites.Initialize(&config.BuildSystem)
 	return err
@@ -94,6 +108,37 @@ func (config *Config) SaveToJSONConfig(configPath string) error {
 	return nil
 }
 
+// GetCacheKeyConfig
+// Returns build system options, environment variables, Git and Package settings in JSON form for
+// the build cache key. The DockerMatrix and platform string are not included, they are represented
+// by the image ID.
+func (config *Config) GetCacheKeyConfig() (json.RawMessage, error) {
+	cacheKeyConfig := struct {
+		Env       map[string]string
+		Git       git.Git
+		Build     Build
+		Package   struct {
+			Name       string
+			VersionTag string
+			IsLibrary  bool
+			IsDevLib   bool
+			IsDebug    bool
+		}
+		DependsOn []string
+	}{
+		Env:       config.Env,
+		Git:       config.Git,
+		Build:     config.Build,
+		DependsOn: config.DependsOn,
+	}
+	cacheKeyConfig.Package.Name = config.Package.Name
+	cacheKeyConfig.Package.VersionTag = config.Package.VersionTag
+	cacheKeyConfig.Package.IsLibrary = config.Package.IsLibrary
+	cacheKeyConfig.Package.IsDevLib = config.Package.IsDevLib
+	cacheKeyConfig.Package.IsDebug = config.Package.IsDebug
+	return json.Marshal(cacheKeyConfig)
+}
+
 // Returns array of builds structs for specific image name. The returned array will contain max one build.
 // It is an array for simple handling of result using for loop.
 func (config *Config) GetBuildStructure(
@@ -102,13 +147,14 @@ func (config *Config) GetBuildStructure(
 	dockerPort     uint16,
 	useLocalRepo   bool,
 	repoPath       string,
+	imageConfig    ImageConfig,
 ) ([]build.Build, error) {
 	var buildConfigs []build.Build
 	for _, value := range config.DockerMatrix.ImageNames {
 		if imageName != "" && imageName != value {
 			continue
 		}
-		build_obj, err := config.fillBuildStructure(imageName, platformString, dockerPort, useLocalRepo, repoPath)
+		build_obj, err := config.fillBuildStructure(imageName, platformString, dockerPort, useLocalRepo, repoPath, imageConfig)
 		if err != nil {
 			return []build.Build{}, err
 		}
@@ -127,6 +173,7 @@ func (config *Config) fillBuildStructure(
 	dockerPort      uint16,
 	useLocalRepo    bool,
 	repoPath        string,
+	imageConfig     ImageConfig,
 ) (build.Build, error) {
 	var err error
 	defaultDocker, err := prerequisites.CreateAndInitialize[docker.Docker](dockerImageName, dockerPort)
@@ -143,6 +190,12 @@ func (config *Config) fillBuildStructure(
 	if err != nil {
 		return build.Build{}, err
 	}
+	defaultDocker.Resources = imageConfig.Resources.Override(config.Resources)
+	config.BuildSystem.SetJobs(defaultDocker.Resources.CPUs)
+	containerExecutor, err := executor.CreateExecutor(imageConfig.Executor, defaultDocker, defaultSSHCredentials)
+	if err != nil {
+		return build.Build{}, err
+	}
 
 	env := &build.EnvironmentVariables{
 		Env: config.Env,
@@ -161,6 +214,7 @@ func (config *Config) fillBuildStructure(
 		"",                                 // Will be filled later after build will have valid sysroot
 		config.Git.URI,
 		constants.EmptyGitCommitHash, // Will be filled later when the hash is retrieved from docker container
+		"",                           // Will be filled later when the cache key inputs are known
 	)
 
 	tmpPackage := config.Package
@@ -178,9 +232,11 @@ func (config *Config) fillBuildStructure(
 		Git:            &config.Git,
 		BuildSystem:    &config.BuildSystem,
 		SSHCredentials: defaultSSHCredentials,
+		Executor:       containerExecutor,
 		Package:        &tmpPackage,
 		BuiltPackage:   builtPackage,
 		UseLocalRepo:   useLocalRepo,
+		Offline:        imageConfig.Offline,
 	}
 
 	err = prerequisites.Initialize(build_obj)
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/executor"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+const (
+	// ImageConfigFileName name of the optional Image config file next to the Dockerfile
+	ImageConfigFileName = "image.json"
+)
+
+// ImageConfig
+// Optional settings of the Image loaded from image.json file next to the Dockerfile.
+type ImageConfig struct {
+	// Executor which runs the build commands in the container, one of executor.Types(). If empty,
+	// SSH is used.
+	Executor  string
+	// Resources limits of the containers, the Package Config can override them
+	Resources docker.Resources
+	// Offline if true, the Package repositories are cloned on the host and the containers run
+	// without network access. Requires docker-exec Executor.
+	Offline   bool
+}
+
+// LoadImageConfig
+// Loads ImageConfig from the JSON file on imageConfigPath and checks its values.
+func LoadImageConfig(imageConfigPath string) (ImageConfig, error) {
+	var imageConfig ImageConfig
+	mbytes, err := os.ReadFile(imageConfigPath)
+	if err != nil {
+		return imageConfig, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(mbytes))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(&imageConfig)
+	if err != nil {
+		return imageConfig, err
+	}
+
+	if imageConfig.Executor != "" && !slices.Contains(executor.Types(), imageConfig.Executor) {
+		return imageConfig, fmt.Errorf("unsupported Executor '%s', supported are %v", imageConfig.Executor, executor.Types())
+	}
+	err = imageConfig.Resources.CheckResources()
+	if err != nil {
+		return imageConfig, fmt.Errorf("invalid Resources - %w", err)
+	}
+	return imageConfig, nil
+}
@@ -3,15 +3,22 @@ package context
 import (
 	"github.com/bacpack-system/packager/internal/config"
 	"github.com/bacpack-system/packager/internal/constants"
+	"github.com/bacpack-system/packager/internal/docker"
 	"github.com/bacpack-system/packager/internal/log"
 	"github.com/bacpack-system/packager/internal/bacpack_package"
 	"github.com/bacpack-system/packager/internal/prerequisites"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
+	"io"
 	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 )
 
 type (
@@ -35,6 +42,7 @@ type ContextManager struct {
 	// ForPackage boolean value if the Context is used for Packages or Apps
 	ForPackage     bool
 	images         ImagesPathType
+	imageConfigs   map[string]config.ImageConfig
 	configs        *ConfigMapType
 	appConfigs     ConfigMapType
 	packageConfigs ConfigMapType
@@ -605,17 +613,36 @@ func (context *ContextManager) loadImagesDockerfilePaths() error {
 	}
 
 	imagePaths := make(ImagesPathType)
+	imageConfigs := make(map[string]config.ImageConfig)
 	for imageName, pathList := range dockerfileList {
 		if len(pathList) != 1 {
 			return fmt.Errorf("wrong number of Dockerfiles for %s image (should be 1)", imageName)
 		}
 		imagePaths[imageName] = pathList[0]
+
+		imageConfigPath := filepath.Join(filepath.Dir(pathList[0]), config.ImageConfigFileName)
+		if _, err := os.Stat(imageConfigPath); os.IsNotExist(err) {
+			continue
+		}
+		imageConfig, err := config.LoadImageConfig(imageConfigPath)
+		if err != nil {
+			return fmt.Errorf("can't load image config from %s path - %w", imageConfigPath, err)
+		}
+		imageConfigs[imageName] = imageConfig
 	}
 
 	context.images = imagePaths
+	context.imageConfigs = imageConfigs
 	return nil
 }
 
+// GetImageConfig
+// Returns ImageConfig of the given Image. If the Image has no image.json file, empty ImageConfig
+// is returned.
+func (context *ContextManager) GetImageConfig(imageName string) config.ImageConfig {
+	return context.imageConfigs[imageName]
+}
+
 // GetAllImagesDockerfilePaths
 // Returns all Dockerfile paths located in the Context directory.
 func (context *ContextManager) GetAllImagesDockerfilePaths() ImagesPathType {
@@ -633,6 +660,204 @@ func (context *ContextManager) GetImageDockerfilePath(imageName string) (string,
 	return path, nil
 }
 
+// GetImageBaseImages
+// Returns names of Context Images which the Image is built from (referenced by FROM instructions
+// of its Dockerfile). Other base images are not returned.
+func (context *ContextManager) GetImageBaseImages(imageName string) ([]string, error) {
+	dockerfilePath, err := context.GetImageDockerfilePath(imageName)
+	if err != nil {
+		return nil, err
+	}
+	fromImages, err := docker.GetBaseImages(dockerfilePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get base images of %s image - %w", imageName, err)
+	}
+	var baseImages []string
+	for _, fromImage := range fromImages {
+		baseImage := docker.GetLocalImageName(fromImage)
+		if _, found := context.images[baseImage]; found && !slices.Contains(baseImages, baseImage) {
+			baseImages = append(baseImages, baseImage)
+		}
+	}
+	return baseImages, nil
+}
+
+// GetImagesBuildOrder
+// Returns Image names sorted so each Image is after all Context Images it is built from. If
+// imageName is empty, all Images are returned, else only imageName and Images it is built from
+// recursively. Returns error if the Images are built from each other in a cycle.
+func (context *ContextManager) GetImagesBuildOrder(imageName string) ([]string, error) {
+	var imageNames []string
+	if imageName != "" {
+		if _, found := context.images[imageName]; !found {
+			return nil, fmt.Errorf("docker image definition does not exist, please check the name")
+		}
+		imageNames = []string{imageName}
+	} else {
+		for name := range context.images {
+			imageNames = append(imageNames, name)
+		}
+		slices.Sort(imageNames)
+	}
+
+	baseImagesMap := make(map[string][]string)
+	for name := range context.images {
+		baseImages, err := context.GetImageBaseImages(name)
+		if err != nil {
+			return nil, err
+		}
+		baseImagesMap[name] = baseImages
+	}
+	return sortImages(imageNames, baseImagesMap)
+}
+
+// sortImages
+// Returns imageNames with all their base Images (recursively) from baseImagesMap sorted so each
+// Image is after its base Images. Returns error if there is a cycle.
+func sortImages(imageNames []string, baseImagesMap map[string][]string) ([]string, error) {
+	var sorted []string
+	// Images which are sorted (true) or which base Images are being sorted (false)
+	visited := make(map[string]bool)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		done, found := visited[name]
+		if found && done {
+			return nil
+		}
+		path = append(path, name)
+		if found {
+			return fmt.Errorf("circular dependency between images: %s", strings.Join(path, " -> "))
+		}
+		visited[name] = false
+		for _, baseImage := range baseImagesMap[name] {
+			err := visit(baseImage, path)
+			if err != nil {
+				return err
+			}
+		}
+		visited[name] = true
+		sorted = append(sorted, name)
+		return nil
+	}
+	for _, name := range imageNames {
+		err := visit(name, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// GetImageContextHash
+// Returns hash of the Image definition - SHA-256 of all files in the Dockerfile directory (except
+// Image config), files copied from the Package Context by COPY or ADD instructions and hashes of
+// Context Images the Image is built from. If the hash changes, the Image must be rebuilt.
+func (context *ContextManager) GetImageContextHash(imageName string) (string, error) {
+	return context.getImageContextHash(imageName, map[string]bool{})
+}
+
+// getImageContextHash
+// Returns hash of the Image definition. The visited contains Images which hash is being computed,
+// it is used for cycle detection.
+func (context *ContextManager) getImageContextHash(imageName string, visited map[string]bool) (string, error) {
+	if visited[imageName] {
+		return "", fmt.Errorf("circular dependency between images with %s image", imageName)
+	}
+	visited[imageName] = true
+	defer delete(visited, imageName)
+
+	dockerfilePath, err := context.GetImageDockerfilePath(imageName)
+	if err != nil {
+		return "", err
+	}
+	imageHash := sha256.New()
+	err = hashPath(imageHash, filepath.Dir(dockerfilePath), "image", func(relPath string) bool {
+		return relPath == config.ImageConfigFileName
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot compute hash of %s image - %w", imageName, err)
+	}
+
+	sources, err := docker.GetPackageContextSources(dockerfilePath)
+	if err != nil {
+		return "", err
+	}
+	for _, source := range sources {
+		matches, err := filepath.Glob(filepath.Join(context.ContextPath, source))
+		if err != nil {
+			return "", fmt.Errorf("invalid source %s in Dockerfile of %s image - %w", source, imageName, err)
+		}
+		for _, match := range matches {
+			relPath, err := filepath.Rel(context.ContextPath, match)
+			if err != nil {
+				return "", err
+			}
+			err = hashPath(imageHash, match, path.Join(docker.PackageContextName, filepath.ToSlash(relPath)), nil)
+			if err != nil {
+				return "", fmt.Errorf("cannot compute hash of %s image - %w", imageName, err)
+			}
+		}
+	}
+
+	baseImages, err := context.GetImageBaseImages(imageName)
+	if err != nil {
+		return "", err
+	}
+	for _, baseImage := range baseImages {
+		baseHash, err := context.getImageContextHash(baseImage, visited)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(imageHash, "base-image\x00%s\x00%s\x00", baseImage, baseHash)
+	}
+	return hex.EncodeToString(imageHash.Sum(nil)), nil
+}
+
+// hashPath
+// Writes names, types and contents of all files in rootPath (file or directory) to imageHash.
+// Only the executable bit of the permissions is written, so the hash does not depend on umask of
+// the checkout. The names are relative to rootPath and prefixed by namePrefix. Files for which
+// skip returns true are not written.
+func hashPath(imageHash hash.Hash, rootPath string, namePrefix string, skip func(relPath string) bool) error {
+	return filepath.WalkDir(rootPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(rootPath, filePath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if skip != nil && skip(relPath) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		executable := info.Mode().IsRegular() && info.Mode().Perm() & 0111 != 0
+		fmt.Fprintf(imageHash, "%s\x00%s\x00%t\x00", path.Join(namePrefix, relPath), info.Mode().Type(), executable)
+		switch {
+		case info.Mode() & fs.ModeSymlink != 0:
+			target, err := os.Readlink(filePath)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(imageHash, "%s\x00", target)
+		case info.Mode().IsRegular():
+			file, err := os.Open(filePath)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			fmt.Fprintf(imageHash, "%d\x00", info.Size())
+			_, err = io.Copy(imageHash, file)
+			return err
+		}
+		return nil
+	})
+}
+
 // validateContextPath
 // Validates Context path if the structure in the Context directory works
 // Return nil if structure is valid, error if the structure is invalid.
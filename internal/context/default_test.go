@@ -3,6 +3,7 @@ package context
 import (
 	"github.com/bacpack-system/packager/internal/constants"
 	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/executor"
 	"github.com/bacpack-system/packager/internal/prerequisites"
 	"path/filepath"
 	"testing"
@@ -160,6 +161,152 @@ func TestGetAllImagesDockerfilePaths(t *testing.T) {
 	}
 }
 
+func TestGetImageConfig(t *testing.T) {
+	context, err := initContext(Set1DirPath)
+	if err != nil {
+		t.Fatalf("Cannot initialize context - %s", err)
+	}
+
+	if context.GetImageConfig(Image1Name).Executor != executor.TypeDockerExec {
+		t.Error("wrong Executor loaded from image config")
+	}
+	if context.GetImageConfig(Image2Name).Executor != "" {
+		t.Error("image without image config has Executor set")
+	}
+}
+
+func TestGetImagesBuildOrder(t *testing.T) {
+	context, err := initContext(Set1DirPath)
+	if err != nil {
+		t.Fatalf("Cannot initialize context - %s", err)
+	}
+
+	baseImages, err := context.GetImageBaseImages(Image2Name)
+	if err != nil {
+		t.Fatalf("GetImageBaseImages failed - %s", err)
+	}
+	if len(baseImages) != 1 || baseImages[0] != Image1Name {
+		t.Errorf("wrong base images %v", baseImages)
+	}
+
+	order, err := context.GetImagesBuildOrder(Image2Name)
+	if err != nil {
+		t.Fatalf("GetImagesBuildOrder failed - %s", err)
+	}
+	if len(order) != 2 || order[0] != Image1Name || order[1] != Image2Name {
+		t.Errorf("wrong build order %v", order)
+	}
+	order, err = context.GetImagesBuildOrder(Image1Name)
+	if err != nil || len(order) != 1 {
+		t.Errorf("image without Context base image has wrong build order %v", order)
+	}
+}
+
+// copyDir
+// Copies content of srcDir to destDir.
+func copyDir(srcDir string, destDir string) error {
+	return filepath.WalkDir(srcDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(destDir, relPath)
+		if entry.IsDir() {
+			return os.MkdirAll(destPath, 0755)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(destPath, content, 0644)
+	})
+}
+
+func TestGetImageContextHash(t *testing.T) {
+	contextPath := t.TempDir()
+	err := copyDir(Set1DirPath, contextPath)
+	if err != nil {
+		t.Fatalf("Cannot copy context - %s", err)
+	}
+	context, err := initContext(contextPath)
+	if err != nil {
+		t.Fatalf("Cannot initialize context - %s", err)
+	}
+
+	hash1, err := context.GetImageContextHash(Image1Name)
+	if err != nil {
+		t.Fatalf("GetImageContextHash failed - %s", err)
+	}
+	hash2, err := context.GetImageContextHash(Image2Name)
+	if err != nil {
+		t.Fatalf("GetImageContextHash failed - %s", err)
+	}
+	if hash1 == hash2 {
+		t.Error("different images have the same hash")
+	}
+
+	image1Path := filepath.Join(contextPath, "docker", Image1Name)
+	err = os.WriteFile(filepath.Join(image1Path, "image.json"), []byte("{}"), 0644)
+	if err != nil {
+		t.Fatalf("Cannot write image config - %s", err)
+	}
+	newHash1, err := context.GetImageContextHash(Image1Name)
+	if err != nil || newHash1 != hash1 {
+		t.Error("hash depends on image config")
+	}
+
+	dockerfilePath := filepath.Join(image1Path, "Dockerfile")
+	err = os.Chmod(dockerfilePath, 0600)
+	if err != nil {
+		t.Fatalf("Cannot change permissions of Dockerfile - %s", err)
+	}
+	newHash1, err = context.GetImageContextHash(Image1Name)
+	if err != nil || newHash1 != hash1 {
+		t.Error("hash depends on permissions other than executable bit")
+	}
+	err = os.Chmod(dockerfilePath, 0755)
+	if err != nil {
+		t.Fatalf("Cannot change permissions of Dockerfile - %s", err)
+	}
+	newHash1, err = context.GetImageContextHash(Image1Name)
+	if err != nil || newHash1 == hash1 {
+		t.Error("hash is not changed by executable bit change")
+	}
+
+	err = os.WriteFile(dockerfilePath, []byte("FROM debian:13\n"), 0644)
+	if err != nil {
+		t.Fatalf("Cannot write Dockerfile - %s", err)
+	}
+	newHash1, err = context.GetImageContextHash(Image1Name)
+	if err != nil || newHash1 == hash1 {
+		t.Error("hash is not changed by Dockerfile change")
+	}
+	newHash2, err := context.GetImageContextHash(Image2Name)
+	if err != nil || newHash2 == hash2 {
+		t.Error("hash is not changed by base image change")
+	}
+}
+
+func TestSortImagesCircular(t *testing.T) {
+	baseImagesMap := map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+		"d": {},
+	}
+	_, err := sortImages([]string{"d", "a"}, baseImagesMap)
+	if err == nil {
+		t.Error("circular dependency between images not detected")
+	}
+	order, err := sortImages([]string{"d"}, baseImagesMap)
+	if err != nil || len(order) != 1 {
+		t.Errorf("image outside of cycle not sorted - %v", err)
+	}
+}
+
 func TestGetPackageWithDepsConfigs(t *testing.T) {
 	context, err := initContext(Set2DirPath)
 	if err != nil {
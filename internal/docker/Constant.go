@@ -4,4 +4,8 @@ package docker
 
 const (
 	DockerExecutablePathConst = "/usr/bin/docker"
+	PodmanExecutablePathConst = "/usr/bin/podman"
+	// Command which keeps the container without SSH server running
+	keepAliveCommand = "sleep"
+	keepAliveArg     = "infinity"
 )
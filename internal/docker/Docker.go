@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"os"
 	"bytes"
-	"strconv"
 )
 
 const (
@@ -25,8 +24,25 @@ type Docker struct {
 	// to the directory inside the docker container
 	// in manner map[string]string { <host_volume_abs_path>:<> }
 	Volumes map[string]string `json:"-"`
+	// ReadOnlyVolumes map a host directory to the directory inside the docker container the same
+	// way as Volumes, but the directory is mounted read-only
+	ReadOnlyVolumes map[string]string `json:"-"`
 	// If true docker command will run in non-blocking mode - as a daemon.
 	RunAsDaemon bool `json:"-"`
+	// If true, the SSH port is not published and the container is kept running by sleep command
+	// instead of the image entrypoint, so the image does not need SSH server.
+	WithoutSSH bool `json:"-"`
+	// Public key in authorized_keys format which is copied to /root/.ssh/authorized_keys in the
+	// container before the container starts. If empty, nothing is copied.
+	AuthorizedKey []byte `json:"-"`
+	// Public host keys of the SSH server in authorized_keys format. They are read once after the
+	// container with AuthorizedKey is started and used for all SSH connections to the container.
+	HostKeys []byte `json:"-"`
+	// Resource limits of the container
+	Resources Resources `json:"-"`
+	// If true, the container is run without network access. Only usable together with WithoutSSH,
+	// because the SSH port cannot be published.
+	NetworkDisabled bool `json:"-"`
 	containerId string
 }
 
@@ -37,9 +53,10 @@ type dockerInitArgs struct {
 
 func (docker *Docker) FillDefault(*prerequisites.Args) error {
 	*docker = Docker{
-		Volumes:     map[string]string{},
-		RunAsDaemon: true,
-		ImageName:   defaultImageNameConst,
+		Volumes:         map[string]string{},
+		ReadOnlyVolumes: map[string]string{},
+		RunAsDaemon:     true,
+		ImageName:       defaultImageNameConst,
 		Port: constants.DefaultSSHPort,
 	}
 	return nil
@@ -59,17 +76,14 @@ func (docker *Docker) FillDynamic(args *prerequisites.Args) error {
 }
 
 // CheckPrerequisites
-// It checks if the docker is installed and can be run by given user.
-// Function returns nil if Docker installation is ok, not nil of the problem is recognized
+// It checks if the container Runtime is installed and can be run by given user.
+// Function returns nil if Runtime installation is ok, not nil of the problem is recognized
 func (docker *Docker) CheckPrerequisites(*prerequisites.Args) error {
-	err := checkIfDockerIsUsable()
+	err := currentRuntime.CheckUsable()
 	if err != nil {
-		return fmt.Errorf(
-			"Docker cannot be used, it is not installed, the Docker daemon is not running " +
-			"or current user is not in Docker group - %w", err,
-		)
+		return err
 	}
-	portAvailable, err := isPortAvailable(docker.Port)
+	portAvailable, err := currentRuntime.IsPortAvailable(docker.Port)
 	if err != nil {
 		return err
 	} else if !portAvailable {
@@ -86,6 +100,14 @@ func (docker *Docker) CheckPrerequisites(*prerequisites.Args) error {
 			return fmt.Errorf("cannot mount non existent directory as volume: '%s'", hostVolume)
 		}
 	}
+	for hostVolume, _ := range docker.ReadOnlyVolumes {
+		if _, err = os.Stat(hostVolume); os.IsNotExist(err) {
+			return fmt.Errorf("cannot mount non existent directory as volume: '%s'", hostVolume)
+		}
+	}
+	if docker.NetworkDisabled && !docker.WithoutSSH {
+		return fmt.Errorf("container without network access cannot be reached by SSH")
+	}
 
 	return nil
 }
@@ -102,25 +124,26 @@ func (docker *Docker) SetVolume(hostDirectory string, containerDirectory string)
 	return nil
 }
 
-// checkIfDockerIsUsable
-// Checks if the Docker can be used. If not, returns error, else nil.
-func checkIfDockerIsUsable() error {
-	var errBuff bytes.Buffer
-	process := process.Process{
-		CommandAbsolutePath: DockerExecutablePathConst,
-		Args: process.ProcessArgs{
-			ExtraArgs: &[]string{
-				"info",
-			},
-		},
-		StdErr: &errBuff,
+// SetReadOnlyVolume
+// Sets read-only volume mapping for a Docker container. It's not possible to overwrite volume
+// mapping that already exists.
+func (docker *Docker) SetReadOnlyVolume(hostDirectory string, containerDirectory string) error {
+	_, hostFound := docker.ReadOnlyVolumes[hostDirectory]
+	if hostFound {
+		return fmt.Errorf("volume mapping is already set: '%s' --> '%s'", hostDirectory, containerDirectory)
 	}
-
-	err := process.Run()
-	if err != nil {
-		return fmt.Errorf(errBuff.String())
+	if docker.ReadOnlyVolumes == nil {
+		docker.ReadOnlyVolumes = map[string]string{}
 	}
-	return nil	
+	docker.ReadOnlyVolumes[hostDirectory] = containerDirectory
+
+	return nil
+}
+
+// GetContainerId
+// Returns ID of the running container, empty if the container is not running.
+func (docker *Docker) GetContainerId() string {
+	return docker.containerId
 }
 
 // checkForImageExistence
@@ -128,7 +151,7 @@ func checkIfDockerIsUsable() error {
 func checkForImageExistence(imageName string) error {
 	var outBuff bytes.Buffer
 	process := process.Process{
-		CommandAbsolutePath: DockerExecutablePathConst,
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
 		Args: process.ProcessArgs{
 			ExtraArgs: &[]string{
 				"images",
@@ -148,33 +171,3 @@ func checkForImageExistence(imageName string) error {
 	}
 	return nil
 }
-
-// isPortAvailable
-// Returns true if port for docker is available, else returns false.
-// When false is returned, the error contains message from the docker command.
-func isPortAvailable(port uint16) (bool, error) {
-	var outBuff, errBuff bytes.Buffer
-
-	process := process.Process{
-		CommandAbsolutePath: DockerExecutablePathConst,
-		Args: process.ProcessArgs{
-			ExtraArgs: &[]string{
-				"container",
-				"ls",
-				"--filter",
-				"publish=" + strconv.Itoa(int(port)),
-				"--format",
-				"{{.ID}}{{.Ports}}",
-			},
-		},
-		StdOut: &outBuff,
-		StdErr: &errBuff,
-	}
-
-	err := process.Run()
-	if err != nil {
-		return false, fmt.Errorf(errBuff.String())
-	}
-
-	return outBuff.Len() == 0, nil
-}
@@ -4,6 +4,7 @@ import (
 	"github.com/bacpack-system/packager/internal/log"
 	"fmt"
 	"os/exec"
+	"slices"
 )
 
 // DockerBuild
@@ -15,6 +16,8 @@ type DockerBuild struct {
 	Tag string
 	// build context
 	Context string
+	// Labels set to the built image
+	Labels map[string]string
 }
 
 // Build given docker image
@@ -22,6 +25,10 @@ func (dockerBuild *DockerBuild) Build() error {
 	if dockerBuild.DockerfileDir == "" {
 		return fmt.Errorf("DockerBuild - DockerfileDir is empty")
 	}
+	err := currentRuntime.CheckUsable()
+	if err != nil {
+		return err
+	}
 
 	var ok = dockerBuild.prepareAndRun(prepareBuildArgs)
 	if !ok {
@@ -39,9 +46,9 @@ func (dockerBuild *DockerBuild) prepareAndRun(f func(build *DockerBuild) []strin
 
 	var cmd exec.Cmd
 	cmdArgs := f(dockerBuild)
-	cmdArgs = append([]string{DockerExecutablePathConst}, cmdArgs...)
+	cmdArgs = append([]string{currentRuntime.GetExecutablePath()}, cmdArgs...)
 	cmd.Args = cmdArgs
-	cmd.Path = DockerExecutablePathConst
+	cmd.Path = currentRuntime.GetExecutablePath()
 	if contextLogger != nil {
 		file, err := contextLogger.GetFile()
 		if err != nil {
@@ -70,7 +77,16 @@ func prepareBuildArgs(dockerBuild *DockerBuild) []string {
 	}
 
 	if dockerBuild.Context != "" {
-		cmdArgs = append(cmdArgs, "--build-context", "package-context=" + dockerBuild.Context)
+		cmdArgs = append(cmdArgs, "--build-context", PackageContextName + "=" + dockerBuild.Context)
+	}
+	labelNames := make([]string, 0, len(dockerBuild.Labels))
+	for name := range dockerBuild.Labels {
+		labelNames = append(labelNames, name)
+	}
+	slices.Sort(labelNames)
+	for _, name := range labelNames {
+		cmdArgs = append(cmdArgs, "--label", name + "=" + dockerBuild.Labels[name])
 	}
+	cmdArgs = append(cmdArgs, currentRuntime.GetBuildArgs()...)
 	return cmdArgs
 }
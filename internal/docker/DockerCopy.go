@@ -23,7 +23,70 @@ func (args *DockerCopy) Copy(filePath string, localPath string) error {
 
 	var errBuff bytes.Buffer
 	process := process.Process{
-		CommandAbsolutePath: DockerExecutablePathConst,
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
+		Args: process.ProcessArgs{
+			ExtraArgs: &extraArgs,
+		},
+		StdErr: &errBuff,
+	}
+	err := process.Run()
+
+	if err != nil {
+		return fmt.Errorf("dockerCopy copy error - %s", errBuff.String())
+	}
+
+	return nil
+}
+
+// CopyDirectory
+// Copies content of directory dirPath from container to existing localDir. Symlinks are copied as
+// symlinks.
+func (args *DockerCopy) CopyDirectory(dirPath string, localDir string) error {
+	if args.containerId == "" {
+		return fmt.Errorf("dockerCopy copy error - container ID is empty")
+	}
+
+	extraArgs := []string{
+		"cp",
+		fmt.Sprintf("%s:%s/.", args.containerId, dirPath),
+		localDir,
+	}
+
+	var errBuff bytes.Buffer
+	process := process.Process{
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
+		Args: process.ProcessArgs{
+			ExtraArgs: &extraArgs,
+		},
+		StdErr: &errBuff,
+	}
+	err := process.Run()
+
+	if err != nil {
+		return fmt.Errorf("dockerCopy copy error - %s", errBuff.String())
+	}
+
+	return nil
+}
+
+// CopyDirectoryToContainer
+// Copies content of localDir to directory dirPath in the container. The directory is created if it
+// does not exist. The files are owned by root in the container. The container does not need to be
+// started.
+func (args *DockerCopy) CopyDirectoryToContainer(localDir string, dirPath string) error {
+	if args.containerId == "" {
+		return fmt.Errorf("dockerCopy copy error - container ID is empty")
+	}
+
+	extraArgs := []string{
+		"cp",
+		localDir + "/.",
+		fmt.Sprintf("%s:%s", args.containerId, dirPath),
+	}
+
+	var errBuff bytes.Buffer
+	process := process.Process{
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
 		Args: process.ProcessArgs{
 			ExtraArgs: &extraArgs,
 		},
@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"github.com/bacpack-system/packager/internal/process"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+type DockerExec Docker
+
+// Exec
+// Runs command in the running container. Stdout and stderr of the command are written to stdOut.
+func (args *DockerExec) Exec(command []string, stdOut io.Writer) error {
+	if args.containerId == "" {
+		return fmt.Errorf("dockerExec exec error - container ID is empty")
+	}
+
+	extraArgs := append([]string{"exec", args.containerId}, command...)
+	process := process.Process{
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
+		Args: process.ProcessArgs{
+			ExtraArgs: &extraArgs,
+		},
+		StdOut: stdOut,
+		StdErr: stdOut,
+	}
+	err := process.Run()
+	if err != nil {
+		return fmt.Errorf("dockerExec exec error - %w", err)
+	}
+	return nil
+}
+
+// ExecInteractive
+// Runs command in the running container with terminal attached to stdin, stdout and stderr of the
+// program. The command is run in workDir with env variables set.
+func (args *DockerExec) ExecInteractive(command []string, workDir string, env map[string]string) error {
+	if args.containerId == "" {
+		return fmt.Errorf("dockerExec exec error - container ID is empty")
+	}
+
+	extraArgs := args.GetInteractiveCmdLine(command, workDir, env)[1:]
+	process := process.Process{
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
+		Args: process.ProcessArgs{
+			ExtraArgs: &extraArgs,
+		},
+		StdOut: os.Stdout,
+		StdErr: os.Stderr,
+		StdIn:  os.Stdin,
+	}
+	return process.Run()
+}
+
+// GetInteractiveCmdLine
+// Returns command line (with the Runtime executable) which runs command interactively in the
+// running container. The command is run in workDir with env variables set.
+func (args *DockerExec) GetInteractiveCmdLine(command []string, workDir string, env map[string]string) []string {
+	cmdLine := []string{currentRuntime.GetExecutablePath(), "exec", "-it", "-w", workDir}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		cmdLine = append(cmdLine, "-e", key + "=" + env[key])
+	}
+	cmdLine = append(cmdLine, args.containerId)
+	return append(cmdLine, command...)
+}
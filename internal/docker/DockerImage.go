@@ -4,11 +4,17 @@ import (
 	"github.com/bacpack-system/packager/internal/log"
 	"github.com/bacpack-system/packager/internal/process"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
 )
 
+const (
+	// Label of the image with hash of the image definition, see ContextManager.GetImageContextHash
+	ImageContextHashLabel = "bap.context-hash"
+)
+
 type DockerImage Docker
 
 func (dockerImage *DockerImage) ImageExists() bool {
@@ -43,10 +49,48 @@ func (dockerImage *DockerImage) ImageExists() bool {
 	return false
 }
 
+// GetImageId
+// Returns ID of the image with ImageName.
+func (dockerImage *DockerImage) GetImageId() (string, error) {
+	output, err := dockerImage.runDockerImageCommand([]string{
+		"image",
+		"inspect",
+		"--format",
+		"{{.Id}}",
+		dockerImage.ImageName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot inspect %s image - %w", dockerImage.ImageName, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// GetLabel
+// Returns value of the label of the image with ImageName. Returns empty string if the image has
+// no such label.
+func (dockerImage *DockerImage) GetLabel(label string) (string, error) {
+	output, err := dockerImage.runDockerImageCommand([]string{
+		"image",
+		"inspect",
+		"--format",
+		"{{json .Config.Labels}}",
+		dockerImage.ImageName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot inspect %s image - %w", dockerImage.ImageName, err)
+	}
+	var labels map[string]string
+	err = json.Unmarshal([]byte(strings.TrimSpace(output)), &labels)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse labels of %s image - %w", dockerImage.ImageName, err)
+	}
+	return labels[label], nil
+}
+
 func (dockerImage *DockerImage) runDockerImageCommand(extraArgs []string) (string, error) {
 	var stdOut bytes.Buffer
 	process := process.Process{
-		CommandAbsolutePath: DockerExecutablePathConst,
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
 		Args: process.ProcessArgs{
 			ExtraArgs: &extraArgs,
 		},
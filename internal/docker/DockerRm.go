@@ -15,7 +15,7 @@ func (args *DockerRm) RemoveContainer() error {
 
 	var cerrBuff bytes.Buffer
 	process := process.Process{
-		CommandAbsolutePath: DockerExecutablePathConst,
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
 		Args: process.ProcessArgs{
 			CmdLineHandler: args,
 		},
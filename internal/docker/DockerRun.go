@@ -4,19 +4,35 @@ import (
 	"github.com/bacpack-system/packager/internal/process"
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 )
 
+const (
+	// Directory in the container where the authorized_keys file is copied
+	containerSSHDir = "/root/.ssh"
+	authorizedKeysFileName = "authorized_keys"
+	// Public host keys of the SSH server in the container
+	hostKeysPattern = "/etc/ssh/ssh_host_*_key.pub"
+)
+
 type DockerRun Docker
 
 // Run starts the container. If succeed
 // the container Id is stored and used for stop and other commands that needs it.
+// If AuthorizedKey is set, the container is created, the key is copied to it and then the
+// container is started, so the key is present before the SSH server starts. The host keys of the
+// SSH server are read after the start and stored in HostKeys.
 func (args *DockerRun) Run() error {
+	if len(args.AuthorizedKey) != 0 && !args.WithoutSSH {
+		return args.createAndStart()
+	}
 
 	var outBuff, errBuff bytes.Buffer
 	process := process.Process{
-		CommandAbsolutePath: DockerExecutablePathConst,
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
 		Args: process.ProcessArgs{
 			CmdLineHandler: args,
 		},
@@ -28,13 +44,7 @@ func (args *DockerRun) Run() error {
 	if err != nil {
 		return fmt.Errorf("dockerRun run error - %s, stderr: %s", err, errBuff.String())
 	}
-	regexp, regexpErr := regexp.CompilePOSIX("^([0-9a-zA-Z]+)")
-	if regexpErr != nil {
-		return fmt.Errorf("DockerRun run - invalid regexp")
-	}
-	id := outBuff.String()
-	args.containerId = regexp.FindString(id)
-	return nil
+	return args.setContainerId(outBuff.String())
 }
 
 func (runArgs *DockerRun) GenerateCmdLine() ([]string, error) {
@@ -43,14 +53,118 @@ func (runArgs *DockerRun) GenerateCmdLine() ([]string, error) {
 	if runArgs.RunAsDaemon {
 		cmdArgs = append(cmdArgs, "-d")
 	}
-	portPair := strconv.Itoa(int(runArgs.Port)) + ":" + strconv.Itoa(sshPort)
-	cmdArgs = append(cmdArgs, "-p")
-	cmdArgs = append(cmdArgs, portPair)
+	cmdArgs = append(cmdArgs, runArgs.getContainerArgs()...)
+	return cmdArgs, nil
+}
+
+// getContainerArgs
+// Returns arguments of the run (create) command which configure the container.
+func (runArgs *DockerRun) getContainerArgs() []string {
+	cmdArgs := make([]string, 0)
+	if !runArgs.WithoutSSH {
+		portPair := strconv.Itoa(int(runArgs.Port)) + ":" + strconv.Itoa(sshPort)
+		cmdArgs = append(cmdArgs, "-p")
+		cmdArgs = append(cmdArgs, portPair)
+	}
+	cmdArgs = append(cmdArgs, currentRuntime.GetRunArgs()...)
+	cmdArgs = append(cmdArgs, runArgs.Resources.getRunArgs()...)
 	for key, value := range runArgs.Volumes {
 		volumePair := key + ":" + value
 		cmdArgs = append(cmdArgs, "-v", volumePair)
 	}
+	for key, value := range runArgs.ReadOnlyVolumes {
+		volumePair := key + ":" + value + ":ro"
+		cmdArgs = append(cmdArgs, "-v", volumePair)
+	}
+	if runArgs.NetworkDisabled {
+		cmdArgs = append(cmdArgs, "--network", "none")
+	}
 
-	cmdArgs = append(cmdArgs, runArgs.ImageName)
-	return cmdArgs, nil
+	if runArgs.WithoutSSH {
+		// --init makes the sleep command stop on docker stop without waiting for timeout
+		cmdArgs = append(cmdArgs, "--init", "--entrypoint", keepAliveCommand, runArgs.ImageName, keepAliveArg)
+	} else {
+		cmdArgs = append(cmdArgs, runArgs.ImageName)
+	}
+	return cmdArgs
+}
+
+// createAndStart
+// Creates the container, copies AuthorizedKey to /root/.ssh/authorized_keys in the container and
+// starts the container. If the container runs as a daemon, its host keys are stored to HostKeys.
+func (args *DockerRun) createAndStart() error {
+	args.HostKeys = nil
+	createArgs := append([]string{"create"}, args.getContainerArgs()...)
+	output, err := runCommand(currentRuntime.GetExecutablePath(), createArgs)
+	if err != nil {
+		return fmt.Errorf("dockerRun create error - %w", err)
+	}
+	err = args.setContainerId(output)
+	if err != nil {
+		return err
+	}
+
+	err = args.copyAuthorizedKey()
+	if err != nil {
+		(*DockerRm)(args).RemoveContainer()
+		return err
+	}
+
+	startArgs := []string{"start"}
+	if !args.RunAsDaemon {
+		startArgs = append(startArgs, "-a")
+	}
+	startArgs = append(startArgs, args.containerId)
+	_, err = runCommand(currentRuntime.GetExecutablePath(), startArgs)
+	if err != nil {
+		(*DockerRm)(args).RemoveContainer()
+		return fmt.Errorf("dockerRun start error - %w", err)
+	}
+	if !args.RunAsDaemon {
+		return nil
+	}
+
+	var stdOut bytes.Buffer
+	err = (*DockerExec)(args).Exec([]string{"sh", "-c", "cat " + hostKeysPattern}, &stdOut)
+	if err != nil {
+		(*DockerStop)(args).Stop()
+		(*DockerRm)(args).RemoveContainer()
+		return fmt.Errorf("cannot read host keys of the container - %w", err)
+	}
+	args.HostKeys = stdOut.Bytes()
+	return nil
+}
+
+// copyAuthorizedKey
+// Copies AuthorizedKey as authorized_keys file to the created container.
+func (args *DockerRun) copyAuthorizedKey() error {
+	tmpDir, err := os.MkdirTemp("", "bap-ssh-*")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary directory for authorized_keys - %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// sshd refuses authorized_keys file accessible by other users
+	err = os.Chmod(tmpDir, 0700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(tmpDir, authorizedKeysFileName), args.AuthorizedKey, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write authorized_keys - %w", err)
+	}
+
+	dockerCopy := (*DockerCopy)(args)
+	return dockerCopy.CopyDirectoryToContainer(tmpDir, containerSSHDir)
+}
+
+// setContainerId
+// Sets container ID from output of the run or create command.
+func (args *DockerRun) setContainerId(output string) error {
+	regexp, regexpErr := regexp.CompilePOSIX("^([0-9a-zA-Z]+)")
+	if regexpErr != nil {
+		return fmt.Errorf("DockerRun run - invalid regexp")
+	}
+	args.containerId = regexp.FindString(output)
+	return nil
 }
@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"github.com/bacpack-system/packager/internal/process"
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// DockerRuntime
+// Runs containers by Docker daemon.
+type DockerRuntime struct {}
+
+func (runtime *DockerRuntime) GetName() string {
+	return RuntimeDocker
+}
+
+func (runtime *DockerRuntime) GetExecutablePath() string {
+	return DockerExecutablePathConst
+}
+
+// CheckUsable
+// Checks if the Docker can be used. If not, returns error, else nil.
+func (runtime *DockerRuntime) CheckUsable() error {
+	var errBuff bytes.Buffer
+	process := process.Process{
+		CommandAbsolutePath: DockerExecutablePathConst,
+		Args: process.ProcessArgs{
+			ExtraArgs: &[]string{
+				"info",
+			},
+		},
+		StdErr: &errBuff,
+	}
+
+	err := process.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"Docker cannot be used, it is not installed, the Docker daemon is not running " +
+			"or current user is not in Docker group - %s", errBuff.String(),
+		)
+	}
+	return nil
+}
+
+// IsPortAvailable
+// Returns true if port for docker is available, else returns false.
+// When false is returned, the error contains message from the docker command.
+func (runtime *DockerRuntime) IsPortAvailable(port uint16) (bool, error) {
+	var outBuff, errBuff bytes.Buffer
+
+	process := process.Process{
+		CommandAbsolutePath: DockerExecutablePathConst,
+		Args: process.ProcessArgs{
+			ExtraArgs: &[]string{
+				"container",
+				"ls",
+				"--filter",
+				"publish=" + strconv.Itoa(int(port)),
+				"--format",
+				"{{.ID}}{{.Ports}}",
+			},
+		},
+		StdOut: &outBuff,
+		StdErr: &errBuff,
+	}
+
+	err := process.Run()
+	if err != nil {
+		return false, fmt.Errorf(errBuff.String())
+	}
+
+	return outBuff.Len() == 0, nil
+}
+
+func (runtime *DockerRuntime) GetRunArgs() []string {
+	return []string{}
+}
+
+func (runtime *DockerRuntime) GetBuildArgs() []string {
+	return []string{}
+}
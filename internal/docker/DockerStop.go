@@ -13,7 +13,7 @@ type DockerStop Docker
 func (dockerStop *DockerStop) Stop() error {
 	var outBuff, errBuff bytes.Buffer
 	process := process.Process{
-		CommandAbsolutePath: DockerExecutablePathConst,
+		CommandAbsolutePath: currentRuntime.GetExecutablePath(),
 		Args: process.ProcessArgs{
 			CmdLineHandler: dockerStop,
 		},
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// Name of the additional build context set to the Package Context root
+	PackageContextName = "package-context"
+	// Tag used for the built images
+	latestTag = "latest"
+	// Prefix which Podman adds to names of locally built images
+	podmanLocalPrefix = "localhost/"
+)
+
+// GetBaseImages
+// Returns images referenced by FROM instructions of the Dockerfile on dockerfilePath in the order
+// of the instructions. References to previous build stages (FROM <stage>) are not returned.
+func GetBaseImages(dockerfilePath string) ([]string, error) {
+	instructions, err := readInstructions(dockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+	var baseImages []string
+	stages := map[string]bool{}
+	for _, fields := range instructions {
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		_, args := splitOptions(fields[1:])
+		if len(args) == 0 {
+			continue
+		}
+		image := args[0]
+		if !stages[strings.ToLower(image)] {
+			baseImages = append(baseImages, image)
+		}
+		if len(args) >= 3 && strings.EqualFold(args[1], "AS") {
+			stages[strings.ToLower(args[2])] = true
+		}
+	}
+	return baseImages, nil
+}
+
+// GetPackageContextSources
+// Returns source paths of COPY and ADD instructions of the Dockerfile on dockerfilePath which copy
+// from the Package Context (--from=package-context). The paths are relative to the Package
+// Context root and can contain wildcards.
+func GetPackageContextSources(dockerfilePath string) ([]string, error) {
+	instructions, err := readInstructions(dockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for _, fields := range instructions {
+		if len(fields) < 2 || (!strings.EqualFold(fields[0], "COPY") && !strings.EqualFold(fields[0], "ADD")) {
+			continue
+		}
+		options, args := splitOptions(fields[1:])
+		if options["--from"] != PackageContextName {
+			continue
+		}
+		if len(args) > 0 && strings.HasPrefix(args[0], "[") {
+			// JSON form, e.g. COPY ["src", "dest"]
+			err = json.Unmarshal([]byte(strings.Join(args, " ")), &args)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s instruction in Dockerfile - %w", fields[0], err)
+			}
+		}
+		if len(args) < 2 {
+			continue
+		}
+		sources = append(sources, args[:len(args) - 1]...)
+	}
+	return sources, nil
+}
+
+// GetLocalImageName
+// Returns name of the locally built image referenced by image (without the latest tag and Podman
+// localhost prefix). Returns empty string if image references other tag or digest.
+func GetLocalImageName(image string) string {
+	name := strings.TrimPrefix(image, podmanLocalPrefix)
+	if strings.Contains(name, "@") {
+		return ""
+	}
+	name, tag, found := strings.Cut(name, ":")
+	if found && tag != latestTag {
+		return ""
+	}
+	return name
+}
+
+// readInstructions
+// Returns instructions of the Dockerfile on dockerfilePath split to fields. Comments are skipped
+// and lines ending with backslash are joined.
+func readInstructions(dockerfilePath string) ([][]string, error) {
+	file, err := os.Open(dockerfilePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open Dockerfile - %w", err)
+	}
+	defer file.Close()
+
+	var instructions [][]string
+	instruction := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			instruction += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		instruction += line
+		fields := strings.Fields(instruction)
+		instruction = ""
+		if len(fields) > 0 {
+			instructions = append(instructions, fields)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read Dockerfile - %w", err)
+	}
+	return instructions, nil
+}
+
+// splitOptions
+// Splits leading options (--name=value) of the instruction arguments from the other arguments.
+// Returns map of the option names to values and the other arguments.
+func splitOptions(args []string) (map[string]string, []string) {
+	options := map[string]string{}
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		name, value, _ := strings.Cut(args[0], "=")
+		options[name] = value
+		args = args[1:]
+	}
+	return options, args
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Minimal Podman version supporting keep-id user namespace with uid/gid and named build contexts
+	podmanMinMajorVersion = 4
+	podmanMinMinorVersion = 3
+)
+
+// PodmanRuntime
+// Runs containers by rootless Podman. The current user is mapped to root in the container, so
+// the mounted volumes (/sysroot, /lfsrepo) are owned by root in the container and the files created
+// by the container in the volumes are owned by the current user.
+type PodmanRuntime struct {}
+
+func (runtime *PodmanRuntime) GetName() string {
+	return RuntimePodman
+}
+
+func (runtime *PodmanRuntime) GetExecutablePath() string {
+	return PodmanExecutablePathConst
+}
+
+// CheckUsable
+// Checks if the Podman is installed, runs rootless and has supported version. If not, returns
+// error, else nil.
+func (runtime *PodmanRuntime) CheckUsable() error {
+	output, err := runCommand(PodmanExecutablePathConst, []string{"info", "--format", "{{.Host.Security.Rootless}}"})
+	if err != nil {
+		return fmt.Errorf("Podman cannot be used, it is not installed or not configured - %w", err)
+	}
+	if strings.TrimSpace(output) != "true" {
+		return fmt.Errorf("Podman must run rootless")
+	}
+
+	output, err = runCommand(PodmanExecutablePathConst, []string{"version", "--format", "{{.Client.Version}}"})
+	if err != nil {
+		return fmt.Errorf("cannot get Podman version - %w", err)
+	}
+	return checkPodmanVersion(strings.TrimSpace(output))
+}
+
+// IsPortAvailable
+// Returns true if the port is not bound on the host. Rootless Podman binds published ports on the
+// host, so the port used by other container or process is detected.
+func (runtime *PodmanRuntime) IsPortAvailable(port uint16) (bool, error) {
+	return isHostPortFree(port), nil
+}
+
+// GetRunArgs
+// Returns arguments which map the current user to root in the container.
+func (runtime *PodmanRuntime) GetRunArgs() []string {
+	return []string{"--userns=keep-id:uid=0,gid=0"}
+}
+
+// GetBuildArgs
+// Returns arguments which build the image in Docker format, so Dockerfile instructions not
+// supported by OCI format (SHELL, HEALTHCHECK) are not ignored.
+func (runtime *PodmanRuntime) GetBuildArgs() []string {
+	return []string{"--format", "docker"}
+}
+
+// checkPodmanVersion
+// Returns error if the version is lower than minimal supported Podman version.
+func checkPodmanVersion(version string) error {
+	versionParts := strings.SplitN(version, ".", 3)
+	if len(versionParts) < 2 {
+		return fmt.Errorf("invalid Podman version '%s'", version)
+	}
+	major, err := strconv.Atoi(versionParts[0])
+	if err != nil {
+		return fmt.Errorf("invalid Podman version '%s'", version)
+	}
+	minor, err := strconv.Atoi(versionParts[1])
+	if err != nil {
+		return fmt.Errorf("invalid Podman version '%s'", version)
+	}
+	if major < podmanMinMajorVersion || (major == podmanMinMajorVersion && minor < podmanMinMinorVersion) {
+		return fmt.Errorf(
+			"Podman %s is not supported, at least %d.%d is required",
+			version, podmanMinMajorVersion, podmanMinMinorVersion,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+var sizeRegexp *regexp.Regexp = regexp.MustCompilePOSIX("^[0-9]+[bkmgBKMG]?$")
+var ulimitNameRegexp *regexp.Regexp = regexp.MustCompilePOSIX("^[a-z]+$")
+var ulimitValueRegexp *regexp.Regexp = regexp.MustCompilePOSIX("^(-1|[0-9]+)(:(-1|[0-9]+))?$")
+
+// Resources
+// Resource limits of the container. Zero or empty values mean no limit.
+type Resources struct {
+	// CPUs number of CPUs available to the container, it is also used as number of build jobs
+	CPUs    int
+	// Memory limit of the container memory, number with optional unit (b, k, m, g), for example 8g
+	Memory  string
+	// ShmSize size of /dev/shm, number with optional unit (b, k, m, g)
+	ShmSize string
+	// Ulimits maps ulimit name to <soft>[:<hard>] value, for example "nofile": "1024:4096"
+	Ulimits map[string]string
+}
+
+// CheckResources
+// Returns error if any of the Resources values is invalid.
+func (resources *Resources) CheckResources() error {
+	if resources.CPUs < 0 {
+		return fmt.Errorf("CPUs must not be negative")
+	}
+	if resources.Memory != "" && !sizeRegexp.MatchString(resources.Memory) {
+		return fmt.Errorf("invalid Memory '%s'", resources.Memory)
+	}
+	if resources.ShmSize != "" && !sizeRegexp.MatchString(resources.ShmSize) {
+		return fmt.Errorf("invalid ShmSize '%s'", resources.ShmSize)
+	}
+	for name, value := range resources.Ulimits {
+		if !ulimitNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid ulimit name '%s'", name)
+		}
+		if !ulimitValueRegexp.MatchString(value) {
+			return fmt.Errorf("invalid value '%s' of ulimit %s", value, name)
+		}
+	}
+	return nil
+}
+
+// Override
+// Returns Resources with values of other Resources which are set. Ulimits are merged, other
+// Ulimits have priority. If other is nil, copy of the Resources is returned.
+func (resources Resources) Override(other *Resources) Resources {
+	result := resources
+	result.Ulimits = maps.Clone(resources.Ulimits)
+	if other == nil {
+		return result
+	}
+	if other.CPUs != 0 {
+		result.CPUs = other.CPUs
+	}
+	if other.Memory != "" {
+		result.Memory = other.Memory
+	}
+	if other.ShmSize != "" {
+		result.ShmSize = other.ShmSize
+	}
+	if len(other.Ulimits) != 0 && result.Ulimits == nil {
+		result.Ulimits = map[string]string{}
+	}
+	maps.Copy(result.Ulimits, other.Ulimits)
+	return result
+}
+
+// getRunArgs
+// Returns arguments of the run command which set the resource limits.
+func (resources *Resources) getRunArgs() []string {
+	cmdArgs := make([]string, 0)
+	if resources.CPUs != 0 {
+		cmdArgs = append(cmdArgs, "--cpus", strconv.Itoa(resources.CPUs))
+	}
+	if resources.Memory != "" {
+		cmdArgs = append(cmdArgs, "--memory", resources.Memory)
+	}
+	if resources.ShmSize != "" {
+		cmdArgs = append(cmdArgs, "--shm-size", resources.ShmSize)
+	}
+	ulimitNames := make([]string, 0, len(resources.Ulimits))
+	for name := range resources.Ulimits {
+		ulimitNames = append(ulimitNames, name)
+	}
+	slices.Sort(ulimitNames)
+	for _, name := range ulimitNames {
+		cmdArgs = append(cmdArgs, "--ulimit", name + "=" + resources.Ulimits[name])
+	}
+	return cmdArgs
+}
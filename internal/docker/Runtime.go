@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"github.com/bacpack-system/packager/internal/process"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const (
+	// RuntimeDocker runs containers by Docker
+	RuntimeDocker = "docker"
+	// RuntimePodman runs containers by rootless Podman
+	RuntimePodman = "podman"
+)
+
+// Runtime
+// Container runtime which runs the containers and builds the images. All runtimes must support
+// Docker CLI commands used by this package (run, exec, cp, stop, rm, build, image inspect).
+type Runtime interface {
+	// GetName returns name of the Runtime
+	GetName() string
+	// GetExecutablePath returns absolute path of the Runtime CLI
+	GetExecutablePath() string
+	// CheckUsable returns error if the Runtime cannot be used by current user
+	CheckUsable() error
+	// IsPortAvailable returns true if the host port can be published by a new container
+	IsPortAvailable(port uint16) (bool, error)
+	// GetRunArgs returns additional arguments of the run command
+	GetRunArgs() []string
+	// GetBuildArgs returns additional arguments of the build command
+	GetBuildArgs() []string
+}
+
+var currentRuntime Runtime = &DockerRuntime{}
+
+// Runtimes
+// Returns names of all supported Runtimes.
+func Runtimes() []string {
+	return []string{RuntimeDocker, RuntimePodman}
+}
+
+// SetRuntime
+// Sets Runtime used by all containers and image builds. Empty runtimeName means Docker. It must be
+// called before any container is run.
+func SetRuntime(runtimeName string) error {
+	switch runtimeName {
+	case RuntimeDocker, "":
+		currentRuntime = &DockerRuntime{}
+	case RuntimePodman:
+		currentRuntime = &PodmanRuntime{}
+	default:
+		return fmt.Errorf("unsupported container runtime '%s'", runtimeName)
+	}
+	return nil
+}
+
+// GetRuntime
+// Returns Runtime used by all containers and image builds.
+func GetRuntime() Runtime {
+	return currentRuntime
+}
+
+// isHostPortFree
+// Returns true if the TCP port can be bound on the host, else false.
+func isHostPortFree(port uint16) bool {
+	listener, err := net.Listen("tcp", ":" + strconv.Itoa(int(port)))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+// runCommand
+// Runs executable with extraArgs and returns its stdout. If the command fails, the error contains
+// stderr of the command.
+func runCommand(executablePath string, extraArgs []string) (string, error) {
+	var outBuff, errBuff bytes.Buffer
+	process := process.Process{
+		CommandAbsolutePath: executablePath,
+		Args: process.ProcessArgs{
+			ExtraArgs: &extraArgs,
+		},
+		StdOut: &outBuff,
+		StdErr: &errBuff,
+	}
+	err := process.Run()
+	if err != nil {
+		return "", fmt.Errorf("%s, stderr: %s", err, errBuff.String())
+	}
+	return outBuff.String(), nil
+}
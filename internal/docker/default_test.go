@@ -4,6 +4,8 @@ import (
 	"github.com/bacpack-system/packager/internal/docker"
 	"github.com/bacpack-system/packager/internal/prerequisites"
 	"github.com/bacpack-system/packager/internal/constants"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"strconv"
@@ -100,4 +102,229 @@ func TestDockerRun_GenerateCmdLine(t *testing.T) {
 		t.Errorf("invalid Docker Run cmd line with volumes!")
 		return
 	}
+
+	dockerRun.Volumes = nil
+	dockerRun.WithoutSSH = true
+	validCmdLine = []string{
+		"run",
+		"-d",
+		"--init",
+		"--entrypoint",
+		"sleep",
+		dockerRun.ImageName,
+		"infinity",
+	}
+	cmdLine, err = dockerRun.GenerateCmdLine()
+	if err != nil {
+		t.Errorf("cannot generate reference cmd line")
+		return
+	}
+	cmdLineValid = reflect.DeepEqual(cmdLine, validCmdLine)
+	if !cmdLineValid {
+		t.Errorf("invalid Docker Run cmd line without SSH!")
+	}
+}
+
+func TestDockerRunOffline_GenerateCmdLine(t *testing.T) {
+	dockerRun := docker.DockerRun{
+		ImageName:  "debian13",
+		WithoutSSH: true,
+		ReadOnlyVolumes: map[string]string{
+			"A": "/source",
+		},
+		NetworkDisabled: true,
+	}
+	validCmdLine := []string{
+		"run",
+		"-v",
+		"A:/source:ro",
+		"--network",
+		"none",
+		"--init",
+		"--entrypoint",
+		"sleep",
+		dockerRun.ImageName,
+		"infinity",
+	}
+	cmdLine, err := dockerRun.GenerateCmdLine()
+	if err != nil {
+		t.Fatalf("cannot generate reference cmd line")
+	}
+	if !reflect.DeepEqual(cmdLine, validCmdLine) {
+		t.Errorf("invalid Docker Run cmd line without network!")
+	}
+}
+
+func TestGetBaseImages(t *testing.T) {
+	dockerfilePath := filepath.Join(t.TempDir(), "Dockerfile")
+	content := "# FROM commented\n" +
+		"ARG VERSION=1\n" +
+		"FROM --platform=linux/amd64 debian13-base:latest AS builder\n" +
+		"RUN make\n" +
+		"from \\\n" +
+		"  builder\n" +
+		"FROM localhost/toolchain\n" +
+		"COPY --from=builder /out /out\n"
+	err := os.WriteFile(dockerfilePath, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("cannot write Dockerfile - %s", err)
+	}
+	baseImages, err := docker.GetBaseImages(dockerfilePath)
+	if err != nil {
+		t.Fatalf("GetBaseImages failed - %s", err)
+	}
+	if !reflect.DeepEqual(baseImages, []string{"debian13-base:latest", "localhost/toolchain"}) {
+		t.Errorf("wrong base images %v", baseImages)
+	}
+
+	localNames := map[string]string{
+		"debian13-base:latest": "debian13-base",
+		"localhost/toolchain":  "toolchain",
+		"debian:13":            "",
+		"image@sha256:0123":    "",
+	}
+	for image, localName := range localNames {
+		if docker.GetLocalImageName(image) != localName {
+			t.Errorf("wrong local image name of %s", image)
+		}
+	}
+}
+
+func TestGetPackageContextSources(t *testing.T) {
+	dockerfilePath := filepath.Join(t.TempDir(), "Dockerfile")
+	content := "FROM debian:13\n" +
+		"COPY --from=package-context docker/common/setup.sh /setup.sh\n" +
+		"COPY --from=builder /out /out\n" +
+		"COPY local.txt /local.txt\n" +
+		"ADD --chown=root --from=package-context \\\n" +
+		"  package/a package/b /opt/\n" +
+		"COPY --from=package-context [\"docker/json file\", \"/dest\"]\n"
+	err := os.WriteFile(dockerfilePath, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("cannot write Dockerfile - %s", err)
+	}
+	sources, err := docker.GetPackageContextSources(dockerfilePath)
+	if err != nil {
+		t.Fatalf("GetPackageContextSources failed - %s", err)
+	}
+	expected := []string{"docker/common/setup.sh", "package/a", "package/b", "docker/json file"}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("wrong package context sources %v", sources)
+	}
+}
+
+func TestDockerExecInteractive_GenerateCmdLine(t *testing.T) {
+	dockerExec := (*docker.DockerExec)(&docker.Docker{})
+	env := map[string]string{
+		"B": "x y",
+		"A": "1",
+	}
+	cmdLine := dockerExec.GetInteractiveCmdLine([]string{"bash", "-l"}, "/git", env)
+	validCmdLine := []string{
+		docker.DockerExecutablePathConst, "exec", "-it", "-w", "/git", "-e", "A=1", "-e", "B=x y", "", "bash", "-l",
+	}
+	if !reflect.DeepEqual(cmdLine, validCmdLine) {
+		t.Errorf("invalid Docker Exec interactive cmd line %v", cmdLine)
+	}
+}
+
+func TestSetRuntime(t *testing.T) {
+	defer docker.SetRuntime(docker.RuntimeDocker)
+
+	err := docker.SetRuntime(docker.RuntimePodman)
+	if err != nil {
+		t.Fatalf("cannot set Podman runtime - %s", err)
+	}
+	if docker.GetRuntime().GetExecutablePath() != docker.PodmanExecutablePathConst {
+		t.Error("Podman runtime does not use podman executable")
+	}
+	err = docker.SetRuntime("")
+	if err != nil || docker.GetRuntime().GetName() != docker.RuntimeDocker {
+		t.Error("Docker is not the default runtime")
+	}
+	err = docker.SetRuntime("unknown")
+	if err == nil {
+		t.Error("unsupported runtime set")
+	}
+}
+
+func TestPodmanRun_GenerateCmdLine(t *testing.T) {
+	defer docker.SetRuntime(docker.RuntimeDocker)
+	err := docker.SetRuntime(docker.RuntimePodman)
+	if err != nil {
+		t.Fatalf("cannot set Podman runtime - %s", err)
+	}
+
+	dockerRun := docker.DockerRun{
+		ImageName: "debian13",
+		Port:      uint16(constants.DefaultSSHPort),
+		Volumes: map[string]string{
+			"A": "/sysroot",
+		},
+	}
+	validCmdLine := []string{
+		"run",
+		"-p",
+		strconv.Itoa(constants.DefaultSSHPort) + ":22",
+		"--userns=keep-id:uid=0,gid=0",
+		"-v",
+		"A:/sysroot",
+		dockerRun.ImageName,
+	}
+	cmdLine, err := dockerRun.GenerateCmdLine()
+	if err != nil {
+		t.Fatalf("cannot generate reference cmd line")
+	}
+	if !reflect.DeepEqual(cmdLine, validCmdLine) {
+		t.Errorf("invalid Podman Run cmd line!")
+	}
+}
+
+func TestResources(t *testing.T) {
+	imageResources := docker.Resources{
+		CPUs:    8,
+		Memory:  "16g",
+		Ulimits: map[string]string{"nofile": "1024:4096", "core": "0"},
+	}
+	packageResources := docker.Resources{
+		Memory:  "32g",
+		ShmSize: "1g",
+		Ulimits: map[string]string{"nofile": "8192"},
+	}
+	err := packageResources.CheckResources()
+	if err != nil {
+		t.Fatalf("valid Resources refused - %s", err)
+	}
+
+	dockerRun := docker.DockerRun{
+		ImageName: "debian13",
+		Port:      uint16(constants.DefaultSSHPort),
+		Resources: imageResources.Override(&packageResources),
+	}
+	validCmdLine := []string{
+		"run",
+		"-p",
+		strconv.Itoa(constants.DefaultSSHPort) + ":22",
+		"--cpus", "8",
+		"--memory", "32g",
+		"--shm-size", "1g",
+		"--ulimit", "core=0",
+		"--ulimit", "nofile=8192",
+		dockerRun.ImageName,
+	}
+	cmdLine, err := dockerRun.GenerateCmdLine()
+	if err != nil {
+		t.Fatalf("cannot generate reference cmd line")
+	}
+	if !reflect.DeepEqual(cmdLine, validCmdLine) {
+		t.Errorf("invalid Docker Run cmd line with resources - %v", cmdLine)
+	}
+	if imageResources.Ulimits["nofile"] != "1024:4096" {
+		t.Error("Override changed the image Resources")
+	}
+
+	invalidResources := docker.Resources{Memory: "lots"}
+	if invalidResources.CheckResources() == nil {
+		t.Error("invalid Memory accepted")
+	}
 }
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/ssh"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DockerExecExecutor
+// Runs commands by docker exec and downloads files by docker cp. The image does not need SSH
+// server, but it must contain Bash.
+type DockerExecExecutor struct {
+	Docker *docker.Docker
+}
+
+func (executor *DockerExecExecutor) Run(shell *ssh.ShellEvaluator) error {
+	dockerExec := (*docker.DockerExec)(executor.Docker)
+	return dockerExec.Exec([]string{"bash", "-li", "-c", shell.GetScript()}, shell.StdOut)
+}
+
+func (executor *DockerExecExecutor) RunCommand(command string) (string, error) {
+	var stdOut bytes.Buffer
+	dockerExec := (*docker.DockerExec)(executor.Docker)
+	err := dockerExec.Exec([]string{"sh", "-c", command}, &stdOut)
+	if err != nil {
+		return "", err
+	}
+	return stdOut.String(), nil
+}
+
+func (executor *DockerExecExecutor) DownloadDirectory(remoteDir string, localDir string, logWriter io.Writer) error {
+	if _, err := os.Stat(localDir); os.IsNotExist(err) {
+		return fmt.Errorf("local directory '%s' does not exist", localDir)
+	}
+	localDirContent, _ := os.ReadDir(localDir)
+	if len(localDirContent) != 0 {
+		return fmt.Errorf("local directory '%s' is not empty", localDir)
+	}
+	if logWriter != nil {
+		fmt.Fprintf(logWriter, "docker cp %s/. %s\n", remoteDir, localDir)
+	}
+	dockerCopy := (*docker.DockerCopy)(executor.Docker)
+	return dockerCopy.CopyDirectory(remoteDir, localDir)
+}
@@ -0,0 +1,51 @@
+// Package executor runs commands in a running container and downloads files from it. The
+// commands can be run over SSH (the image must run SSH server) or by docker exec.
+package executor
+
+import (
+	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/ssh"
+	"fmt"
+	"io"
+)
+
+const (
+	// TypeSSH runs commands over SSH, the image must run SSH server on port 22
+	TypeSSH = "ssh"
+	// TypeDockerExec runs commands by docker exec, the image does not need SSH server
+	TypeDockerExec = "docker-exec"
+)
+
+// Executor
+// Runs commands in a running container and downloads files from it.
+type Executor interface {
+	// Run runs commands of shell by Bash in one session. Output is written to shell.StdOut.
+	Run(shell *ssh.ShellEvaluator) error
+	// RunCommand runs single command and returns its stdout.
+	RunCommand(command string) (string, error)
+	// DownloadDirectory downloads content of remoteDir to existing and empty localDir.
+	DownloadDirectory(remoteDir string, localDir string, logWriter io.Writer) error
+}
+
+// Types
+// Returns all supported Executor types.
+func Types() []string {
+	return []string{TypeSSH, TypeDockerExec}
+}
+
+// CreateExecutor
+// Creates Executor of executorType for the container represented by dockerContainer. The
+// credentials are used only by SSH Executor, the port is taken from dockerContainer and the public
+// key of the credentials is copied to the container when it is started. For docker exec
+// Executor the container is configured to run without SSH server. Empty executorType means SSH.
+func CreateExecutor(executorType string, dockerContainer *docker.Docker, credentials *ssh.SSHCredentials) (Executor, error) {
+	switch executorType {
+	case TypeSSH, "":
+		dockerContainer.AuthorizedKey = credentials.GetAuthorizedKey()
+		return &SSHExecutor{Credentials: credentials, Docker: dockerContainer}, nil
+	case TypeDockerExec:
+		dockerContainer.WithoutSSH = true
+		return &DockerExecExecutor{Docker: dockerContainer}, nil
+	}
+	return nil, fmt.Errorf("unsupported executor '%s'", executorType)
+}
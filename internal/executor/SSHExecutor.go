@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/ssh"
+	"io"
+)
+
+// SSHExecutor
+// Runs commands over SSH. The image must run SSH server, which is reachable with Credentials on
+// the port published by the Docker container.
+type SSHExecutor struct {
+	Credentials *ssh.SSHCredentials
+	Docker      *docker.Docker
+}
+
+func (executor *SSHExecutor) Run(shell *ssh.ShellEvaluator) error {
+	return shell.RunOverSSH(executor.getCredentials())
+}
+
+func (executor *SSHExecutor) RunCommand(command string) (string, error) {
+	commandSsh := ssh.Command{
+		Command: command,
+	}
+	return commandSsh.RunCommandOverSSH(executor.getCredentials())
+}
+
+func (executor *SSHExecutor) DownloadDirectory(remoteDir string, localDir string, logWriter io.Writer) error {
+	credentials := executor.getCredentials()
+	sftpClient := ssh.SFTP{
+		RemoteDir:      remoteDir,
+		EmptyLocalDir:  localDir,
+		SSHCredentials: &credentials,
+		LogWriter:      logWriter,
+	}
+	return sftpClient.DownloadDirectory()
+}
+
+// getCredentials
+// Returns Credentials with Port set to the port published by the Docker container. The host keys
+// of the SSH server are pinned when the container is started, so only the SSH server of the
+// container is accepted.
+func (executor *SSHExecutor) getCredentials() ssh.SSHCredentials {
+	credentials := *executor.Credentials
+	credentials.Port = executor.Docker.Port
+	credentials.HostKeys = executor.Docker.HostKeys
+	return credentials
+}
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"github.com/bacpack-system/packager/internal/docker"
+	"github.com/bacpack-system/packager/internal/ssh"
+	"testing"
+)
+
+func TestCreateExecutor(t *testing.T) {
+	credentials := ssh.SSHCredentials{}
+	dock := docker.Docker{}
+	sshExecutor, err := CreateExecutor("", &dock, &credentials)
+	if err != nil {
+		t.Fatalf("CreateExecutor failed - %s", err)
+	}
+	if _, ok := sshExecutor.(*SSHExecutor); !ok || dock.WithoutSSH {
+		t.Error("SSH Executor is not the default")
+	}
+
+	dockerExecExecutor, err := CreateExecutor(TypeDockerExec, &dock, &credentials)
+	if err != nil {
+		t.Fatalf("CreateExecutor failed - %s", err)
+	}
+	if _, ok := dockerExecExecutor.(*DockerExecExecutor); !ok || !dock.WithoutSSH {
+		t.Error("docker exec Executor is not created or container runs with SSH")
+	}
+
+	_, err = CreateExecutor("unknown", &dock, &credentials)
+	if err == nil {
+		t.Error("unsupported Executor created")
+	}
+}
+
+func TestSSHExecutorPort(t *testing.T) {
+	credentials := ssh.SSHCredentials{Port: 22}
+	dock := docker.Docker{Port: 1122}
+	executor := SSHExecutor{Credentials: &credentials, Docker: &dock}
+	if executor.getCredentials().Port != dock.Port {
+		t.Error("SSH Executor does not use port of the container")
+	}
+}
+
+func TestSSHExecutorHostKeys(t *testing.T) {
+	hostKeyPair, err := ssh.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed - %s", err)
+	}
+	credentials := ssh.SSHCredentials{}
+	dock := docker.Docker{HostKeys: hostKeyPair.AuthorizedKey}
+	executor := SSHExecutor{Credentials: &credentials, Docker: &dock}
+	if string(executor.getCredentials().HostKeys) != string(hostKeyPair.AuthorizedKey) {
+		t.Error("SSH Executor does not use host keys pinned for the container")
+	}
+}
+
+func TestSSHExecutorAuthorizedKey(t *testing.T) {
+	keyPair, err := ssh.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed - %s", err)
+	}
+	credentials := ssh.SSHCredentials{KeyPair: keyPair}
+	dock := docker.Docker{}
+	_, err = CreateExecutor(TypeSSH, &dock, &credentials)
+	if err != nil {
+		t.Fatalf("CreateExecutor failed - %s", err)
+	}
+	if string(dock.AuthorizedKey) != string(keyPair.AuthorizedKey) {
+		t.Error("public key is not copied to the container")
+	}
+}
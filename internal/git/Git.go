@@ -9,6 +9,9 @@ type Git struct {
 	URI       string
 	Revision  string
 	ClonePath string `json:"-"`
+	// Reference path to a local mirror of the repository. If not empty, the objects present in the
+	// mirror are not downloaded by the clone.
+	Reference string `json:"-"`
 }
 
 type GitClone struct {
@@ -37,9 +40,12 @@ func (args *GitClone) ConstructCMDLine() []string {
 		GitExecutablePath,
 		"clone",
 		"--recursive",
-		args.URI,
-		args.ClonePath,
 	}
+	if args.Reference != "" {
+		// The mirror does not have to contain mirrors of all submodules
+		cmd = append(cmd, "--reference-if-able", args.Reference)
+	}
+	cmd = append(cmd, args.URI, args.ClonePath)
 	return []string{strings.Join(cmd, " ")}
 }
 
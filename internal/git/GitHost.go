@@ -0,0 +1,38 @@
+package git
+
+import (
+	"github.com/bacpack-system/packager/internal/process"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// RunOnHost
+// Runs git with args on the host. Stdout and stderr of git is written to logWriter, if not nil.
+// Returns error with stderr of git if it fails.
+func RunOnHost(args []string, logWriter io.Writer) error {
+	gitPath, err := exec.LookPath(GitExecutablePath)
+	if err != nil {
+		return fmt.Errorf("git is not installed on the host - %w", err)
+	}
+	var errBuff bytes.Buffer
+	var stdErr io.Writer = &errBuff
+	if logWriter != nil {
+		stdErr = io.MultiWriter(&errBuff, logWriter)
+	}
+	process := process.Process{
+		CommandAbsolutePath: gitPath,
+		Args: process.ProcessArgs{
+			ExtraArgs: &args,
+		},
+		StdOut: logWriter,
+		StdErr: stdErr,
+	}
+	err = process.Run()
+	if err != nil {
+		return fmt.Errorf("'git %s' failed - %w, stderr: %s", strings.Join(args, " "), err, errBuff.String())
+	}
+	return nil
+}
@@ -71,3 +71,23 @@ func TestGitSubmoduleUpdate_ConstructCMDLine(t *testing.T) {
 		t.Errorf("git update CMD line is not valid!")
 	}
 }
+
+func TestGitCloneReference_ConstructCMDLine(t *testing.T) {
+	gitClone := git.GitClone{}
+	gitClone.URI = "TestUri"
+	gitClone.ClonePath = "local"
+	gitClone.Reference = "/git-mirror"
+	cmdLine := gitClone.ConstructCMDLine()
+	validCmdLine := []string{
+		git.GitExecutablePath,
+		"clone",
+		"--recursive",
+		"--reference-if-able",
+		gitClone.Reference,
+		gitClone.URI,
+		gitClone.ClonePath,
+	}
+	if cmdLine[0] != strings.Join(validCmdLine, " ") {
+		t.Errorf("git clone CMD line with reference is not valid!")
+	}
+}
@@ -0,0 +1,397 @@
+// Package git_mirror keeps bare mirrors of Package git repositories on the host. The mirrors are
+// used as reference of the clone, so only objects missing in the mirror are downloaded.
+package git_mirror
+
+import (
+	"github.com/bacpack-system/packager/internal/git"
+	"github.com/bacpack-system/packager/internal/log"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tmpDirPattern = ".tmp-*"
+	mirrorDirExt  = ".git"
+	// Directory in the cache with checkouts of the repositories used by offline builds, it is
+	// hidden, so it is not listed as mirror
+	checkoutsDirName = ".checkouts"
+	// Directory in the mirror with mirrors of submodules, git computes alternates of submodules
+	// as <reference>/modules/<submodule name>
+	modulesDirName = "modules"
+	// Maximum depth of nested submodules which are mirrored
+	maxSubmoduleDepth = 5
+)
+
+var invalidNameCharsRegexp = regexp.MustCompile("[^a-zA-Z0-9._-]+")
+
+// MirrorCache
+// Cache of bare mirrors of git repositories. Each mirror is stored in
+// <CacheDir>/<repository name>-<hash of URI>.git directory. Mirrors of submodules are stored in
+// modules directory of the mirror, so git uses them for submodules too. Checkouts of the
+// repositories (for offline builds) are stored in <CacheDir>/.checkouts/<repository name>-<hash of
+// URI> directory. It is safe to use it from multiple goroutines.
+type MirrorCache struct {
+	// CacheDir path to the cache directory, it is created if it does not exist
+	CacheDir string
+	// locks mutexes of the mirrors and checkouts, one mirror or checkout is used by one goroutine
+	// at a time
+	locks    sync.Map
+}
+
+// Mirror
+// Mirror of one git repository in the MirrorCache.
+type Mirror struct {
+	// Path to the mirror directory
+	Path       string
+	// URI of the mirrored repository
+	URI        string
+	// UpdateTime time of the last successful update
+	UpdateTime time.Time
+}
+
+type mirrorCacheInitArgs struct {
+	CacheDir string
+}
+
+func (cache *MirrorCache) FillDefault(*prerequisites.Args) error {
+	return nil
+}
+
+func (cache *MirrorCache) FillDynamic(args *prerequisites.Args) error {
+	if !prerequisites.IsEmpty(args) {
+		var argsStruct mirrorCacheInitArgs
+		prerequisites.GetArgs(args, &argsStruct)
+		cache.CacheDir = argsStruct.CacheDir
+	}
+	return nil
+}
+
+func (cache *MirrorCache) CheckPrerequisites(*prerequisites.Args) error {
+	if cache.CacheDir == "" {
+		return fmt.Errorf("git mirror cache directory must be set")
+	}
+	err := os.MkdirAll(cache.CacheDir, 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create git mirror cache directory '%s' - %w", cache.CacheDir, err)
+	}
+	return nil
+}
+
+// GetMirrorPath
+// Returns path to the mirror of repository on uri. The mirror does not have to exist.
+func (cache *MirrorCache) GetMirrorPath(uri string) string {
+	return filepath.Join(cache.CacheDir, getMirrorDirName(uri))
+}
+
+// Contains
+// Returns true if the mirror of repository on uri exists, else false.
+func (cache *MirrorCache) Contains(uri string) bool {
+	return isMirror(cache.GetMirrorPath(uri))
+}
+
+// Update
+// Creates the mirror of repository on uri or fetches all changes to the existing one. The mirrors
+// of submodules (at HEAD of the repository) are updated too, failure of the submodule mirror is
+// only logged. Output of git is written to logWriter, if not nil. Returns path to the mirror.
+func (cache *MirrorCache) Update(uri string, logWriter io.Writer) (string, error) {
+	mirrorPath := cache.GetMirrorPath(uri)
+	lock, _ := cache.locks.LoadOrStore(mirrorPath, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	return mirrorPath, updateMirror(uri, mirrorPath, logWriter, 0)
+}
+
+// GetCheckoutPath
+// Returns path to the checkout of repository on uri. The checkout does not have to exist.
+func (cache *MirrorCache) GetCheckoutPath(uri string) string {
+	return filepath.Join(cache.CacheDir, checkoutsDirName, strings.TrimSuffix(getMirrorDirName(uri), mirrorDirExt))
+}
+
+// Checkout
+// Checks out revision of repository on uri with all submodules in the checkout directory of the
+// cache. The checkout is cloned (with the mirror as reference, if it exists) only once and then
+// only fetched, so it is reused by all builds of the repository. The checkout is locked until the
+// returned unlock function is called, so it is not changed by other goroutine while it is used.
+// Output of git is written to logWriter, if not nil. Returns path to the checkout.
+func (cache *MirrorCache) Checkout(uri string, revision string, logWriter io.Writer) (string, func(), error) {
+	checkoutPath := cache.GetCheckoutPath(uri)
+	lock, _ := cache.locks.LoadOrStore(checkoutPath, &sync.Mutex{})
+	mutex := lock.(*sync.Mutex)
+	mutex.Lock()
+
+	err := updateCheckout(uri, revision, checkoutPath, cache.GetMirrorPath(uri), logWriter)
+	if err != nil {
+		mutex.Unlock()
+		return "", nil, err
+	}
+	return checkoutPath, mutex.Unlock, nil
+}
+
+// List
+// Returns all mirrors in the cache.
+func (cache *MirrorCache) List() ([]Mirror, error) {
+	entries, err := os.ReadDir(cache.CacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read git mirror cache directory - %w", err)
+	}
+	mirrors := []Mirror{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		mirrorPath := filepath.Join(cache.CacheDir, entry.Name())
+		if !isMirror(mirrorPath) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		uri, err := getMirrorUri(mirrorPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read URI of git mirror '%s' - %w", mirrorPath, err)
+		}
+		mirrors = append(mirrors, Mirror{
+			Path:       mirrorPath,
+			URI:        uri,
+			UpdateTime: info.ModTime(),
+		})
+	}
+	return mirrors, nil
+}
+
+// Remove
+// Removes the mirror and the checkout of the mirrored repository from the cache.
+func (cache *MirrorCache) Remove(mirror Mirror) error {
+	lock, _ := cache.locks.LoadOrStore(mirror.Path, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	err := os.RemoveAll(mirror.Path)
+	if err != nil {
+		return fmt.Errorf("cannot remove git mirror '%s' - %w", mirror.Path, err)
+	}
+
+	checkoutPath := cache.GetCheckoutPath(mirror.URI)
+	checkoutLock, _ := cache.locks.LoadOrStore(checkoutPath, &sync.Mutex{})
+	checkoutLock.(*sync.Mutex).Lock()
+	defer checkoutLock.(*sync.Mutex).Unlock()
+
+	err = os.RemoveAll(checkoutPath)
+	if err != nil {
+		return fmt.Errorf("cannot remove git checkout '%s' - %w", checkoutPath, err)
+	}
+	return nil
+}
+
+// updateMirror
+// Creates or fetches the mirror on mirrorPath of repository on uri and then mirrors of its
+// submodules up to maxSubmoduleDepth.
+func updateMirror(uri string, mirrorPath string, logWriter io.Writer, depth int) error {
+	var err error
+	if isMirror(mirrorPath) {
+		err = git.RunOnHost([]string{"-C", mirrorPath, "fetch", "--prune", "--prune-tags"}, logWriter)
+	} else {
+		err = createMirror(uri, mirrorPath, logWriter)
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = os.Chtimes(mirrorPath, now, now)
+	if err != nil {
+		return err
+	}
+
+	if depth >= maxSubmoduleDepth {
+		return nil
+	}
+	submodules := getSubmoduleUris(mirrorPath, uri)
+	for name, submoduleUri := range submodules {
+		submodulePath := filepath.Join(mirrorPath, modulesDirName, name)
+		err = updateMirror(submoduleUri, submodulePath, logWriter, depth + 1)
+		if err != nil {
+			log.GetLogger().WarnIndent("Cannot update git mirror of submodule %s - %s", name, err)
+		}
+	}
+	return nil
+}
+
+// createMirror
+// Clones bare mirror of repository on uri to a temporary directory and renames it to mirrorPath,
+// so incomplete mirror is never used.
+func createMirror(uri string, mirrorPath string, logWriter io.Writer) error {
+	err := os.MkdirAll(filepath.Dir(mirrorPath), 0755)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(mirrorPath), tmpDirPattern)
+	if err != nil {
+		return fmt.Errorf("cannot create temporary directory in git mirror cache - %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = git.RunOnHost([]string{"clone", "--mirror", uri, tmpDir}, logWriter)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, mirrorPath)
+}
+
+// updateCheckout
+// Creates the checkout on checkoutPath of repository on uri, if it does not exist, fetches all
+// branches and tags to it and checks out revision with all submodules. Files not in revision are
+// removed, so the checkout is the same as fresh clone.
+func updateCheckout(uri string, revision string, checkoutPath string, mirrorPath string, logWriter io.Writer) error {
+	if !isCheckout(checkoutPath) {
+		err := createCheckout(uri, checkoutPath, mirrorPath, logWriter)
+		if err != nil {
+			return err
+		}
+	}
+	// HEAD is always detached, so all local branches can be updated
+	commands := [][]string{
+		{"-C", checkoutPath, "fetch", "--force", "--prune", "--tags", "--update-head-ok", "origin", "+refs/heads/*:refs/heads/*"},
+		{"-C", checkoutPath, "checkout", "--force", "--detach", revision},
+		{"-C", checkoutPath, "clean", "-ffdx"},
+		{"-C", checkoutPath, "submodule", "sync", "--recursive"},
+		{"-C", checkoutPath, "submodule", "update", "--init", "--recursive", "--force"},
+	}
+	for _, command := range commands {
+		err := git.RunOnHost(command, logWriter)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createCheckout
+// Clones repository on uri without checkout to a temporary directory and renames it to
+// checkoutPath, so incomplete clone is never used. The mirror on mirrorPath is used as reference,
+// if it exists. Objects are copied from it, so the checkout does not depend on the mirror.
+func createCheckout(uri string, checkoutPath string, mirrorPath string, logWriter io.Writer) error {
+	err := os.MkdirAll(filepath.Dir(checkoutPath), 0755)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(checkoutPath), tmpDirPattern)
+	if err != nil {
+		return fmt.Errorf("cannot create temporary directory in git mirror cache - %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cloneArgs := []string{"clone", "--no-checkout"}
+	if isMirror(mirrorPath) {
+		cloneArgs = append(cloneArgs, "--reference", mirrorPath, "--dissociate")
+	}
+	err = git.RunOnHost(append(cloneArgs, uri, tmpDir), logWriter)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, checkoutPath)
+}
+
+// getSubmoduleUris
+// Returns map of submodule names to URIs read from .gitmodules at HEAD of the mirror. Relative
+// URIs are resolved against uri of the mirrored repository.
+func getSubmoduleUris(mirrorPath string, uri string) map[string]string {
+	var output bytes.Buffer
+	submodules := map[string]string{}
+	err := git.RunOnHost([]string{"-C", mirrorPath, "cat-file", "-e", "HEAD:.gitmodules"}, nil)
+	if err != nil {
+		// No submodules
+		return submodules
+	}
+	err = git.RunOnHost([]string{"-C", mirrorPath, "config", "--blob", "HEAD:.gitmodules", "--get-regexp", "^submodule\\..*\\.url$"}, &output)
+	if err != nil {
+		// No submodule has URL
+		return submodules
+	}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		key, submoduleUri, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "submodule."), ".url")
+		if !filepath.IsLocal(name) {
+			continue
+		}
+		submodules[name] = resolveSubmoduleUri(uri, submoduleUri)
+	}
+	return submodules
+}
+
+// resolveSubmoduleUri
+// Returns submoduleUri resolved against parentUri if it is relative (starts with ./ or ../), else
+// returns submoduleUri.
+func resolveSubmoduleUri(parentUri string, submoduleUri string) string {
+	if !strings.HasPrefix(submoduleUri, "./") && !strings.HasPrefix(submoduleUri, "../") {
+		return submoduleUri
+	}
+	base := strings.TrimSuffix(parentUri, "/")
+	separator := "/"
+	rest := submoduleUri
+	for {
+		if strings.HasPrefix(rest, "./") {
+			rest = strings.TrimPrefix(rest, "./")
+		} else if strings.HasPrefix(rest, "../") {
+			rest = strings.TrimPrefix(rest, "../")
+			// The host of scp-like URI is separated by colon
+			index := strings.LastIndexAny(base, "/:")
+			if index >= 0 {
+				separator = base[index:index + 1]
+				base = base[:index]
+			}
+		} else {
+			break
+		}
+	}
+	return base + separator + rest
+}
+
+// getMirrorUri
+// Returns URI of the repository mirrored to mirrorPath.
+func getMirrorUri(mirrorPath string) (string, error) {
+	var output bytes.Buffer
+	err := git.RunOnHost([]string{"-C", mirrorPath, "config", "--get", "remote.origin.url"}, &output)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output.String()), nil
+}
+
+// getMirrorDirName
+// Returns name of the mirror directory of repository on uri. The name contains the repository
+// name for readability and hash of the uri for uniqueness.
+func getMirrorDirName(uri string) string {
+	hash := sha256.Sum256([]byte(uri))
+	name := strings.TrimSuffix(strings.TrimSuffix(uri, "/"), mirrorDirExt)
+	index := strings.LastIndexAny(name, "/:")
+	name = invalidNameCharsRegexp.ReplaceAllString(name[index + 1:], "_")
+	return name + "-" + hex.EncodeToString(hash[:8]) + mirrorDirExt
+}
+
+// isCheckout
+// Returns true if checkoutPath is a git repository with work tree, else false.
+func isCheckout(checkoutPath string) bool {
+	return isMirror(filepath.Join(checkoutPath, ".git"))
+}
+
+// isMirror
+// Returns true if mirrorPath is a bare git repository, else false.
+func isMirror(mirrorPath string) bool {
+	info, err := os.Stat(filepath.Join(mirrorPath, "HEAD"))
+	return err == nil && info.Mode().IsRegular()
+}
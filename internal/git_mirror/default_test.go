@@ -0,0 +1,185 @@
+package git_mirror
+
+import (
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit
+// Runs git with args in the test, fails the test if git fails.
+func runGit(t *testing.T, args ...string) {
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed - %s: %s", strings.Join(args, " "), err, output)
+	}
+}
+
+// createRepositories
+// Creates repository with one submodule in dir. Returns path to the repository.
+func createRepositories(t *testing.T, dir string) string {
+	subPath := filepath.Join(dir, "sub")
+	topPath := filepath.Join(dir, "top")
+	runGit(t, "init", "-q", "-b", "main", subPath)
+	runGit(t, "-C", subPath, "commit", "-q", "--allow-empty", "-m", "sub")
+	runGit(t, "init", "-q", "-b", "main", topPath)
+	runGit(t, "-C", topPath, "-c", "protocol.file.allow=always", "submodule", "add", "-q", "../sub", "sub")
+	runGit(t, "-C", topPath, "commit", "-q", "-m", "top")
+	return topPath
+}
+
+func TestUpdateAndList(t *testing.T) {
+	topPath := createRepositories(t, t.TempDir())
+	cache, err := prerequisites.CreateAndInitialize[MirrorCache](filepath.Join(t.TempDir(), "mirrors"))
+	if err != nil {
+		t.Fatalf("git mirror cache initialization failed - %s", err)
+	}
+
+	if cache.Contains(topPath) {
+		t.Error("empty cache contains mirror")
+	}
+	mirrorPath, err := cache.Update(topPath, nil)
+	if err != nil {
+		t.Fatalf("Update failed - %s", err)
+	}
+	if mirrorPath != cache.GetMirrorPath(topPath) || !cache.Contains(topPath) {
+		t.Error("mirror is not created")
+	}
+	if !isMirror(filepath.Join(mirrorPath, modulesDirName, "sub")) {
+		t.Error("mirror of submodule is not created")
+	}
+
+	runGit(t, "-C", topPath, "commit", "-q", "--allow-empty", "-m", "second")
+	_, err = cache.Update(topPath, nil)
+	if err != nil {
+		t.Fatalf("Update of existing mirror failed - %s", err)
+	}
+	runGit(t, "-C", mirrorPath, "cat-file", "-e", "main~1")
+
+	mirrors, err := cache.List()
+	if err != nil {
+		t.Fatalf("List failed - %s", err)
+	}
+	if len(mirrors) != 1 || mirrors[0].URI != topPath || mirrors[0].Path != mirrorPath {
+		t.Fatalf("unexpected mirrors %v", mirrors)
+	}
+	err = cache.Remove(mirrors[0])
+	if err != nil {
+		t.Fatalf("Remove failed - %s", err)
+	}
+	if cache.Contains(topPath) {
+		t.Error("removed mirror is in cache")
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	// Submodule is cloned from local path in the test
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+	topPath := createRepositories(t, t.TempDir())
+	runGit(t, "-C", topPath, "tag", "v1")
+	runGit(t, "-C", topPath, "commit", "-q", "--allow-empty", "-m", "second")
+	cache, err := prerequisites.CreateAndInitialize[MirrorCache](t.TempDir())
+	if err != nil {
+		t.Fatalf("git mirror cache initialization failed - %s", err)
+	}
+	_, err = cache.Update(topPath, nil)
+	if err != nil {
+		t.Fatalf("Update failed - %s", err)
+	}
+
+	checkoutPath, unlock, err := cache.Checkout(topPath, "v1", nil)
+	if err != nil {
+		t.Fatalf("Checkout failed - %s", err)
+	}
+	unlock()
+	if checkoutPath != cache.GetCheckoutPath(topPath) {
+		t.Errorf("unexpected checkout path %s", checkoutPath)
+	}
+	output, err := exec.Command("git", "-C", checkoutPath, "log", "-1", "--pretty=format:%s").Output()
+	if err != nil || string(output) != "top" {
+		t.Error("revision is not checked out")
+	}
+	if _, err = os.Stat(filepath.Join(checkoutPath, "sub", ".git")); err != nil {
+		t.Error("submodule is not checked out")
+	}
+
+	err = os.WriteFile(filepath.Join(checkoutPath, "untracked"), []byte{}, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, "-C", topPath, "commit", "-q", "--allow-empty", "-m", "third")
+	checkoutPath, unlock, err = cache.Checkout(topPath, "main", nil)
+	if err != nil {
+		t.Fatalf("Checkout of existing checkout failed - %s", err)
+	}
+	unlock()
+	output, err = exec.Command("git", "-C", checkoutPath, "log", "-1", "--pretty=format:%s").Output()
+	if err != nil || string(output) != "third" {
+		t.Error("new commit of the branch is not checked out")
+	}
+	if _, err = os.Stat(filepath.Join(checkoutPath, "untracked")); !os.IsNotExist(err) {
+		t.Error("untracked file is not removed from checkout")
+	}
+
+	_, _, err = cache.Checkout(topPath, "unknown", nil)
+	if err == nil {
+		t.Error("checkout of unknown revision succeeded")
+	}
+
+	mirrors, err := cache.List()
+	if err != nil || len(mirrors) != 1 {
+		t.Fatalf("unexpected mirrors %v - %v", mirrors, err)
+	}
+	err = cache.Remove(mirrors[0])
+	if err != nil {
+		t.Fatalf("Remove failed - %s", err)
+	}
+	if isCheckout(checkoutPath) {
+		t.Error("checkout of removed mirror is in cache")
+	}
+}
+
+func TestUpdateInvalidUri(t *testing.T) {
+	cache, err := prerequisites.CreateAndInitialize[MirrorCache](t.TempDir())
+	if err != nil {
+		t.Fatalf("git mirror cache initialization failed - %s", err)
+	}
+	_, err = cache.Update(filepath.Join(t.TempDir(), "missing"), nil)
+	if err == nil {
+		t.Error("mirror of missing repository created")
+	}
+	mirrors, err := cache.List()
+	if err != nil || len(mirrors) != 0 {
+		t.Error("failed mirror is listed")
+	}
+}
+
+func TestResolveSubmoduleUri(t *testing.T) {
+	cases := [][3]string{
+		{"https://github.com/org/top.git", "../sub.git", "https://github.com/org/sub.git"},
+		{"https://github.com/org/top.git", "./sub", "https://github.com/org/top.git/sub"},
+		{"git@github.com:org/top.git", "../../other/sub.git", "git@github.com:other/sub.git"},
+		{"https://github.com/org/top.git", "https://gitlab.com/sub.git", "https://gitlab.com/sub.git"},
+	}
+	for _, c := range cases {
+		resolved := resolveSubmoduleUri(c[0], c[1])
+		if resolved != c[2] {
+			t.Errorf("%s resolved against %s is %s, expected %s", c[1], c[0], resolved, c[2])
+		}
+	}
+}
+
+func TestGetMirrorDirName(t *testing.T) {
+	name := getMirrorDirName("https://github.com/org/top.git")
+	if !strings.HasPrefix(name, "top-") || !strings.HasSuffix(name, mirrorDirExt) {
+		t.Errorf("unexpected mirror directory name %s", name)
+	}
+	if name == getMirrorDirName("https://github.com/other/top.git") {
+		t.Error("mirrors of different URIs have the same directory")
+	}
+}
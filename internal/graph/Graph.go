@@ -0,0 +1,207 @@
+// Package graph represents the Package dependency graph and writes it in formats suitable for
+// visualization (Graphviz DOT, Mermaid) or for further processing (JSON).
+package graph
+
+import (
+	"github.com/bacpack-system/packager/internal/config"
+	"github.com/bacpack-system/packager/internal/context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// FormatDOT Graphviz DOT output format
+	FormatDOT = "dot"
+	// FormatJSON JSON output format
+	FormatJSON = "json"
+	// FormatMermaid Mermaid flowchart output format
+	FormatMermaid = "mermaid"
+
+	debugBuildType   = "debug"
+	releaseBuildType = "release"
+	indent           = "\x20\x20\x20\x20" // four spaces
+)
+
+// Node
+// Represents one Package in the dependency graph. BuildTypes and ImageNames are filled only if
+// requested by GraphOptions.
+type Node struct {
+	Name       string
+	DependsOn  []string
+	BuildTypes []string `json:",omitempty"`
+	ImageNames []string `json:",omitempty"`
+}
+
+// Graph
+// Package dependency graph. Nodes are sorted by Package name.
+type Graph struct {
+	Nodes []Node
+}
+
+// GraphOptions
+// Options for the graph creation.
+type GraphOptions struct {
+	// ShowBuildTypes marks debug/release Configs on each node
+	ShowBuildTypes bool
+	// ShowImages marks images from DockerMatrix on each node
+	ShowImages bool
+}
+
+// CreateGraph
+// Creates dependency graph from given Configs. Only dependencies which are present in configs are
+// added as edges, so the graph can be limited to a subset of Packages.
+func CreateGraph(configs []config.Config, options GraphOptions) (*Graph, error) {
+	configMap := make(context.ConfigMapType)
+	for _, cfg := range configs {
+		configMap[cfg.Package.Name] = append(configMap[cfg.Package.Name], cfg)
+	}
+
+	dependsMap, _, err := context.CreateDependsMap(&configMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var graph Graph
+	for packageName, deps := range dependsMap {
+		node := Node{
+			Name:      packageName,
+			DependsOn: []string{},
+		}
+		for depName := range *deps {
+			_, found := configMap[depName]
+			if found {
+				node.DependsOn = append(node.DependsOn, depName)
+			}
+		}
+		sort.Strings(node.DependsOn)
+		if options.ShowBuildTypes {
+			node.BuildTypes = getBuildTypes(configMap[packageName])
+		}
+		if options.ShowImages {
+			node.ImageNames = getImageNames(configMap[packageName])
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].Name < graph.Nodes[j].Name
+	})
+
+	return &graph, nil
+}
+
+// Write
+// Writes the graph to writer in given format.
+func (graph *Graph) Write(writer io.Writer, format string) error {
+	switch format {
+	case FormatDOT:
+		return graph.writeDOT(writer)
+	case FormatJSON:
+		return graph.writeJSON(writer)
+	case FormatMermaid:
+		return graph.writeMermaid(writer)
+	}
+	return fmt.Errorf("unsupported graph format '%s'", format)
+}
+
+// writeDOT
+// Writes the graph in Graphviz DOT format.
+func (graph *Graph) writeDOT(writer io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString("digraph packages {\n")
+	for _, node := range graph.Nodes {
+		label := strings.Join(node.getLabelLines(), "\\n")
+		builder.WriteString(fmt.Sprintf("%s\"%s\" [label=\"%s\"];\n", indent, node.Name, label))
+	}
+	for _, node := range graph.Nodes {
+		for _, dep := range node.DependsOn {
+			builder.WriteString(fmt.Sprintf("%s\"%s\" -> \"%s\";\n", indent, node.Name, dep))
+		}
+	}
+	builder.WriteString("}\n")
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// writeJSON
+// Writes the graph in JSON format.
+func (graph *Graph) writeJSON(writer io.Writer) error {
+	bytes, err := json.MarshalIndent(graph, "", indent)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(bytes, '\n'))
+	return err
+}
+
+// writeMermaid
+// Writes the graph as Mermaid flowchart. Node ids are generated, because Package names can contain
+// characters which are not allowed in Mermaid ids.
+func (graph *Graph) writeMermaid(writer io.Writer) error {
+	nodeIds := make(map[string]string)
+	for i, node := range graph.Nodes {
+		nodeIds[node.Name] = fmt.Sprintf("node%d", i)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("graph TD\n")
+	for _, node := range graph.Nodes {
+		label := strings.Join(node.getLabelLines(), "<br/>")
+		builder.WriteString(fmt.Sprintf("%s%s[\"%s\"]\n", indent, nodeIds[node.Name], label))
+	}
+	for _, node := range graph.Nodes {
+		for _, dep := range node.DependsOn {
+			builder.WriteString(fmt.Sprintf("%s%s --> %s\n", indent, nodeIds[node.Name], nodeIds[dep]))
+		}
+	}
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// getLabelLines
+// Returns lines of node label - Package name followed by build types and images if present.
+func (node *Node) getLabelLines() []string {
+	lines := []string{node.Name}
+	if len(node.BuildTypes) > 0 {
+		lines = append(lines, strings.Join(node.BuildTypes, ", "))
+	}
+	if len(node.ImageNames) > 0 {
+		lines = append(lines, strings.Join(node.ImageNames, ", "))
+	}
+	return lines
+}
+
+// getBuildTypes
+// Returns sorted build types (debug, release) of given Configs.
+func getBuildTypes(configs []config.Config) []string {
+	var buildTypes []string
+	for _, cfg := range configs {
+		buildType := releaseBuildType
+		if cfg.Package.IsDebug {
+			buildType = debugBuildType
+		}
+		if !slices.Contains(buildTypes, buildType) {
+			buildTypes = append(buildTypes, buildType)
+		}
+	}
+	sort.Strings(buildTypes)
+	return buildTypes
+}
+
+// getImageNames
+// Returns sorted union of images from DockerMatrix of given Configs.
+func getImageNames(configs []config.Config) []string {
+	var imageNames []string
+	for _, cfg := range configs {
+		for _, imageName := range cfg.DockerMatrix.ImageNames {
+			if !slices.Contains(imageNames, imageName) {
+				imageNames = append(imageNames, imageName)
+			}
+		}
+	}
+	sort.Strings(imageNames)
+	return imageNames
+}
@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/config"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const (
+	Pack1Name = "pack1"
+	Pack2Name = "pack2"
+	Pack3Name = "pack3"
+	Image1Name = "image1"
+	Image2Name = "image2"
+)
+
+func createConfig(packageName string, isDebug bool, imageNames []string, dependsOn []string) config.Config {
+	return config.Config{
+		Package: bacpack_package.Package{
+			Name: packageName,
+			IsDebug: isDebug,
+		},
+		DockerMatrix: config.DockerMatrix{
+			ImageNames: imageNames,
+		},
+		DependsOn: dependsOn,
+	}
+}
+
+// createTestConfigs
+// Returns Configs where pack1 depends on pack2 and pack3 and pack2 depends on pack3.
+func createTestConfigs() []config.Config {
+	return []config.Config{
+		createConfig(Pack1Name, false, []string{Image1Name}, []string{Pack2Name, Pack3Name}),
+		createConfig(Pack1Name, true, []string{Image1Name}, []string{Pack2Name, Pack3Name}),
+		createConfig(Pack2Name, false, []string{Image2Name, Image1Name}, []string{Pack3Name}),
+		createConfig(Pack3Name, true, []string{Image1Name}, []string{}),
+	}
+}
+
+func TestCreateGraph(t *testing.T) {
+	graph, err := CreateGraph(createTestConfigs(), GraphOptions{})
+	if err != nil {
+		t.Fatalf("CreateGraph failed - %s", err)
+	}
+	if len(graph.Nodes) != 3 {
+		t.Fatalf("wrong number of nodes - %d", len(graph.Nodes))
+	}
+	if graph.Nodes[0].Name != Pack1Name || graph.Nodes[1].Name != Pack2Name || graph.Nodes[2].Name != Pack3Name {
+		t.Errorf("nodes are not sorted by name")
+	}
+	if strings.Join(graph.Nodes[0].DependsOn, ",") != Pack2Name + "," + Pack3Name {
+		t.Errorf("wrong dependencies of %s - %v", Pack1Name, graph.Nodes[0].DependsOn)
+	}
+	if len(graph.Nodes[2].DependsOn) != 0 {
+		t.Errorf("%s should not have dependencies", Pack3Name)
+	}
+	if graph.Nodes[0].BuildTypes != nil || graph.Nodes[0].ImageNames != nil {
+		t.Errorf("build types and images are filled without options")
+	}
+}
+
+func TestCreateGraphSubset(t *testing.T) {
+	configs := createTestConfigs()[:2]
+	graph, err := CreateGraph(configs, GraphOptions{})
+	if err != nil {
+		t.Fatalf("CreateGraph failed - %s", err)
+	}
+	if len(graph.Nodes) != 1 {
+		t.Fatalf("wrong number of nodes - %d", len(graph.Nodes))
+	}
+	if len(graph.Nodes[0].DependsOn) != 0 {
+		t.Errorf("edges to Packages which are not in graph are present")
+	}
+}
+
+func TestCreateGraphOptions(t *testing.T) {
+	graph, err := CreateGraph(createTestConfigs(), GraphOptions{ShowBuildTypes: true, ShowImages: true})
+	if err != nil {
+		t.Fatalf("CreateGraph failed - %s", err)
+	}
+	if strings.Join(graph.Nodes[0].BuildTypes, ",") != "debug,release" {
+		t.Errorf("wrong build types - %v", graph.Nodes[0].BuildTypes)
+	}
+	if strings.Join(graph.Nodes[2].BuildTypes, ",") != "debug" {
+		t.Errorf("wrong build types - %v", graph.Nodes[2].BuildTypes)
+	}
+	if strings.Join(graph.Nodes[1].ImageNames, ",") != Image1Name + "," + Image2Name {
+		t.Errorf("wrong images - %v", graph.Nodes[1].ImageNames)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	graph, err := CreateGraph(createTestConfigs(), GraphOptions{ShowBuildTypes: true})
+	if err != nil {
+		t.Fatalf("CreateGraph failed - %s", err)
+	}
+	var buffer bytes.Buffer
+	err = graph.Write(&buffer, FormatDOT)
+	if err != nil {
+		t.Fatalf("Write failed - %s", err)
+	}
+	output := buffer.String()
+	if !strings.HasPrefix(output, "digraph packages {\n") || !strings.HasSuffix(output, "}\n") {
+		t.Errorf("output is not DOT digraph - %s", output)
+	}
+	if !strings.Contains(output, "\"pack1\" [label=\"pack1\\ndebug, release\"];") {
+		t.Errorf("node label is missing - %s", output)
+	}
+	if !strings.Contains(output, "\"pack2\" -> \"pack3\";") {
+		t.Errorf("edge is missing - %s", output)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	graph, err := CreateGraph(createTestConfigs(), GraphOptions{ShowImages: true})
+	if err != nil {
+		t.Fatalf("CreateGraph failed - %s", err)
+	}
+	var buffer bytes.Buffer
+	err = graph.Write(&buffer, FormatJSON)
+	if err != nil {
+		t.Fatalf("Write failed - %s", err)
+	}
+	var parsed Graph
+	err = json.Unmarshal(buffer.Bytes(), &parsed)
+	if err != nil {
+		t.Fatalf("output is not valid JSON - %s", err)
+	}
+	if len(parsed.Nodes) != 3 || len(parsed.Nodes[0].DependsOn) != 2 || len(parsed.Nodes[0].ImageNames) != 1 {
+		t.Errorf("parsed graph differs - %v", parsed)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	graph, err := CreateGraph(createTestConfigs(), GraphOptions{})
+	if err != nil {
+		t.Fatalf("CreateGraph failed - %s", err)
+	}
+	var buffer bytes.Buffer
+	err = graph.Write(&buffer, FormatMermaid)
+	if err != nil {
+		t.Fatalf("Write failed - %s", err)
+	}
+	output := buffer.String()
+	if !strings.HasPrefix(output, "graph TD\n") {
+		t.Errorf("output is not Mermaid graph - %s", output)
+	}
+	if !strings.Contains(output, "node0[\"pack1\"]") || !strings.Contains(output, "node1 --> node2") {
+		t.Errorf("node or edge is missing - %s", output)
+	}
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	graph, err := CreateGraph(createTestConfigs(), GraphOptions{})
+	if err != nil {
+		t.Fatalf("CreateGraph failed - %s", err)
+	}
+	var buffer bytes.Buffer
+	err = graph.Write(&buffer, "svg")
+	if err == nil {
+		t.Errorf("unsupported format does not return error")
+	}
+}
@@ -49,7 +49,8 @@ type Args struct {
 // If VAL len is zero then the dataOut is initialized by K{}: *dataOut = K{}
 //
 // Let T_K[i] is Type of the i-th element of structure K.
-// Then for each i T_K[i] == T[i] otherwise the panic will raise.
+// Then for each i T_K[i] == T[i] otherwise the panic will raise. If T_K[i] is an interface type,
+// T[i] must implement it.
 func GetArgs[K any](filler *Args, dataOut *K) {
 	if len(filler.variadicArgs) == 0 {
 		var _t K
@@ -68,6 +69,9 @@ func GetArgs[K any](filler *Args, dataOut *K) {
 	for i := 0; i < argsFiledCount; i++ {
 		argField := dataType.Field(i)
 		argsType := reflect.TypeOf(filler.variadicArgs[i])
+		if argField.Type.Kind() == reflect.Interface && argsType != nil && argsType.Implements(argField.Type) {
+			continue
+		}
 		if argField.Type.Name() != argsType.Name() {
 			panic(fmt.Errorf("invalid type! Expected '%s', got '%s'", argField.Type.Name(), argsType.Name()))
 		}
@@ -7,7 +7,11 @@
 // registered signal is received, all added (and not yet removed) handlers will be executed in
 // reverse order and then the program exits with status code 1.
 //
-// Note: Do not use with concurrent programming. Can behave unexpectedly!
+// Note: Handlers can be added and removed from multiple goroutines. Each returned function removes
+// only the handler it was created for, regardless of the order in which the handlers are removed.
+// The handler is removed before it is executed by the returned function, so it is not executed
+// again when a signal is received during its execution. The signal handling waits for such
+// in-flight handlers before the remaining handlers are executed and the program exits.
 
 package process
 
@@ -18,9 +22,18 @@ import (
 	"os/signal"
 )
 
+// signalHandler
+// Handler added by SignalHandlerAddHandler with unique id used for its removal.
+type signalHandler struct {
+	id      uint64
+	handler func() error
+}
 
 var lock sync.Mutex
-var handlers []func() error
+var handlers []signalHandler
+var lastHandlerId uint64
+// inFlightHandlers counts removed handlers which are being executed by their remover
+var inFlightHandlers sync.WaitGroup
 
 // SignalHandlerRegisterSignal
 // Registers handling of specified signals to process package
@@ -29,15 +42,22 @@ func SignalHandlerRegisterSignal(sig ...os.Signal) {
 	signal.Notify(sigs, sig...)
 	go func() {
 		_ = <-sigs
-		lock.Lock()
-		defer lock.Unlock()
-		logger := log.GetLogger()
-		logger.Info("SIGINT received - %d handlers to execute", len(handlers))
-		executeAllHandlers()
+		handleSignal()
 		os.Exit(1)
 	}()
 }
 
+// handleSignal
+// Waits for in-flight handlers and executes all remaining handlers. The lock is kept, so no
+// handler is added or removed afterwards.
+func handleSignal() {
+	lock.Lock()
+	logger := log.GetLogger()
+	logger.Info("SIGINT received - %d handlers to execute", len(handlers))
+	inFlightHandlers.Wait()
+	executeAllHandlers()
+}
+
 // SignalHandlerAddHandler
 // Adds handler for execution after signal is received by process package. Returns
 // function, which executes handler and removes it from handling by process module.
@@ -47,28 +67,42 @@ func SignalHandlerRegisterSignal(sig ...os.Signal) {
 func SignalHandlerAddHandler(handler func() error) func() {
 	lock.Lock()
 	defer lock.Unlock()
-	handlers = append(handlers, handler)
+	lastHandlerId++
+	id := lastHandlerId
+	handlers = append(handlers, signalHandler{id: id, handler: handler})
 	return func() {
-		lock.Lock()
-		defer lock.Unlock()
+		// The handler is executed without the lock, so slow handlers of parallel jobs do not wait
+		// for each other and do not block the signal handling
+		if !removeHandler(id) {
+			return
+		}
+		defer inFlightHandlers.Done()
 		err := handler()
 		if err != nil {
 			log.GetLogger().Error("Handler returned error - %s", err)
 		}
-		removeLastHandler()
 	}
 }
 
-func removeLastHandler() {
-	if len(handlers) == 0 {
-		return
+// removeHandler
+// Removes handler with given id and marks it as in-flight. Returns false if there is no such
+// handler.
+func removeHandler(id uint64) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	for i := len(handlers) - 1; i >= 0; i-- {
+		if handlers[i].id == id {
+			handlers = append(handlers[:i], handlers[i + 1:]...)
+			inFlightHandlers.Add(1)
+			return true
+		}
 	}
-	handlers = handlers[:len(handlers) - 1]
+	return false
 }
 
 func executeAllHandlers() {
 	for i := len(handlers)-1; i >= 0; i-- {
-		err := handlers[i]()
+		err := handlers[i].handler()
 		if err != nil {
 			log.GetLogger().Error("Handler returned error - %s", err)
 		}
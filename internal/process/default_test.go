@@ -0,0 +1,103 @@
+package process
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func getHandlersCount() int {
+	lock.Lock()
+	defer lock.Unlock()
+	return len(handlers)
+}
+
+func TestSignalHandlerRemoverParallel(t *testing.T) {
+	firstStarted := make(chan struct{})
+	secondExecuted := make(chan struct{})
+	firstDone := make(chan struct{})
+	firstRemover := SignalHandlerAddHandler(func() error {
+		close(firstStarted)
+		// Waits for the second handler, which must not be blocked by this one
+		<-secondExecuted
+		return nil
+	})
+	secondRemover := SignalHandlerAddHandler(func() error {
+		close(secondExecuted)
+		return nil
+	})
+
+	go func() {
+		firstRemover()
+		close(firstDone)
+	}()
+	<-firstStarted
+	go secondRemover()
+
+	select {
+	case <-firstDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handlers are not executed in parallel")
+	}
+	if count := getHandlersCount(); count != 0 {
+		t.Errorf("%d handlers not removed", count)
+	}
+}
+
+func TestSignalHandlerRemoverExecutesOnce(t *testing.T) {
+	count := 0
+	remover := SignalHandlerAddHandler(func() error {
+		count++
+		return nil
+	})
+	remover()
+	remover()
+	if count != 1 {
+		t.Errorf("handler executed %d times", count)
+	}
+}
+
+func TestHandleSignalWaitsForInFlightHandler(t *testing.T) {
+	var orderLock sync.Mutex
+	var order []string
+	record := func(name string) {
+		orderLock.Lock()
+		defer orderLock.Unlock()
+		order = append(order, name)
+	}
+	// The outer handler is executed by the signal handling
+	SignalHandlerAddHandler(func() error {
+		record("outer")
+		return nil
+	})
+	innerStarted := make(chan struct{})
+	release := make(chan struct{})
+	innerRemover := SignalHandlerAddHandler(func() error {
+		close(innerStarted)
+		<-release
+		record("inner")
+		return nil
+	})
+	go innerRemover()
+	<-innerStarted
+
+	signalHandled := make(chan struct{})
+	go func() {
+		handleSignal()
+		close(signalHandled)
+	}()
+	select {
+	case <-signalHandled:
+		t.Fatal("signal handled before in-flight handler finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	<-signalHandled
+	// handleSignal keeps the lock, the program exits after it
+	handlers = nil
+	lock.Unlock()
+
+	if len(order) != 2 || order[0] != "inner" || order[1] != "outer" {
+		t.Errorf("unexpected order of handlers %v", order)
+	}
+}
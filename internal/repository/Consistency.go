@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/config"
+	"github.com/bacpack-system/packager/internal/constants"
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/log"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	// Count of files which will be list in warnings
+	listFileCount = 10
+)
+
+// dividePackagesForCurrentImage
+// Divides allConfigs to packages for imageName and not for imageName and returns 2 slices.
+func dividePackagesForCurrentImage(allConfigs []*config.Config, imageName string) ([]bacpack_package.Package, []bacpack_package.Package) {
+	var packagesForImage []bacpack_package.Package
+	var packagesNotForImage []bacpack_package.Package
+
+	for _, config := range allConfigs {
+		if slices.Contains(config.DockerMatrix.ImageNames, imageName) {
+			packagesForImage = append(packagesForImage, config.Package)
+		} else {
+			packagesNotForImage = append(packagesNotForImage, config.Package)
+		}
+	}
+
+	return packagesForImage, packagesNotForImage
+}
+
+// compareConfigsAndStore
+// Compares Packages/Apps (depends on packageOrApp string) in Context and in the store and returns
+// four arrays of strings - error Paths, expected Paths for imageName, expected paths not for
+// imageName and superseded Paths. The superseded Paths are older versions of Packages/Apps in
+// Context, they are returned only if keepSuperseded is true, else they are error Paths.
+func compareConfigsAndStore(
+	store          fileStore,
+	platformString *bacpack_package.PlatformString,
+	configs        []*config.Config,
+	imageName      string,
+	packageOrApp   string,
+	keepSuperseded bool,
+) (error, []string, []string, []string, []string) {
+	packagesForImage, packagesNotForImage := dividePackagesForCurrentImage(configs, imageName)
+
+	var errorPaths, expectedPathsForImage, expectedPathsNotForImage, supersededPaths []string
+	for _, pack := range packagesForImage {
+		expectedPathsForImage = append(expectedPathsForImage, getArchiveFilePath(pack, packageOrApp))
+	}
+	for _, pack := range packagesNotForImage {
+		expectedPathsNotForImage = append(expectedPathsNotForImage, getArchiveFilePath(pack, packageOrApp))
+	}
+
+	lookupPath := path.Join(
+		packageOrApp,
+		platformString.String.DistroName,
+		platformString.String.DistroRelease,
+		platformString.String.Machine,
+	)
+	files, err := store.listFiles(lookupPath)
+	if err != nil {
+		return err, []string{}, []string{}, []string{}, []string{}
+	}
+	configsMap := getConfigsMap(configs)
+	filesSet := getFilesSet(files)
+	for _, filePath := range files {
+		if strings.HasSuffix(filePath, ManifestExt) {
+			// Manifest belongs to the archive next to it, which is checked itself
+			if _, found := filesSet[getArchivePath(filePath)]; found {
+				continue
+			}
+		}
+		index := slices.Index(expectedPathsForImage, filePath)
+		if index < 0 {
+			if keepSuperseded && isSupersededArchive(filePath, packageOrApp, configsMap) {
+				supersededPaths = append(supersededPaths, filePath)
+			} else {
+				errorPaths = append(errorPaths, filePath)
+			}
+		} else {
+			// Remove element from expected package paths
+			expectedPathsForImage[index] = expectedPathsForImage[len(expectedPathsForImage) - 1]
+			expectedPathsForImage = expectedPathsForImage[:len(expectedPathsForImage) - 1]
+		}
+	}
+
+	return nil, errorPaths, expectedPathsForImage, expectedPathsNotForImage, supersededPaths
+}
+
+// isSupersededArchive
+// Returns true if the file on repoPath is an archive of older version of Package/App from
+// configsMap in the directory of its platform.
+func isSupersededArchive(repoPath string, packageOrApp string, configsMap map[string][]*config.Config) bool {
+	pathParts := strings.Split(strings.TrimPrefix(repoPath, packageOrApp + "/"), "/")
+	if len(pathParts) != archivePathDepth || path.Ext(repoPath) != bacpack_package.ZipExt {
+		return false
+	}
+	nameParts, err := bacpack_package.ParseFullPackageName(path.Base(repoPath))
+	if err != nil {
+		return false
+	}
+	if nameParts.PlatformString != pathParts[2] + "-" + pathParts[0] + "-" + pathParts[1] {
+		return false
+	}
+	issue := checkArchiveConfig(pathParts[3], nameParts, configsMap, true)
+	return issue != nil && issue.Type == IssueSuperseded
+}
+
+// checkStoreConsistency
+// Checks consistency of the store based on Context. Prints and returns errors if in the store is
+// any Package/App which is not in Context. Prints warnings if the store is missing any
+// Packages/Apps present in Context and prints warnings if any Package/App won't build for current
+// imageName. If keepSuperseded is true, older versions of Packages/Apps in Context are not errors,
+// only their count is printed.
+func checkStoreConsistency(
+	store          fileStore,
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+	imageName      string,
+	keepSuperseded bool,
+) error {
+	configs := contextManager.GetAllPackageConfigsArray(platformString)
+	err, errorPaths, expectedPathsForImage, expectedPathsNotForImage, supersededPaths := compareConfigsAndStore(store, platformString, configs, imageName, constants.PackageDirName, keepSuperseded)
+	if err != nil {
+		return err
+	}
+
+	configs = contextManager.GetAllAppConfigsArray(platformString)
+	err, errorPaths_Apps, expectedPathsForImage_Apps, expectedPathsNotForImage_Apps, supersededPaths_Apps := compareConfigsAndStore(store, platformString, configs, imageName, constants.AppDirName, keepSuperseded)
+	if err != nil {
+		return err
+	}
+
+	errorPaths = append(errorPaths, errorPaths_Apps...)
+	expectedPathsForImage = append(expectedPathsForImage, expectedPathsForImage_Apps...)
+	expectedPathsNotForImage = append(expectedPathsNotForImage, expectedPathsNotForImage_Apps...)
+	supersededPaths = append(supersededPaths, supersededPaths_Apps...)
+
+	if len(supersededPaths) > 0 {
+		log.GetLogger().Info("%d superseded versions of Packages/Apps are kept in Package Repository", len(supersededPaths))
+	}
+	return printErrors(errorPaths, expectedPathsForImage, expectedPathsNotForImage)
+}
+
+// printErrors
+// Prints errors and warnings for Package Repository consistency check.
+func printErrors(errorPaths []string, expectedPathsForImage []string, expectedPathsNotForImage []string) error {
+	logger := log.GetLogger()
+	if len(errorPaths) > 0 {
+		logger.Error("%d Packages/Apps are not in Json definitions but are in Package Repository (listing first %d):", len(errorPaths), listFileCount)
+		for i, errorPath := range errorPaths {
+			if i > listFileCount - 1 {
+				break
+			}
+			logger.ErrorIndent("%s", errorPath)
+		}
+		return fmt.Errorf("Packages/Apps in Package Repository are not subset of Packages/Apps in Json definitions")
+	}
+
+	if len(expectedPathsForImage) > 0 {
+		logger.Warn("Expected %d Packages/Apps (built for target image) to be in Package Repository (listing first %d):", len(expectedPathsForImage), listFileCount)
+		for i, expectedPathForImage := range expectedPathsForImage {
+			if i > listFileCount - 1 {
+				break
+			}
+			logger.WarnIndent("%s", expectedPathForImage)
+		}
+	}
+	if len(expectedPathsNotForImage) > 0 {
+		logger.Warn("%d Packages/Apps are in context but are not built for target image (listing first %d):", len(expectedPathsNotForImage), listFileCount)
+		for i, expectedPathNotForImage := range expectedPathsNotForImage {
+			if i > listFileCount - 1 {
+				break
+			}
+			logger.WarnIndent("%s", expectedPathNotForImage)
+		}
+	}
+	return nil
+}
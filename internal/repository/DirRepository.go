@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"fmt"
+	"os"
+	"path"
+)
+
+// DirRepository
+// Package/App repository in a plain directory. The archives are stored in the same directory
+// structure as in GitLFSRepository, but no history is kept.
+type DirRepository struct {
+	// RepoPath path to the repository directory
+	RepoPath string
+	// config of the repository read from the repository root
+	config   RepositoryConfig
+}
+
+type dirRepositoryInitArgs struct {
+	RepoPath string
+}
+
+func (dir *DirRepository) FillDefault(*prerequisites.Args) error {
+	return nil
+}
+
+func (dir *DirRepository) FillDynamic(args *prerequisites.Args) error {
+	if !prerequisites.IsEmpty(args) {
+		var argsStruct dirRepositoryInitArgs
+		prerequisites.GetArgs(args, &argsStruct)
+		dir.RepoPath = argsStruct.RepoPath
+	}
+	return nil
+}
+
+func (dir *DirRepository) CheckPrerequisites(*prerequisites.Args) error {
+	info, err := os.Stat(dir.RepoPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("package repository '%s' does not exist", dir.RepoPath)
+	} else if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("package repository '%s' is not a directory", dir.RepoPath)
+	}
+	dir.config, err = readRepositoryConfig(dir.getStore())
+	return err
+}
+
+// GetConfig
+// Returns RepositoryConfig read from the repository by CheckPrerequisites.
+func (dir *DirRepository) GetConfig() RepositoryConfig {
+	return dir.config
+}
+
+// CreatePath
+// Returns path of the directory for specific pack inside the repository. The path depends on
+// packageOrApp string which should be either "package" or "app".
+func (dir *DirRepository) CreatePath(pack bacpack_package.Package, packageOrApp string) string {
+	return path.Join(dir.RepoPath, getArchiveDir(pack, packageOrApp))
+}
+
+// CopyToRepository
+// Copies the pack with its manifest to the repository, more in Repository.CopyToRepository. The
+// files are written to temporary files and renamed, so the interrupted copy does not leave
+// incomplete archive in the repository.
+func (dir *DirRepository) CopyToRepository(
+	pack         bacpack_package.Package,
+	sourceDir    string,
+	packageOrApp string,
+	provenance   Provenance,
+) error {
+	return copyToStore(dir.getStore(), pack, sourceDir, packageOrApp, provenance)
+}
+
+// CheckConsistency
+// Checks the repository consistency based on Context, more in Repository.CheckConsistency.
+func (dir *DirRepository) CheckConsistency(
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+	imageName      string,
+	keepSuperseded bool,
+) error {
+	return checkStoreConsistency(dir.getStore(), contextManager, platformString, imageName, keepSuperseded)
+}
+
+// FetchArchive
+// Returns path of the archive of pack in the repository, more in Repository.FetchArchive.
+func (dir *DirRepository) FetchArchive(pack bacpack_package.Package, packageOrApp string, tmpDir string) (string, error) {
+	return fetchArchive(dir.getStore(), pack, packageOrApp, tmpDir)
+}
+
+// GetArchiveSha256
+// Returns SHA-256 of the archive of pack, more in Repository.GetArchiveSha256.
+func (dir *DirRepository) GetArchiveSha256(pack bacpack_package.Package, packageOrApp string) (string, error) {
+	return getStoreArchiveSha256(dir.getStore(), pack, packageOrApp)
+}
+
+// Verify
+// Verifies all archives in the repository, more in Verification.go.
+func (dir *DirRepository) Verify(contextManager *context.ContextManager, keepSuperseded bool) ([]VerificationIssue, error) {
+	return verifyStore(dir.getStore(), contextManager, keepSuperseded)
+}
+
+// Prune
+// Removes archives which are not in Context, more in Repository.Prune.
+func (dir *DirRepository) Prune(contextManager *context.ContextManager, platformString *bacpack_package.PlatformString, keep int, dryRun bool) ([]string, error) {
+	return pruneStore(dir.getStore(), contextManager, platformString, keep, dryRun)
+}
+
+// RestoreAllChanges
+// Removes temporary files left by the interrupted CopyToRepository. The archives and manifests
+// are stored atomically, so nothing else has to be restored.
+func (dir *DirRepository) RestoreAllChanges() error {
+	store := localStore{root: dir.RepoPath}
+	return store.removeTmpFiles()
+}
+
+// getStore
+// Returns store of the repository directory.
+func (dir *DirRepository) getStore() fileStore {
+	return &localStore{root: dir.RepoPath}
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	// Pattern of the temporary files created next to the stored file
+	tmpFilePattern = ".tmp-*"
+)
+
+// fileStore
+// Storage of the repository files. Paths of the files are slash separated and relative to the
+// repository root.
+type fileStore interface {
+	// listFiles returns paths of all files in dir (recursively), empty if dir does not exist
+	listFiles(dir string) ([]string, error)
+	// fetchFile returns local path of the file. If the store is not local, the file is downloaded
+	// to tmpDir with the same file name. Returns error wrapping fs.ErrNotExist if the file does not
+	// exist.
+	fetchFile(filePath string, tmpDir string) (string, error)
+	// storeFile stores the local file on localPath as filePath, the existing file is replaced
+	storeFile(localPath string, filePath string) error
+	// removeFile removes the file, it is not an error if the file does not exist
+	removeFile(filePath string) error
+}
+
+// localStore
+// Stores the files in a directory on the local filesystem. Git metadata (.git directories) are
+// not listed.
+type localStore struct {
+	root string
+}
+
+func (store *localStore) listFiles(dir string) ([]string, error) {
+	files := []string{}
+	dirPath := store.getLocalPath(dir)
+	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
+		return files, nil
+	}
+	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(store.root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (store *localStore) fetchFile(filePath string, tmpDir string) (string, error) {
+	localPath := store.getLocalPath(filePath)
+	_, err := os.Stat(localPath)
+	if err != nil {
+		return "", err
+	}
+	return localPath, nil
+}
+
+func (store *localStore) storeFile(localPath string, filePath string) error {
+	destPath := store.getLocalPath(filePath)
+	err := os.MkdirAll(filepath.Dir(destPath), 0755)
+	if err != nil {
+		return err
+	}
+	// The file is copied to a temporary file and renamed, so incomplete file is never in the store
+	tmpFile, err := os.CreateTemp(filepath.Dir(destPath), tmpFilePattern)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	err = copyFile(localPath, tmpFile)
+	tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("cannot copy %s - %w", filePath, err)
+	}
+	err = os.Chmod(tmpFile.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), destPath)
+}
+
+func (store *localStore) removeFile(filePath string) error {
+	err := os.Remove(store.getLocalPath(filePath))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// removeTmpFiles
+// Removes temporary files left by interrupted storeFile.
+func (store *localStore) removeTmpFiles() error {
+	files, err := store.listFiles(".")
+	if err != nil {
+		return err
+	}
+	for _, filePath := range files {
+		matched, _ := path.Match(tmpFilePattern, path.Base(filePath))
+		if !matched {
+			continue
+		}
+		err = store.removeFile(filePath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLocalPath
+// Returns path of the file on the local filesystem.
+func (store *localStore) getLocalPath(filePath string) string {
+	return filepath.Join(store.root, filepath.FromSlash(filePath))
+}
+
+// copyFile
+// Copies content of the file on srcPath to writer.
+func copyFile(srcPath string, writer io.Writer) error {
+	srcFile, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	_, err = io.Copy(writer, srcFile)
+	return err
+}
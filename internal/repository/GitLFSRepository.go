@@ -3,52 +3,70 @@ package repository
 import (
 	"github.com/bacpack-system/packager/internal/bacpack_package"
 	"github.com/bacpack-system/packager/internal/prerequisites"
-	"github.com/bacpack-system/packager/internal/log"
 	"github.com/bacpack-system/packager/internal/context"
-	"github.com/bacpack-system/packager/internal/config"
-	"github.com/bacpack-system/packager/internal/constants"
+	"github.com/bacpack-system/packager/internal/log"
 	"bytes"
 	"fmt"
-	"io/fs"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
-	"slices"
+	"sort"
+	"strings"
+	"sync"
 )
 
 // GitLFSRepository represents Package/App repository based on Git LFS
 type GitLFSRepository struct {
 	GitRepoPath string
+	// transaction in progress, nil if each Package/App is committed separately
+	transaction *gitTransaction
+	// config of the repository read from the repository root
+	config      RepositoryConfig
+}
+
+type gitLFSRepositoryInitArgs struct {
+	GitRepoPath string
 }
 
 const (
 	gitExecutablePath = "/usr/bin/git"
-	// Count of files which will be list in warnings
-	listFileCount = 10
 )
 
+// gitLock serializes all git operations which change the repository, so the Packages/Apps can be
+// copied to the repository from multiple goroutines.
+var gitLock sync.Mutex
+
 func (lfs *GitLFSRepository) FillDefault(args *prerequisites.Args) error {
 	return nil
 }
 
 func (lfs *GitLFSRepository) FillDynamic(args *prerequisites.Args) error {
+	if !prerequisites.IsEmpty(args) {
+		var argsStruct gitLFSRepositoryInitArgs
+		prerequisites.GetArgs(args, &argsStruct)
+		lfs.GitRepoPath = argsStruct.GitRepoPath
+	}
 	return nil
 }
 
 func (lfs *GitLFSRepository) CheckPrerequisites(*prerequisites.Args) error {
-	if _, err := os.Stat(lfs.GitRepoPath); os.IsNotExist(err) {
-		return fmt.Errorf("package repository '%s' does not exist", lfs.GitRepoPath)
+	err := lfs.checkGitRepository()
+	if err != nil {
+		return err
 	}
-	if _, err := os.Stat(lfs.GitRepoPath + "/.git"); os.IsNotExist(err) {
-		return fmt.Errorf("package repository '%s' is not a git repository", lfs.GitRepoPath)
+	err = lfs.checkLFSSetup()
+	if err != nil {
+		log.GetLogger().Warn("%s", err)
 	}
+	lfs.config, err = readRepositoryConfig(lfs.getStore())
+	return err
+}
 
-	isStatusEmpty := lfs.gitIsStatusEmpty()
-	if !isStatusEmpty {
-		return fmt.Errorf("sorry, but the given git root does not have empty `git status`. clean up changes and try again")
-	}
-	return nil
+// GetConfig
+// Returns RepositoryConfig read from the repository by CheckPrerequisites.
+func (lfs *GitLFSRepository) GetConfig() RepositoryConfig {
+	return lfs.config
 }
 
 // commitPackage
@@ -69,6 +87,8 @@ func (lfs *GitLFSRepository) commitPackage(packageName string) error {
 // RestoreAllChanges
 // Restores all changes in repository and cleans all untracked changes.
 func (lfs *GitLFSRepository) RestoreAllChanges() error {
+	gitLock.Lock()
+	defer gitLock.Unlock()
 	var err error
 	if !lfs.isRepoEmpty() {
 		err = lfs.gitRestoreAll()
@@ -84,200 +104,118 @@ func (lfs *GitLFSRepository) RestoreAllChanges() error {
 	return nil
 }
 
-// dividePackagesForCurrentImage
-// Divides allConfigs to packages for imageName and not for imageName and returns 2 slices.
-func dividePackagesForCurrentImage(allConfigs []*config.Config, imageName string) ([]bacpack_package.Package, []bacpack_package.Package) {
-	var packagesForImage []bacpack_package.Package
-	var packagesNotForImage []bacpack_package.Package
-
-	for _, config := range allConfigs {
-		if slices.Contains(config.DockerMatrix.ImageNames, imageName) {
-			packagesForImage = append(packagesForImage, config.Package)
-		} else {
-			packagesNotForImage = append(packagesNotForImage, config.Package)
-		}
-	}
+// CheckConsistency
+// Checks Git Lfs consistency based on Context, more in Repository.CheckConsistency.
+func (lfs *GitLFSRepository) CheckConsistency(
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+	imageName      string,
+	keepSuperseded bool,
+) error {
+	return checkStoreConsistency(lfs.getStore(), contextManager, platformString, imageName, keepSuperseded)
+}
 
-	return packagesForImage, packagesNotForImage
+// CreatePath
+// Returns path for specific pack inside Git Lfs. The path depends on packageOrApp string which
+// should be either "package" or "app".
+func (lfs *GitLFSRepository) CreatePath(pack bacpack_package.Package, packageOrApp string) string {
+	return path.Join(lfs.GitRepoPath, getArchiveDir(pack, packageOrApp))
 }
 
-// compareConfigsAndGitLfs
-// Compares Packages/Apps (depends on packageOrApp string) in Context and in Git Lfs and returns
-// three arrays of strings - error Paths, expected Paths for imageName and expected paths not for
-// imageName.
-func (lfs *GitLFSRepository) compareConfigsAndGitLfs(
-	platformString *bacpack_package.PlatformString,
-	configs []*config.Config,
-	imageName string,
+// CopyToRepository
+// Copies the pack to the Git LFS repository. packageOrApp is a string representing type of
+// Package, it should be either "package" or "app". Each Package/App is stored in different
+// directory structure represented by
+// packageOrApp / PlatformString.DistroName / PlatformString.DistroRelease / PlatformString.Machine / <package>
+// The manifest with content checksums and provenance is stored next to the archive and committed
+// together with it. It is safe to call it from multiple goroutines, the commits are serialized.
+// If a transaction is in progress, the archive is only staged and committed by CommitTransaction.
+func (lfs *GitLFSRepository) CopyToRepository(
+	pack         bacpack_package.Package,
+	sourceDir    string,
 	packageOrApp string,
-) (error, []string, []string, []string) {
-	packagesForImage, packagesNotForImage := dividePackagesForCurrentImage(configs, imageName)
+	provenance   Provenance,
+) error {
+	gitLock.Lock()
+	defer gitLock.Unlock()
 
-	var errorPaths, expectedPathsForImage, expectedPathsNotForImage []string
-	for _, pack := range packagesForImage {
-		packPath := filepath.Join(lfs.CreatePath(pack, packageOrApp) + "/" + pack.GetFullPackageName() + ".zip")
-		expectedPathsForImage = append(expectedPathsForImage, packPath)
-	}
-	for _, pack := range packagesNotForImage {
-		packPath := filepath.Join(lfs.CreatePath(pack, packageOrApp) + "/" + pack.GetFullPackageName() + ".zip")
-		expectedPathsNotForImage = append(expectedPathsNotForImage, packPath)
+	err := copyToStore(lfs.getStore(), pack, sourceDir, packageOrApp, provenance)
+	if err != nil {
+		return err
 	}
 
-	lookupPath := filepath.Join(
-		lfs.GitRepoPath,
-		packageOrApp,
-		platformString.String.DistroName,
-		platformString.String.DistroRelease,
-		platformString.String.Machine,
-	)
-
-	_, err := os.Stat(lookupPath)
-	if !os.IsNotExist(err) {
-		err = filepath.WalkDir(lookupPath, func(path string, d fs.DirEntry, err error) error {
-			if d.Name() == ".git" && d.IsDir() {
-				return filepath.SkipDir
-			}
-			if !d.IsDir() {
-				if !slices.Contains(expectedPathsForImage, path) {
-					errorPaths = append(errorPaths, path)
-				} else {
-					// Remove element from expected package paths
-					index := slices.Index(expectedPathsForImage, path)
-					expectedPathsForImage[index] = expectedPathsForImage[len(expectedPathsForImage) - 1]
-					expectedPathsForImage = expectedPathsForImage[:len(expectedPathsForImage) - 1]
-				}
-			}
-			return nil
-		})
+	if lfs.transaction != nil {
+		err = lfs.gitAddAll()
 		if err != nil {
-			return err, []string{}, []string{}, []string{}
+			return err
 		}
+		lfs.transaction.packageNames = append(lfs.transaction.packageNames, pack.GetFullPackageName())
+		return nil
 	}
-
-	return nil, errorPaths, expectedPathsForImage, expectedPathsNotForImage
-}
-
-// comparePackagesAndGitLfs
-// Compares Packages in Context and in Git Lfs and returns three arrays of strings - error Paths,
-// expected Paths for imageName and expected paths not for imageName.
-func (lfs *GitLFSRepository) comparePackagesAndGitLfs(
-	contextManager *context.ContextManager,
-	platformString *bacpack_package.PlatformString,
-	imageName string,
-) (error, []string, []string, []string) {
-	configs := contextManager.GetAllPackageConfigsArray(platformString)
-	return lfs.compareConfigsAndGitLfs(platformString, configs, imageName, constants.PackageDirName)
-}
-
-// compareAppsAndGitLfs
-// Compares Apps in Context and in Git Lfs and returns three arrays of strings - error Paths,
-// expected Paths for imageName and expected paths not for imageName.
-func (lfs *GitLFSRepository) compareAppsAndGitLfs(
-	contextManager *context.ContextManager,
-	platformString *bacpack_package.PlatformString,
-	imageName string,
-) (error, []string, []string, []string) {
-	configs := contextManager.GetAllAppConfigsArray(platformString)
-	return lfs.compareConfigsAndGitLfs(platformString, configs, imageName, constants.AppDirName)
-}
-
-// CheckGitLfsConsistency
-// Checks Git Lfs consistency based on Context. Prints and returns errors if in Git Lfs is any
-// Package/App which is not in Context. Prints warnings if the Git Lfs is missing any Packages/Apps
-// present in Context and prints warnings if any Package/App won't build for current imageName.
-func (lfs *GitLFSRepository) CheckGitLfsConsistency(contextManager *context.ContextManager, platformString *bacpack_package.PlatformString, imageName string) error {
-	err, errorPaths, expectedPathsForImage, expectedPathsNotForImage := lfs.comparePackagesAndGitLfs(contextManager, platformString, imageName)
+	err = lfs.commitPackage(pack.GetFullPackageName())
 	if err != nil {
 		return err
 	}
 
-	err, errorPaths_Apps, expectedPathsForImage_Apps, expectedPathsNotForImage_Apps := lfs.compareAppsAndGitLfs(contextManager, platformString, imageName)
-
-	errorPaths = append(errorPaths, errorPaths_Apps...)
-	expectedPathsForImage = append(expectedPathsForImage, expectedPathsForImage_Apps...)
-	expectedPathsNotForImage = append(expectedPathsNotForImage, expectedPathsNotForImage_Apps...)
-
-	err = printErrors(errorPaths, expectedPathsForImage, expectedPathsNotForImage)
-	if err != nil {
-		return err
-	}
 	return nil
 }
 
-// printErrors
-// Prints errors and warnings for Git Lfs consistency check.
-func printErrors(errorPaths []string, expectedPathsForImage []string, expectedPathsNotForImage []string) error {
-	logger := log.GetLogger()
-	if len(errorPaths) > 0 {
-		logger.Error("%d Packages/Apps are not in Json definitions but are in Git Lfs (listing first %d):", len(errorPaths), listFileCount)
-		for i, errorPath := range errorPaths {
-			if i > listFileCount - 1 {
-				break
-			}
-			logger.ErrorIndent("%s", errorPath)
-		}
-		return fmt.Errorf("Packages/Apps in Git Lfs are not subset of Packages/Apps in Json definitions")
-	}
-
-	if len(expectedPathsForImage) > 0 {
-		logger.Warn("Expected %d Packages/Apps (built for target image) to be in Git Lfs (listing first %d):", len(expectedPathsForImage), listFileCount)
-		for i, expectedPathForImage := range expectedPathsForImage {
-			if i > listFileCount - 1 {
-				break
-			}
-			logger.WarnIndent("%s", expectedPathForImage)
-		}
-	}
-	if len(expectedPathsNotForImage) > 0 {
-		logger.Warn("%d Packages/Apps are in context but are not built for target image (listing first %d):", len(expectedPathsNotForImage), listFileCount)
-		for i, expectedPathNotForImage := range expectedPathsNotForImage {
-			if i > listFileCount - 1 {
-				break
-			}
-			logger.WarnIndent("%s", expectedPathNotForImage)
-		}
-	}
-	return nil
+// FetchArchive
+// Returns path of the archive of pack in Git Lfs, more in Repository.FetchArchive.
+func (lfs *GitLFSRepository) FetchArchive(pack bacpack_package.Package, packageOrApp string, tmpDir string) (string, error) {
+	return fetchArchive(lfs.getStore(), pack, packageOrApp, tmpDir)
 }
 
-// CreatePath
-// Returns path for specific pack inside Git Lfs. The path depends on packageOrApp string which
-// should be either "package" or "app".
-func (lfs *GitLFSRepository) CreatePath(pack bacpack_package.Package, packageOrApp string) string {
-	repositoryPath := path.Join(
-		pack.PlatformString.String.DistroName,
-		pack.PlatformString.String.DistroRelease,
-		pack.PlatformString.String.Machine,
-		pack.Name,
-	)
-	return path.Join(lfs.GitRepoPath, packageOrApp, repositoryPath)
+// GetArchiveSha256
+// Returns SHA-256 of the archive of pack in Git Lfs, more in Repository.GetArchiveSha256.
+func (lfs *GitLFSRepository) GetArchiveSha256(pack bacpack_package.Package, packageOrApp string) (string, error) {
+	return getStoreArchiveSha256(lfs.getStore(), pack, packageOrApp)
 }
 
-// CopyToRepository
-// Copies the pack to the Git LFS repository. packageOrApp is a string representing type of
-// Package, it should be either "package" or "app". Each Package/App is stored in different
-// directory structure represented by
-// packageOrApp / PlatformString.DistroName / PlatformString.DistroRelease / PlatformString.Machine / <package>
-func (lfs *GitLFSRepository) CopyToRepository(pack bacpack_package.Package, sourceDir string, packageOrApp string) error {
-	archiveDirectory := lfs.CreatePath(pack, packageOrApp)
-
-	var err error
-	err = os.MkdirAll(archiveDirectory, 0755)
+// Verify
+// Verifies all archives in Git Lfs, more in Verification.go. The committed archives must be Git
+// LFS pointers with objects in local Git LFS storage, more in verifyLFSPointers.
+func (lfs *GitLFSRepository) Verify(contextManager *context.ContextManager, keepSuperseded bool) ([]VerificationIssue, error) {
+	issues, err := verifyStore(lfs.getStore(), contextManager, keepSuperseded)
 	if err != nil {
-		return err
+		return []VerificationIssue{}, err
 	}
-
-	err = pack.CreatePackage(sourceDir, archiveDirectory)
+	lfsIssues, err := lfs.verifyLFSPointers()
 	if err != nil {
-		return err
+		return []VerificationIssue{}, err
 	}
+	issues = append(issues, lfsIssues...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Path < issues[j].Path
+	})
+	return issues, nil
+}
 
-	err = lfs.commitPackage(pack.GetFullPackageName())
+// Prune
+// Removes archives which are not in Context and commits the removal, more in Repository.Prune.
+func (lfs *GitLFSRepository) Prune(contextManager *context.ContextManager, platformString *bacpack_package.PlatformString, keep int, dryRun bool) ([]string, error) {
+	gitLock.Lock()
+	defer gitLock.Unlock()
+	paths, err := pruneStore(lfs.getStore(), contextManager, platformString, keep, dryRun)
+	if err != nil || dryRun || len(paths) == 0 {
+		return paths, err
+	}
+	err = lfs.gitAddAll()
 	if err != nil {
-		return err
+		return paths, err
+	}
+	message := fmt.Sprintf("Prune %d files not in Context\n\n%s", len(paths), strings.Join(paths, "\n"))
+	err = lfs.gitCommitMessage(message)
+	if err != nil {
+		return paths, err
 	}
+	return paths, nil
+}
 
-	return nil
+// getStore
+// Returns store of the Git Lfs working tree.
+func (lfs *GitLFSRepository) getStore() fileStore {
+	return &localStore{root: lfs.GitRepoPath}
 }
 
 // gitIsStatusEmpty
@@ -314,10 +252,16 @@ func (lfs *GitLFSRepository) gitAddAll() error {
 // gitCommit
 // Commits Git Lfs with packageName description.
 func (lfs *GitLFSRepository) gitCommit(packageName string) error {
+	return lfs.gitCommitMessage("Build package " + packageName)
+}
+
+// gitCommitMessage
+// Commits Git Lfs with message.
+func (lfs *GitLFSRepository) gitCommitMessage(message string) error {
 	var ok, _ = lfs.prepareAndRun([]string{
 		"commit",
 		"-m",
-		"Build package " + packageName,
+		message,
 	},
 	)
 	if !ok {
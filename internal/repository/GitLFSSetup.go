@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/constants"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// lfsArchivePattern pattern of the archives tracked by Git LFS
+	lfsArchivePattern = "*" + bacpack_package.ZipExt
+	// lfsFilter name of the Git LFS filter in git attributes and config
+	lfsFilter = "lfs"
+)
+
+// SetupGitLFS
+// Sets up Git LFS in the git repository in gitRepoPath, so it can be used as Package Repository.
+// Git LFS is installed to the repository config and the archives are tracked by Git LFS. The
+// changed .gitattributes is committed. The git status of the repository must be empty.
+func SetupGitLFS(gitRepoPath string) error {
+	lfs := GitLFSRepository{GitRepoPath: gitRepoPath}
+	err := lfs.checkGitRepository()
+	if err != nil {
+		return err
+	}
+	var ok, _ = lfs.prepareAndRun([]string{
+		"lfs",
+		"install",
+		"--local",
+	},
+	)
+	if !ok {
+		return fmt.Errorf("cannot install Git LFS to package repository '%s', is git-lfs installed?", gitRepoPath)
+	}
+	if lfs.isArchiveTrackedByLFS() {
+		return nil
+	}
+	ok, _ = lfs.prepareAndRun([]string{
+		"lfs",
+		"track",
+		lfsArchivePattern,
+	},
+	)
+	if !ok {
+		return fmt.Errorf("cannot track %s by Git LFS in package repository '%s'", lfsArchivePattern, gitRepoPath)
+	}
+	err = lfs.gitAddAll()
+	if err != nil {
+		return err
+	}
+	return lfs.gitCommitMessage("Track archives by Git LFS")
+}
+
+// checkGitRepository
+// Checks that GitRepoPath is a git repository with empty git status.
+func (lfs *GitLFSRepository) checkGitRepository() error {
+	if _, err := os.Stat(lfs.GitRepoPath); os.IsNotExist(err) {
+		return fmt.Errorf("package repository '%s' does not exist", lfs.GitRepoPath)
+	}
+	if _, err := os.Stat(lfs.GitRepoPath + "/.git"); os.IsNotExist(err) {
+		return fmt.Errorf("package repository '%s' is not a git repository", lfs.GitRepoPath)
+	}
+
+	isStatusEmpty := lfs.gitIsStatusEmpty()
+	if !isStatusEmpty {
+		return fmt.Errorf("sorry, but the given git root does not have empty `git status`. clean up changes and try again")
+	}
+	return nil
+}
+
+// checkLFSSetup
+// Checks that git-lfs is installed, it is configured in the repository and the archives in package
+// and app directories are tracked by Git LFS, so they are not stored as git blobs. Git LFS is
+// optional, so the caller should only warn about the returned error.
+func (lfs *GitLFSRepository) checkLFSSetup() error {
+	if !lfs.isLFSInstalled() {
+		return fmt.Errorf("git-lfs is not installed, archives in package repository '%s' are stored as git blobs", lfs.GitRepoPath)
+	}
+	if !lfs.isLFSConfigured() {
+		return fmt.Errorf("Git LFS is not installed in package repository '%s', set it up by 'bap-builder repo setup-lfs --git-lfs %s'", lfs.GitRepoPath, lfs.GitRepoPath)
+	}
+	if !lfs.isArchiveTrackedByLFS() {
+		return fmt.Errorf("archives (%s) are not tracked by Git LFS in package repository '%s', set it up by 'bap-builder repo setup-lfs --git-lfs %s'", lfsArchivePattern, lfs.GitRepoPath, lfs.GitRepoPath)
+	}
+	return nil
+}
+
+// isLFSInstalled
+// Returns true if the git lfs command can be run.
+func (lfs *GitLFSRepository) isLFSInstalled() bool {
+	var ok, _ = lfs.prepareAndRun([]string{
+		"lfs",
+		"version",
+	},
+	)
+	return ok
+}
+
+// isLFSConfigured
+// Returns true if the Git LFS filter is configured for the repository (by 'git lfs install').
+func (lfs *GitLFSRepository) isLFSConfigured() bool {
+	var ok, buffer = lfs.prepareAndRun([]string{
+		"config",
+		"--get",
+		"filter." + lfsFilter + ".clean",
+	},
+	)
+	return ok && strings.TrimSpace(buffer.String()) != ""
+}
+
+// isArchiveTrackedByLFS
+// Returns true if the archives in both package and app directories have Git LFS filter attribute.
+func (lfs *GitLFSRepository) isArchiveTrackedByLFS() bool {
+	for _, packageOrApp := range []string{constants.PackageDirName, constants.AppDirName} {
+		var ok, buffer = lfs.prepareAndRun([]string{
+			"check-attr",
+			"filter",
+			"--",
+			path.Join(packageOrApp, "archive" + bacpack_package.ZipExt),
+		},
+		)
+		if !ok {
+			return false
+		}
+		_, filter, _ := strings.Cut(strings.TrimSpace(buffer.String()), ": filter: ")
+		if filter != lfsFilter {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// lfsPointerVersion first line of the Git LFS pointer
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+	// lfsPointerOidPrefix prefix of the pointer line with SHA-256 of the Git LFS object
+	lfsPointerOidPrefix = "oid sha256:"
+	// lfsPointerMaxSize maximal size of the Git LFS pointer, larger blobs are never pointers
+	lfsPointerMaxSize = 1024
+)
+
+// verifyLFSPointers
+// Checks that all archives committed in HEAD of Git Lfs are Git LFS pointers and their objects
+// are in local Git LFS storage. Returns issues of archives committed as git blobs and of pointers
+// with missing objects. The archives are checked even if they are not tracked by Git LFS, so the
+// archives committed as git blobs before Git LFS setup (or without it) are reported.
+func (lfs *GitLFSRepository) verifyLFSPointers() ([]VerificationIssue, error) {
+	if lfs.isRepoEmpty() {
+		return []VerificationIssue{}, nil
+	}
+	var ok, buffer = lfs.prepareAndRun([]string{
+		"ls-tree",
+		"-r",
+		"-l",
+		"-z",
+		"HEAD",
+	},
+	)
+	if !ok {
+		return []VerificationIssue{}, fmt.Errorf("cannot list files of Git Lfs")
+	}
+	gitDir, err := lfs.gitGetDir()
+	if err != nil {
+		return []VerificationIssue{}, err
+	}
+
+	var issues []VerificationIssue
+	for _, entry := range strings.Split(buffer.String(), "\x00") {
+		info, repoPath, found := strings.Cut(entry, "\t")
+		if !found || path.Ext(repoPath) != bacpack_package.ZipExt {
+			continue
+		}
+		// <mode> <type> <object> <size>
+		fields := strings.Fields(info)
+		if len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return []VerificationIssue{}, fmt.Errorf("cannot parse size of %s - %w", repoPath, err)
+		}
+		oid := ""
+		if size <= lfsPointerMaxSize {
+			ok, blobBuffer := lfs.prepareAndRun([]string{
+				"cat-file",
+				"blob",
+				fields[2],
+			},
+			)
+			if !ok {
+				return []VerificationIssue{}, fmt.Errorf("cannot read %s from Git Lfs", repoPath)
+			}
+			oid = parseLFSPointer(blobBuffer.String())
+		}
+		if oid == "" {
+			issues = append(issues, VerificationIssue{
+				Type:    IssueNotLFSPointer,
+				Path:    repoPath,
+				Message: "archive is committed as git blob, it is not tracked by Git LFS",
+			})
+			continue
+		}
+		objectPath := filepath.Join(gitDir, "lfs", "objects", oid[0:2], oid[2:4], oid)
+		if _, err := os.Stat(objectPath); os.IsNotExist(err) {
+			issues = append(issues, VerificationIssue{
+				Type:    IssueLFSObjectMissing,
+				Path:    repoPath,
+				Message: fmt.Sprintf("Git LFS object %s is not in local storage", oid),
+			})
+		}
+	}
+	return issues, nil
+}
+
+// parseLFSPointer
+// Returns SHA-256 of the Git LFS object from pointer content. Returns empty string if the content
+// is not a valid Git LFS pointer.
+func parseLFSPointer(content string) string {
+	lines := strings.Split(content, "\n")
+	if lines[0] != lfsPointerVersion {
+		return ""
+	}
+	for _, line := range lines[1:] {
+		oid, found := strings.CutPrefix(line, lfsPointerOidPrefix)
+		if !found {
+			continue
+		}
+		if _, err := hex.DecodeString(oid); err != nil || len(oid) != 64 {
+			return ""
+		}
+		return oid
+	}
+	return ""
+}
+
+// gitGetDir
+// Returns absolute path of the git directory of Git Lfs.
+func (lfs *GitLFSRepository) gitGetDir() (string, error) {
+	var ok, buffer = lfs.prepareAndRun([]string{
+		"rev-parse",
+		"--absolute-git-dir",
+	},
+	)
+	if !ok {
+		return "", fmt.Errorf("cannot get git directory of Git Lfs")
+	}
+	return strings.TrimSpace(buffer.String()), nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Trailers of the transaction commit message
+	packageTrailer         = "Package"
+	imageNameTrailer       = "Image-Name"
+	platformStringTrailer  = "Platform-String"
+	packagerVersionTrailer = "Packager-Version"
+)
+
+// gitTransaction
+// State of the transaction of GitLFSRepository.
+type gitTransaction struct {
+	// head commit hash before the transaction, empty if the repository has no commits
+	head         string
+	// packageNames full names of Packages/Apps copied in the transaction
+	packageNames []string
+}
+
+// BeginTransaction
+// Starts the transaction, more in TransactionalRepository. The Packages/Apps copied in the
+// transaction are staged and committed at once by CommitTransaction.
+func (lfs *GitLFSRepository) BeginTransaction() error {
+	gitLock.Lock()
+	defer gitLock.Unlock()
+	if lfs.transaction != nil {
+		return fmt.Errorf("transaction of Git Lfs is already in progress")
+	}
+	head := ""
+	if !lfs.isRepoEmpty() {
+		var err error
+		head, err = lfs.gitGetHead()
+		if err != nil {
+			return err
+		}
+	}
+	lfs.transaction = &gitTransaction{head: head}
+	return nil
+}
+
+// CommitTransaction
+// Commits all Packages/Apps copied in the transaction in one commit. The commit message contains
+// trailers with the Packages/Apps and info. Nothing is committed if no Package/App was copied.
+func (lfs *GitLFSRepository) CommitTransaction(info TransactionInfo) error {
+	gitLock.Lock()
+	defer gitLock.Unlock()
+	if lfs.transaction == nil {
+		return fmt.Errorf("no transaction of Git Lfs is in progress")
+	}
+	if len(lfs.transaction.packageNames) > 0 {
+		err := lfs.gitCommitMessage(getTransactionMessage(lfs.transaction.packageNames, info))
+		if err != nil {
+			return err
+		}
+	}
+	lfs.transaction = nil
+	return nil
+}
+
+// RollbackTransaction
+// Resets Git Lfs to the commit before the transaction and removes all untracked files, more in
+// TransactionalRepository.
+func (lfs *GitLFSRepository) RollbackTransaction() error {
+	gitLock.Lock()
+	defer gitLock.Unlock()
+	if lfs.transaction == nil {
+		return nil
+	}
+	var err error
+	if lfs.transaction.head == "" {
+		err = lfs.gitUnstageAll()
+	} else {
+		err = lfs.gitResetHard(lfs.transaction.head)
+	}
+	if err != nil {
+		return err
+	}
+	err = lfs.gitCleanAll()
+	if err != nil {
+		return err
+	}
+	lfs.transaction = nil
+	return nil
+}
+
+// getTransactionMessage
+// Returns commit message of the transaction with packageNames. The packageNames and info are
+// listed as git trailers.
+func getTransactionMessage(packageNames []string, info TransactionInfo) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "Build %d packages\n\n", len(packageNames))
+	for _, packageName := range packageNames {
+		fmt.Fprintf(&message, "%s: %s\n", packageTrailer, packageName)
+	}
+	fmt.Fprintf(&message, "%s: %s\n", imageNameTrailer, info.ImageName)
+	fmt.Fprintf(&message, "%s: %s\n", platformStringTrailer, info.PlatformString)
+	fmt.Fprintf(&message, "%s: %s\n", packagerVersionTrailer, info.PackagerVersion)
+	return message.String()
+}
+
+// gitGetHead
+// Returns hash of the HEAD commit of Git Lfs.
+func (lfs *GitLFSRepository) gitGetHead() (string, error) {
+	var ok, buffer = lfs.prepareAndRun([]string{
+		"rev-parse",
+		"HEAD",
+	},
+	)
+	if !ok {
+		return "", fmt.Errorf("cannot get HEAD of Git Lfs")
+	}
+	return strings.TrimSpace(buffer.String()), nil
+}
+
+// gitResetHard
+// Resets Git Lfs (index and working tree) to the commit.
+func (lfs *GitLFSRepository) gitResetHard(commit string) error {
+	var ok, _ = lfs.prepareAndRun([]string{
+		"reset",
+		"--hard",
+		"--quiet",
+		commit,
+	},
+	)
+	if !ok {
+		return fmt.Errorf("cannot reset Git Lfs to %s", commit)
+	}
+	return nil
+}
+
+// gitUnstageAll
+// Removes all files from the index of Git Lfs without commits, the files become untracked.
+func (lfs *GitLFSRepository) gitUnstageAll() error {
+	var ok, _ = lfs.prepareAndRun([]string{
+		"rm",
+		"-r",
+		"--cached",
+		"--quiet",
+		"--ignore-unmatch",
+		".",
+	},
+	)
+	if !ok {
+		return fmt.Errorf("cannot unstage changes in Git Lfs")
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// ManifestExt extension of the manifest file stored next to each archive
+	ManifestExt = ".manifest.json"
+	manifestIndent = "\x20\x20\x20\x20" // four spaces
+)
+
+// Provenance
+// Information about the build of the Package/App which is stored in the manifest.
+type Provenance struct {
+	// GitUri URI of the Package git repository
+	GitUri        string
+	// GitCommitHash commit hash of the built Package
+	GitCommitHash string
+	// ImageName name of the docker image used for the build
+	ImageName     string
+	// ImageId ID of the docker image used for the build
+	ImageId       string
+	// DependsOn dependencies of the Package with versions used for the build
+	DependsOn     []ManifestDependency
+}
+
+// ManifestDependency
+// Dependency of the Package with resolved version.
+type ManifestDependency struct {
+	Name       string
+	VersionTag string
+}
+
+// ManifestFile
+// One file in the archive.
+type ManifestFile struct {
+	Path   string
+	Size   uint64
+	Sha256 string
+}
+
+// Manifest
+// Describes content and provenance of one archive in the repository. It is stored as
+// <full package name>.manifest.json next to the archive.
+type Manifest struct {
+	// PackageName full name of the Package/App
+	PackageName   string
+	// ArchiveSha256 SHA-256 of the archive
+	ArchiveSha256 string
+	// Files all files in the archive
+	Files         []ManifestFile
+	Provenance
+}
+
+// createManifest
+// Creates manifest for the archive and writes it next to the archive.
+func createManifest(pack bacpack_package.Package, archivePath string, provenance Provenance) error {
+	archiveSha256, err := getFileSha256(archivePath)
+	if err != nil {
+		return fmt.Errorf("cannot compute archive checksum - %w", err)
+	}
+	files, err := getArchiveFiles(archivePath)
+	if err != nil {
+		return fmt.Errorf("cannot list archive files - %w", err)
+	}
+	if provenance.DependsOn == nil {
+		provenance.DependsOn = []ManifestDependency{}
+	}
+	manifest := Manifest{
+		PackageName:   pack.GetFullPackageName(),
+		ArchiveSha256: archiveSha256,
+		Files:         files,
+		Provenance:    provenance,
+	}
+	bytes, err := json.MarshalIndent(manifest, "", manifestIndent)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetManifestPath(archivePath), append(bytes, '\n'), 0644)
+}
+
+// ReadManifest
+// Reads manifest from manifestPath.
+func ReadManifest(manifestPath string) (Manifest, error) {
+	var manifest Manifest
+	bytes, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read manifest - %w", err)
+	}
+	err = json.Unmarshal(bytes, &manifest)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse manifest - %w", err)
+	}
+	return manifest, nil
+}
+
+// GetManifestPath
+// Returns path of the manifest for the archive.
+func GetManifestPath(archivePath string) string {
+	return strings.TrimSuffix(archivePath, bacpack_package.ZipExt) + ManifestExt
+}
+
+// GetArchiveSha256
+// Returns SHA-256 of the archive. The checksum is read from the manifest next to the archive, if
+// the manifest does not exist, the checksum is computed.
+func GetArchiveSha256(archivePath string) (string, error) {
+	manifest, err := ReadManifest(GetManifestPath(archivePath))
+	if err == nil && manifest.ArchiveSha256 != "" {
+		return manifest.ArchiveSha256, nil
+	}
+	return getFileSha256(archivePath)
+}
+
+// getArchivePath
+// Returns path of the archive for the manifest.
+func getArchivePath(manifestPath string) string {
+	return strings.TrimSuffix(manifestPath, ManifestExt) + bacpack_package.ZipExt
+}
+
+// getArchiveFiles
+// Returns all files (without directories) in the zip archive with their sizes and SHA-256.
+func getArchiveFiles(archivePath string) ([]ManifestFile, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	files := []ManifestFile{}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		fileReader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, fileReader)
+		fileReader.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ManifestFile{
+			Path:   file.Name,
+			Size:   file.UncompressedSize64,
+			Sha256: hex.EncodeToString(hash.Sum(nil)),
+		})
+	}
+	return files, nil
+}
+
+// getFileSha256
+// Returns hex encoded SHA-256 of the file.
+func getFileSha256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/config"
+	"github.com/bacpack-system/packager/internal/constants"
+	"github.com/bacpack-system/packager/internal/context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// getPrunePaths
+// Returns sorted paths of files in the store which are removed by prune. The archive is removed if
+// its Package/App or its VersionTag is not in Context or if it is in a directory of another
+// platform. The archive manifest is removed with the archive, the manifests without archive are
+// removed too. keep newest removable archives (by VersionTag) of each Package/App flavour
+// (release, debug, dev - the short name in the archive name) are kept in each Package/App
+// directory. If platformString is nil, the archives of all platforms are pruned. Other files are
+// never removed.
+func getPrunePaths(
+	store          fileStore,
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+	keep           int,
+) ([]string, error) {
+	packagePaths, err := getDirectoryPrunePaths(store, constants.PackageDirName, contextManager.GetAllPackageConfigsArray(nil), platformString, keep)
+	if err != nil {
+		return []string{}, err
+	}
+	appPaths, err := getDirectoryPrunePaths(store, constants.AppDirName, contextManager.GetAllAppConfigsArray(nil), platformString, keep)
+	if err != nil {
+		return []string{}, err
+	}
+	paths := append(packagePaths, appPaths...)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// getDirectoryPrunePaths
+// Returns paths of files in packageOrApp directory of the store which are removed by prune, more
+// in getPrunePaths.
+func getDirectoryPrunePaths(
+	store          fileStore,
+	packageOrApp   string,
+	configs        []*config.Config,
+	platformString *bacpack_package.PlatformString,
+	keep           int,
+) ([]string, error) {
+	configsMap := getConfigsMap(configs)
+
+	lookupPath := packageOrApp
+	if platformString != nil {
+		lookupPath = path.Join(
+			packageOrApp,
+			platformString.String.DistroName,
+			platformString.String.DistroRelease,
+			platformString.String.Machine,
+		)
+	}
+	files, err := store.listFiles(lookupPath)
+	if err != nil {
+		return []string{}, err
+	}
+
+	filesSet := getFilesSet(files)
+	var paths []string
+	removableArchives := make(map[string][]archiveInfo)
+	for _, repoPath := range files {
+		pathParts := strings.Split(strings.TrimPrefix(repoPath, packageOrApp + "/"), "/")
+		if len(pathParts) != archivePathDepth {
+			continue
+		}
+		if strings.HasSuffix(repoPath, ManifestExt) {
+			if _, found := filesSet[getArchivePath(repoPath)]; !found {
+				paths = append(paths, repoPath)
+			}
+			continue
+		}
+		if path.Ext(repoPath) != bacpack_package.ZipExt {
+			continue
+		}
+		nameParts, err := bacpack_package.ParseFullPackageName(path.Base(repoPath))
+		if err != nil {
+			// The archive cannot be matched to the Context, it is reported by verification
+			continue
+		}
+		dirPlatformString := pathParts[2] + "-" + pathParts[0] + "-" + pathParts[1]
+		if nameParts.PlatformString == dirPlatformString && checkArchiveConfig(pathParts[3], nameParts, configsMap, false) == nil {
+			continue
+		}
+		// Versions of release, debug and dev archives are counted separately
+		key := path.Join(path.Dir(repoPath), nameParts.ShortName)
+		removableArchives[key] = append(removableArchives[key], archiveInfo{path: repoPath, nameParts: nameParts})
+	}
+
+	for _, archives := range removableArchives {
+		sort.SliceStable(archives, func(i, j int) bool {
+			return compareVersionTags(archives[i].nameParts.VersionTag, archives[j].nameParts.VersionTag) > 0
+		})
+		for i, archive := range archives {
+			if i < keep {
+				continue
+			}
+			paths = append(paths, archive.path)
+			manifestPath := GetManifestPath(archive.path)
+			if _, found := filesSet[manifestPath]; found {
+				paths = append(paths, manifestPath)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// pruneStore
+// Removes files returned by getPrunePaths from the store and returns their paths. If dryRun is
+// true, nothing is removed.
+func pruneStore(
+	store          fileStore,
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+	keep           int,
+	dryRun         bool,
+) ([]string, error) {
+	paths, err := getPrunePaths(store, contextManager, platformString, keep)
+	if err != nil || dryRun {
+		return paths, err
+	}
+	for _, filePath := range paths {
+		err = store.removeFile(filePath)
+		if err != nil {
+			return paths, fmt.Errorf("cannot remove %s - %w", filePath, err)
+		}
+	}
+	return paths, nil
+}
+
+// compareVersionTags
+// Compares two VersionTags, returns negative number if a is older than b, positive number if a is
+// newer than b and 0 if they are equal. The numeric parts are compared as numbers, other parts as
+// strings, so v1.10.0 is newer than v1.9.0.
+func compareVersionTags(a string, b string) int {
+	aParts := splitVersionTag(a)
+	bParts := splitVersionTag(b)
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNumber, aErr := strconv.ParseUint(aParts[i], 10, 64)
+		bNumber, bErr := strconv.ParseUint(bParts[i], 10, 64)
+		if aErr == nil && bErr == nil {
+			if aNumber != bNumber {
+				if aNumber < bNumber {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if result := strings.Compare(aParts[i], bParts[i]); result != 0 {
+			return result
+		}
+	}
+	return len(aParts) - len(bParts)
+}
+
+// splitVersionTag
+// Splits versionTag to numeric and non-numeric parts.
+func splitVersionTag(versionTag string) []string {
+	var parts []string
+	for i := 0; i < len(versionTag); {
+		j := i + 1
+		isDigit := versionTag[i] >= '0' && versionTag[i] <= '9'
+		for j < len(versionTag) && (versionTag[j] >= '0' && versionTag[j] <= '9') == isDigit {
+			j++
+		}
+		parts = append(parts, versionTag[i:j])
+		i = j
+	}
+	return parts
+}
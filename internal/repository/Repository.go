@@ -1,13 +1,209 @@
 package repository
 
-import "github.com/bacpack-system/packager/internal/bacpack_package"
-
-// RepositoryInterface is an interface for every package repository.
-// If you want to implement
-type RepositoryInterface interface {
-	// CopyToRepository copy package files represented by sourceDir to the repository.
-	// Each repository has a different semantics for managing structure of th repository.
-	//
-	// Repository must not change the package name represented by pack.GetFullPackageName()
-	CopyToRepository(pack bacpack_package.Package, sourceDir string) error
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	// TypeGitLFS stores the Packages/Apps in Git repository with Git LFS, each Package/App is
+	// committed
+	TypeGitLFS = "git-lfs"
+	// TypeDir stores the Packages/Apps in a plain directory
+	TypeDir = "dir"
+	// TypeS3 stores the Packages/Apps in a bucket of S3 compatible object store
+	TypeS3 = "s3"
+
+	// Pattern of the temporary directories for archives
+	tmpDirPattern = "bap-repository-*"
+)
+
+// Repository
+// Storage of built Packages/Apps. The archives are stored in
+// packageOrApp / PlatformString.DistroName / PlatformString.DistroRelease / PlatformString.Machine / <package>
+// structure, the manifest of the archive is stored next to it. The packageOrApp is a string
+// representing type of Package, it should be either "package" or "app".
+type Repository interface {
+	// CopyToRepository creates archive of the pack from sourceDir and stores it with its manifest
+	// to the repository. It must be safe to call it from multiple goroutines. Repository must not
+	// change the package name represented by pack.GetFullPackageName().
+	CopyToRepository(pack bacpack_package.Package, sourceDir string, packageOrApp string, provenance Provenance) error
+	// CheckConsistency checks the repository content against Context. Returns error if any
+	// Package/App in the repository is not in Context, prints warnings for missing ones. If
+	// keepSuperseded is true, older versions of Packages/Apps in Context are not errors.
+	CheckConsistency(contextManager *context.ContextManager, platformString *bacpack_package.PlatformString, imageName string, keepSuperseded bool) error
+	// FetchArchive returns local path of the archive of pack. If the repository is not local, the
+	// archive is downloaded to tmpDir. Returns error wrapping fs.ErrNotExist if the archive is not
+	// in the repository.
+	FetchArchive(pack bacpack_package.Package, packageOrApp string, tmpDir string) (string, error)
+	// GetArchiveSha256 returns SHA-256 of the archive of pack. Returns error wrapping
+	// fs.ErrNotExist if the archive is not in the repository.
+	GetArchiveSha256(pack bacpack_package.Package, packageOrApp string) (string, error)
+	// Verify verifies all archives in the repository, more in Verification.go. If keepSuperseded is
+	// true, older versions of Packages/Apps in Context are reported as superseded.
+	Verify(contextManager *context.ContextManager, keepSuperseded bool) ([]VerificationIssue, error)
+	// Prune removes archives of Packages/Apps and VersionTags which are not in Context (for
+	// platformString or for all platforms if nil) and keeps keep newest of them for each
+	// Package/App, more in Prune.go. Returns paths of removed files. If dryRun is true, nothing is
+	// removed.
+	Prune(contextManager *context.ContextManager, platformString *bacpack_package.PlatformString, keep int, dryRun bool) ([]string, error)
+	// RestoreAllChanges reverts changes of the interrupted CopyToRepository or Prune.
+	RestoreAllChanges() error
+	// GetConfig returns settings of the repository stored in bap_repository.json in the
+	// repository root, more in RepositoryConfig.go.
+	GetConfig() RepositoryConfig
+}
+
+// TransactionalRepository
+// Repository which can store all Packages/Apps of a build session at once. Between
+// BeginTransaction and CommitTransaction the Packages/Apps copied by CopyToRepository are not
+// stored permanently, RollbackTransaction reverts the Repository to the state before
+// BeginTransaction.
+type TransactionalRepository interface {
+	Repository
+	// BeginTransaction starts the transaction, returns error if a transaction is in progress.
+	BeginTransaction() error
+	// CommitTransaction stores all Packages/Apps copied in the transaction and ends it.
+	CommitTransaction(info TransactionInfo) error
+	// RollbackTransaction reverts all changes made in the transaction and ends it. Does nothing if
+	// no transaction is in progress.
+	RollbackTransaction() error
+}
+
+// TransactionInfo
+// Describes the build session of the transaction, it is stored with the committed
+// Packages/Apps.
+type TransactionInfo struct {
+	// ImageName name of the docker image the Packages/Apps are built for
+	ImageName       string
+	// PlatformString serialized platform string of the image
+	PlatformString  string
+	// PackagerVersion version of the packager which built the Packages/Apps
+	PackagerVersion string
+}
+
+// Types
+// Returns all supported Repository types.
+func Types() []string {
+	return []string{TypeGitLFS, TypeDir, TypeS3}
+}
+
+// CreateRepository
+// Creates and initializes Repository of repoType. The location is path to the directory for
+// git-lfs and dir Repository and <bucket>[/<prefix>] for s3 Repository. Empty repoType means
+// git-lfs. RepositoryConfig is read from the repository root during the initialization.
+func CreateRepository(repoType string, location string) (Repository, error) {
+	var repo Repository
+	var err error
+	switch repoType {
+	case TypeGitLFS, "":
+		repo, err = prerequisites.CreateAndInitialize[GitLFSRepository](location)
+	case TypeDir:
+		repo, err = prerequisites.CreateAndInitialize[DirRepository](location)
+	case TypeS3:
+		repo, err = prerequisites.CreateAndInitialize[S3Repository](location)
+	default:
+		return nil, fmt.Errorf("unsupported repository type '%s'", repoType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// getArchiveDir
+// Returns path of the directory with archive of pack relative to the repository root.
+func getArchiveDir(pack bacpack_package.Package, packageOrApp string) string {
+	return path.Join(
+		packageOrApp,
+		pack.PlatformString.String.DistroName,
+		pack.PlatformString.String.DistroRelease,
+		pack.PlatformString.String.Machine,
+		pack.Name,
+	)
+}
+
+// getArchiveFilePath
+// Returns path of the archive of pack relative to the repository root.
+func getArchiveFilePath(pack bacpack_package.Package, packageOrApp string) string {
+	return path.Join(getArchiveDir(pack, packageOrApp), pack.GetFullPackageName() + bacpack_package.ZipExt)
+}
+
+// copyToStore
+// Creates archive of the pack from sourceDir with its manifest in a temporary directory and
+// stores both to the store. The archive is stored first, so the manifest never refers to missing
+// archive.
+func copyToStore(
+	store        fileStore,
+	pack         bacpack_package.Package,
+	sourceDir    string,
+	packageOrApp string,
+	provenance   Provenance,
+) error {
+	tmpDir, err := os.MkdirTemp("", tmpDirPattern)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = pack.CreatePackage(sourceDir, tmpDir)
+	if err != nil {
+		return err
+	}
+	archivePath := filepath.Join(tmpDir, pack.GetFullPackageName() + bacpack_package.ZipExt)
+	err = createManifest(pack, archivePath, provenance)
+	if err != nil {
+		return fmt.Errorf("cannot create manifest - %w", err)
+	}
+
+	archiveFilePath := getArchiveFilePath(pack, packageOrApp)
+	err = store.storeFile(archivePath, archiveFilePath)
+	if err != nil {
+		return fmt.Errorf("cannot store archive - %w", err)
+	}
+	err = store.storeFile(GetManifestPath(archivePath), GetManifestPath(archiveFilePath))
+	if err != nil {
+		return fmt.Errorf("cannot store manifest - %w", err)
+	}
+	return nil
+}
+
+// fetchArchive
+// Returns local path of the archive of pack from the store, more in Repository.FetchArchive.
+func fetchArchive(store fileStore, pack bacpack_package.Package, packageOrApp string, tmpDir string) (string, error) {
+	return store.fetchFile(getArchiveFilePath(pack, packageOrApp), tmpDir)
+}
+
+// getStoreArchiveSha256
+// Returns SHA-256 of the archive of pack in the store. The checksum is read from the manifest, if
+// the manifest cannot be read, the checksum is computed from the archive.
+func getStoreArchiveSha256(store fileStore, pack bacpack_package.Package, packageOrApp string) (string, error) {
+	tmpDir, err := os.MkdirTemp("", tmpDirPattern)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archiveFilePath := getArchiveFilePath(pack, packageOrApp)
+	manifestPath, err := store.fetchFile(GetManifestPath(archiveFilePath), tmpDir)
+	if err == nil {
+		manifest, err := ReadManifest(manifestPath)
+		if err == nil && manifest.ArchiveSha256 != "" {
+			return manifest.ArchiveSha256, nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	archivePath, err := store.fetchFile(archiveFilePath, tmpDir)
+	if err != nil {
+		return "", err
+	}
+	return getFileSha256(archivePath)
 }
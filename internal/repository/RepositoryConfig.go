@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+const (
+	// RepositoryConfigFileName name of the file with RepositoryConfig in the repository root
+	RepositoryConfigFileName = "bap_repository.json"
+)
+
+// RepositoryConfig
+// Settings of the Package Repository stored in bap_repository.json in the repository root, so
+// every command working with the repository uses the same settings. The settings can be
+// overridden by command line options.
+type RepositoryConfig struct {
+	// KeepSuperseded if true, older versions of Packages/Apps in Context are kept in the
+	// repository, they are not consistency errors and they are reported as superseded
+	KeepSuperseded bool
+}
+
+// readRepositoryConfig
+// Reads RepositoryConfig from bap_repository.json in the root of the store. If the file does not
+// exist, the default RepositoryConfig is returned.
+func readRepositoryConfig(store fileStore) (RepositoryConfig, error) {
+	repoConfig := RepositoryConfig{}
+	tmpDir, err := os.MkdirTemp("", tmpDirPattern)
+	if err != nil {
+		return repoConfig, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath, err := store.fetchFile(RepositoryConfigFileName, tmpDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return repoConfig, nil
+	} else if err != nil {
+		return repoConfig, fmt.Errorf("cannot read %s of package repository - %w", RepositoryConfigFileName, err)
+	}
+	bytes, err := os.ReadFile(configPath)
+	if err != nil {
+		return repoConfig, fmt.Errorf("cannot read %s of package repository - %w", RepositoryConfigFileName, err)
+	}
+	err = json.Unmarshal(bytes, &repoConfig)
+	if err != nil {
+		return repoConfig, fmt.Errorf("invalid %s of package repository - %w", RepositoryConfigFileName, err)
+	}
+	return repoConfig, nil
+}
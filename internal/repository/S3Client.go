@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Signing algorithm of AWS Signature Version 4
+	s3SignAlgorithm = "AWS4-HMAC-SHA256"
+	s3Service       = "s3"
+	s3DateFormat    = "20060102"
+	s3TimeFormat    = "20060102T150405Z"
+	// SHA-256 of empty payload
+	emptyPayloadSha256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// Maximum length of error response body included in errors
+	maxErrorBodyLength = 1024
+)
+
+// s3Client
+// Minimal client of S3 compatible object store (AWS S3, MinIO, ...). The objects are addressed
+// in path style (<endpoint>/<bucket>/<key>), requests are signed by AWS Signature Version 4.
+type s3Client struct {
+	endpoint   *url.URL
+	region     string
+	accessKey  string
+	secretKey  string
+	bucket     string
+	httpClient *http.Client
+}
+
+// listBucketResult
+// Response of ListObjectsV2 request.
+type listBucketResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key string
+	}
+}
+
+// headBucket
+// Returns error if the bucket does not exist or cannot be accessed.
+func (client *s3Client) headBucket() error {
+	response, err := client.do(http.MethodHead, "", nil, nil, emptyPayloadSha256)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// listObjects
+// Returns keys of all objects with the prefix.
+func (client *s3Client) listObjects(prefix string) ([]string, error) {
+	keys := []string{}
+	continuationToken := ""
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {prefix},
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		response, err := client.do(http.MethodGet, "", query, nil, emptyPayloadSha256)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse list of objects - %w", err)
+		}
+		for _, content := range result.Contents {
+			keys = append(keys, content.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// getObject
+// Downloads the object to the local file on localPath. Returns error wrapping fs.ErrNotExist if
+// the object does not exist.
+func (client *s3Client) getObject(key string, localPath string) error {
+	response, err := client.do(http.MethodGet, key, nil, nil, emptyPayloadSha256)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	file, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, response.Body)
+	closeErr := file.Close()
+	if err != nil {
+		return fmt.Errorf("cannot download %s - %w", key, err)
+	}
+	return closeErr
+}
+
+// putObject
+// Uploads the local file on localPath as the object.
+func (client *s3Client) putObject(key string, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	response, err := client.do(http.MethodPut, key, nil, file, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// deleteObject
+// Deletes the object. It is not an error if the object does not exist.
+func (client *s3Client) deleteObject(key string) error {
+	response, err := client.do(http.MethodDelete, key, nil, nil, emptyPayloadSha256)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// do
+// Sends signed request for the object with key (the bucket if key is empty) and returns the
+// response. Returns error if the response status is not 2xx, the error wraps fs.ErrNotExist for
+// 404 status.
+func (client *s3Client) do(method string, key string, query url.Values, body *os.File, payloadSha256 string) (*http.Response, error) {
+	requestUrl := *client.endpoint
+	requestUrl.Path = strings.TrimSuffix(requestUrl.Path, "/") + "/" + client.bucket
+	if key != "" {
+		requestUrl.Path += "/" + key
+	}
+	requestUrl.RawPath = uriEncode(requestUrl.Path, false)
+	requestUrl.RawQuery = getCanonicalQuery(query)
+
+	request, err := http.NewRequest(method, requestUrl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		info, err := body.Stat()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = body
+		request.ContentLength = info.Size()
+	}
+	signRequest(request, payloadSha256, client.accessKey, client.secretKey, client.region, time.Now())
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		defer response.Body.Close()
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodyLength))
+		err = fmt.Errorf("S3 %s %s failed - %s: %s", method, requestUrl.Path, response.Status, strings.TrimSpace(string(responseBody)))
+		if response.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w - %s", fs.ErrNotExist, err)
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// signRequest
+// Signs the request by AWS Signature Version 4. All headers of the request and the host are
+// signed. The request URL must be encoded the same way as the canonical request (uriEncode).
+func signRequest(request *http.Request, payloadSha256 string, accessKey string, secretKey string, region string, now time.Time) {
+	now = now.UTC()
+	request.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	request.Header.Set("X-Amz-Content-Sha256", payloadSha256)
+
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+	headers := map[string]string{
+		"host": host,
+	}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	headerNames := make([]string, 0, len(headers))
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadSha256,
+	}, "\n")
+	scope := strings.Join([]string{now.Format(s3DateFormat), region, s3Service, "aws4_request"}, "/")
+	canonicalRequestSha256 := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3SignAlgorithm,
+		now.Format(s3TimeFormat),
+		scope,
+		hex.EncodeToString(canonicalRequestSha256[:]),
+	}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4" + secretKey), now.Format(s3DateFormat))
+	signingKey = hmacSha256(signingKey, region)
+	signingKey = hmacSha256(signingKey, s3Service)
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SignAlgorithm, accessKey, scope, signedHeaders, signature,
+	))
+}
+
+// getCanonicalQuery
+// Returns query encoded as canonical query string of AWS Signature Version 4 (sorted by keys).
+func getCanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, uriEncode(key, true) + "=" + uriEncode(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// uriEncode
+// Encodes value the way AWS Signature Version 4 requires - all characters except unreserved ones
+// (A-Z, a-z, 0-9, '-', '.', '_', '~') are percent encoded. The '/' is encoded only if
+// encodeSlash is true.
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '.' || b == '_' || b == '~' || (b == '/' && !encodeSlash) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+// hmacSha256
+// Returns HMAC-SHA256 of data with key.
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/context"
+	"github.com/bacpack-system/packager/internal/prerequisites"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Environment variables with configuration of the S3 client, the same as used by AWS CLI
+	s3EndpointEnv  = "AWS_ENDPOINT_URL"
+	s3RegionEnv    = "AWS_REGION"
+	s3AccessKeyEnv = "AWS_ACCESS_KEY_ID"
+	s3SecretKeyEnv = "AWS_SECRET_ACCESS_KEY"
+
+	defaultS3Endpoint = "https://s3.amazonaws.com"
+	defaultS3Region   = "us-east-1"
+)
+
+// S3Repository
+// Package/App repository in a bucket of S3 compatible object store. The objects are stored in the
+// same structure as files in GitLFSRepository under Prefix. The endpoint, region and credentials
+// are read from AWS_ENDPOINT_URL, AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+// environment variables.
+type S3Repository struct {
+	// Bucket name of the bucket
+	Bucket    string
+	// Prefix of all objects of the repository in the bucket, can be empty
+	Prefix    string
+	// Endpoint URL of the object store, e.g. http://localhost:9000 for local MinIO
+	Endpoint  string
+	// Region of the bucket
+	Region    string
+	// AccessKey access key ID of the credentials
+	AccessKey string
+	// SecretKey secret access key of the credentials
+	SecretKey string
+	// config of the repository read from the repository root (Prefix)
+	config    RepositoryConfig
+}
+
+type s3RepositoryInitArgs struct {
+	// Location <bucket>[/<prefix>]
+	Location string
+}
+
+func (s3 *S3Repository) FillDefault(*prerequisites.Args) error {
+	*s3 = S3Repository{
+		Endpoint:  os.Getenv(s3EndpointEnv),
+		Region:    os.Getenv(s3RegionEnv),
+		AccessKey: os.Getenv(s3AccessKeyEnv),
+		SecretKey: os.Getenv(s3SecretKeyEnv),
+	}
+	if s3.Endpoint == "" {
+		s3.Endpoint = defaultS3Endpoint
+	}
+	if s3.Region == "" {
+		s3.Region = defaultS3Region
+	}
+	return nil
+}
+
+func (s3 *S3Repository) FillDynamic(args *prerequisites.Args) error {
+	if !prerequisites.IsEmpty(args) {
+		var argsStruct s3RepositoryInitArgs
+		prerequisites.GetArgs(args, &argsStruct)
+		location := strings.Trim(argsStruct.Location, "/")
+		s3.Bucket, s3.Prefix, _ = strings.Cut(location, "/")
+	}
+	return nil
+}
+
+func (s3 *S3Repository) CheckPrerequisites(*prerequisites.Args) error {
+	if s3.Bucket == "" {
+		return fmt.Errorf("bucket of the S3 package repository must be set")
+	}
+	if s3.AccessKey == "" || s3.SecretKey == "" {
+		return fmt.Errorf("credentials of the S3 package repository must be set by %s and %s environment variables", s3AccessKeyEnv, s3SecretKeyEnv)
+	}
+	client, err := s3.getClient()
+	if err != nil {
+		return err
+	}
+	err = client.headBucket()
+	if err != nil {
+		return fmt.Errorf("cannot access bucket '%s' of the S3 package repository - %w", s3.Bucket, err)
+	}
+	store, err := s3.getStore()
+	if err != nil {
+		return err
+	}
+	s3.config, err = readRepositoryConfig(store)
+	return err
+}
+
+// GetConfig
+// Returns RepositoryConfig read from the repository by CheckPrerequisites.
+func (s3 *S3Repository) GetConfig() RepositoryConfig {
+	return s3.config
+}
+
+// CopyToRepository
+// Uploads the pack with its manifest to the bucket, more in Repository.CopyToRepository.
+func (s3 *S3Repository) CopyToRepository(
+	pack         bacpack_package.Package,
+	sourceDir    string,
+	packageOrApp string,
+	provenance   Provenance,
+) error {
+	store, err := s3.getStore()
+	if err != nil {
+		return err
+	}
+	return copyToStore(store, pack, sourceDir, packageOrApp, provenance)
+}
+
+// CheckConsistency
+// Checks the bucket content based on Context, more in Repository.CheckConsistency.
+func (s3 *S3Repository) CheckConsistency(
+	contextManager *context.ContextManager,
+	platformString *bacpack_package.PlatformString,
+	imageName      string,
+	keepSuperseded bool,
+) error {
+	store, err := s3.getStore()
+	if err != nil {
+		return err
+	}
+	return checkStoreConsistency(store, contextManager, platformString, imageName, keepSuperseded)
+}
+
+// FetchArchive
+// Downloads the archive of pack to tmpDir, more in Repository.FetchArchive.
+func (s3 *S3Repository) FetchArchive(pack bacpack_package.Package, packageOrApp string, tmpDir string) (string, error) {
+	store, err := s3.getStore()
+	if err != nil {
+		return "", err
+	}
+	return fetchArchive(store, pack, packageOrApp, tmpDir)
+}
+
+// GetArchiveSha256
+// Returns SHA-256 of the archive of pack, more in Repository.GetArchiveSha256.
+func (s3 *S3Repository) GetArchiveSha256(pack bacpack_package.Package, packageOrApp string) (string, error) {
+	store, err := s3.getStore()
+	if err != nil {
+		return "", err
+	}
+	return getStoreArchiveSha256(store, pack, packageOrApp)
+}
+
+// Verify
+// Verifies all archives in the bucket, more in Verification.go. Each archive is downloaded.
+func (s3 *S3Repository) Verify(contextManager *context.ContextManager, keepSuperseded bool) ([]VerificationIssue, error) {
+	store, err := s3.getStore()
+	if err != nil {
+		return nil, err
+	}
+	return verifyStore(store, contextManager, keepSuperseded)
+}
+
+// Prune
+// Removes objects of archives which are not in Context, more in Repository.Prune.
+func (s3 *S3Repository) Prune(contextManager *context.ContextManager, platformString *bacpack_package.PlatformString, keep int, dryRun bool) ([]string, error) {
+	store, err := s3.getStore()
+	if err != nil {
+		return nil, err
+	}
+	return pruneStore(store, contextManager, platformString, keep, dryRun)
+}
+
+// RestoreAllChanges
+// Nothing to restore, the objects are uploaded atomically.
+func (s3 *S3Repository) RestoreAllChanges() error {
+	return nil
+}
+
+// getClient
+// Returns client of the bucket.
+func (s3 *S3Repository) getClient() (*s3Client, error) {
+	endpoint, err := url.Parse(s3.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint '%s' - %w", s3.Endpoint, err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint '%s' - scheme and host must be set", s3.Endpoint)
+	}
+	return &s3Client{
+		endpoint:   endpoint,
+		region:     s3.Region,
+		accessKey:  s3.AccessKey,
+		secretKey:  s3.SecretKey,
+		bucket:     s3.Bucket,
+		httpClient: http.DefaultClient,
+	}, nil
+}
+
+// getStore
+// Returns store of the objects under Prefix in the bucket.
+func (s3 *S3Repository) getStore() (fileStore, error) {
+	client, err := s3.getClient()
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{client: client, prefix: s3.Prefix}, nil
+}
+
+// s3Store
+// Stores the files as objects in S3 bucket. The object key is the file path with prefix.
+type s3Store struct {
+	client *s3Client
+	prefix string
+}
+
+func (store *s3Store) listFiles(dir string) ([]string, error) {
+	listPrefix := store.getKey(dir)
+	if listPrefix != "" {
+		listPrefix += "/"
+	}
+	keys, err := store.client.listObjects(listPrefix)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			// Directory placeholder object
+			continue
+		}
+		if store.prefix != "" {
+			key = strings.TrimPrefix(key, store.prefix + "/")
+		}
+		files = append(files, key)
+	}
+	return files, nil
+}
+
+func (store *s3Store) fetchFile(filePath string, tmpDir string) (string, error) {
+	localPath := filepath.Join(tmpDir, path.Base(filePath))
+	err := store.client.getObject(store.getKey(filePath), localPath)
+	if err != nil {
+		return "", err
+	}
+	return localPath, nil
+}
+
+func (store *s3Store) storeFile(localPath string, filePath string) error {
+	return store.client.putObject(store.getKey(filePath), localPath)
+}
+
+func (store *s3Store) removeFile(filePath string) error {
+	return store.client.deleteObject(store.getKey(filePath))
+}
+
+// getKey
+// Returns key of the object for filePath. The key of the root ("." filePath) without prefix is
+// empty.
+func (store *s3Store) getKey(filePath string) string {
+	key := path.Join(store.prefix, filePath)
+	if key == "." {
+		return ""
+	}
+	return key
+}
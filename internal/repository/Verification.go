@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"github.com/bacpack-system/packager/internal/config"
+	"github.com/bacpack-system/packager/internal/constants"
+	"github.com/bacpack-system/packager/internal/context"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// IssueCorrupt the archive cannot be opened or its files cannot be read
+	IssueCorrupt = "corrupt"
+	// IssueEmpty the archive does not contain any file
+	IssueEmpty = "empty"
+	// IssueInvalidName the archive name cannot be parsed into Package name parts
+	IssueInvalidName = "invalid-name"
+	// IssueOrphaned the archive does not belong to any Package/App Config in Context
+	IssueOrphaned = "orphaned"
+	// IssueDuplicate more archives of the same Package/App are in one directory
+	IssueDuplicate = "duplicate"
+	// IssueWrongPlatform platform string in the archive name differs from its directory
+	IssueWrongPlatform = "wrong-platform"
+	// IssueUnexpectedFile the file is not a zip archive in the expected directory structure
+	IssueUnexpectedFile = "unexpected-file"
+	// IssueManifestMismatch the manifest of the archive cannot be read or does not match the archive
+	IssueManifestMismatch = "manifest-mismatch"
+	// IssueSuperseded the archive is older version of Package/App in Context, reported only if the
+	// superseded versions are kept. It is not an error.
+	IssueSuperseded = "superseded"
+	// IssueNotLFSPointer the archive is committed as git blob instead of Git LFS pointer
+	IssueNotLFSPointer = "not-lfs-pointer"
+	// IssueLFSObjectMissing the Git LFS object of the committed archive is not in local storage
+	IssueLFSObjectMissing = "lfs-object-missing"
+
+	// Number of path elements of the archive relative to package/app directory -
+	// DistroName / DistroRelease / Machine / <package> / <archive>
+	archivePathDepth = 5
+)
+
+// VerificationIssue
+// Represents one problem found in repository by Verify.
+type VerificationIssue struct {
+	// Type of the issue (Issue* constants)
+	Type    string
+	// Path of the file relative to the repository root
+	Path    string
+	// Message with details of the issue
+	Message string
+}
+
+// archiveInfo
+// Holds information about archive found in repository, which is needed for duplicate detection.
+type archiveInfo struct {
+	path      string
+	nameParts bacpack_package.PackageNameParts
+}
+
+// verifyStore
+// Verifies all archives in package/ and app/ directories of the store. Each archive must be
+// a readable and non-empty zip file, its name must parse into Package name parts, it must belong
+// to a Package/App Config in Context, its platform string must match its directory and only one
+// archive of each Package/App may be in one directory. The manifest next to the archive must match
+// the archive checksum. If keepSuperseded is true, older versions of Packages/Apps in Context are
+// reported as superseded instead of orphaned and duplicate. Returns found issues sorted by path.
+func verifyStore(store fileStore, contextManager *context.ContextManager, keepSuperseded bool) ([]VerificationIssue, error) {
+	var issues []VerificationIssue
+	packageIssues, err := verifyDirectory(store, constants.PackageDirName, contextManager.GetAllPackageConfigsArray(nil), keepSuperseded)
+	if err != nil {
+		return []VerificationIssue{}, err
+	}
+	issues = append(issues, packageIssues...)
+	appIssues, err := verifyDirectory(store, constants.AppDirName, contextManager.GetAllAppConfigsArray(nil), keepSuperseded)
+	if err != nil {
+		return []VerificationIssue{}, err
+	}
+	issues = append(issues, appIssues...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Path < issues[j].Path
+	})
+	return issues, nil
+}
+
+// verifyDirectory
+// Verifies all files in packageOrApp directory of the store against configs. The files of not
+// local store are downloaded one by one to a temporary directory. Each archive is downloaded once,
+// its checksum is computed during its verification and the manifests are verified against it.
+func verifyDirectory(store fileStore, packageOrApp string, configs []*config.Config, keepSuperseded bool) ([]VerificationIssue, error) {
+	configsMap := getConfigsMap(configs)
+
+	var issues []VerificationIssue
+	archivesInDirs := make(map[string][]archiveInfo)
+	files, err := store.listFiles(packageOrApp)
+	if err != nil {
+		return []VerificationIssue{}, err
+	}
+	filesSet := getFilesSet(files)
+	archiveSha256s := make(map[string]string)
+	var manifestPaths []string
+	for _, repoPath := range files {
+		pathParts := strings.Split(strings.TrimPrefix(repoPath, packageOrApp + "/"), "/")
+		if len(pathParts) == archivePathDepth && strings.HasSuffix(repoPath, ManifestExt) {
+			// The manifests are verified when checksums of all archives are known
+			manifestPaths = append(manifestPaths, repoPath)
+			continue
+		}
+		if len(pathParts) != archivePathDepth || path.Ext(repoPath) != bacpack_package.ZipExt {
+			issues = append(issues, VerificationIssue{
+				Type:    IssueUnexpectedFile,
+				Path:    repoPath,
+				Message: fmt.Sprintf("file is not a zip archive in %s/<distro>/<release>/<machine>/<name>/ directory", packageOrApp),
+			})
+			continue
+		}
+
+		archiveIssue, err := verifyStoreFile(store, repoPath, func(archivePath string) *VerificationIssue {
+			archiveSha256, err := getFileSha256(archivePath)
+			if err == nil {
+				archiveSha256s[repoPath] = archiveSha256
+			}
+			return verifyArchive(archivePath)
+		})
+		if err != nil {
+			return []VerificationIssue{}, err
+		}
+		if archiveIssue != nil {
+			archiveIssue.Path = repoPath
+			issues = append(issues, *archiveIssue)
+		}
+
+		nameParts, err := bacpack_package.ParseFullPackageName(path.Base(repoPath))
+		if err != nil {
+			issues = append(issues, VerificationIssue{
+				Type:    IssueInvalidName,
+				Path:    repoPath,
+				Message: err.Error(),
+			})
+			continue
+		}
+		dirPlatformString := pathParts[2] + "-" + pathParts[0] + "-" + pathParts[1]
+		if nameParts.PlatformString != dirPlatformString {
+			issues = append(issues, VerificationIssue{
+				Type:    IssueWrongPlatform,
+				Path:    repoPath,
+				Message: fmt.Sprintf("platform string %s differs from directory platform string %s", nameParts.PlatformString, dirPlatformString),
+			})
+		}
+		orphanedIssue := checkArchiveConfig(pathParts[3], nameParts, configsMap, keepSuperseded)
+		if orphanedIssue != nil {
+			orphanedIssue.Path = repoPath
+			issues = append(issues, *orphanedIssue)
+		}
+		dir := path.Dir(repoPath)
+		archivesInDirs[dir] = append(archivesInDirs[dir], archiveInfo{path: repoPath, nameParts: nameParts})
+	}
+
+	for _, manifestPath := range manifestPaths {
+		var manifestIssue *VerificationIssue
+		archivePath := getArchivePath(manifestPath)
+		if _, found := filesSet[archivePath]; !found {
+			manifestIssue = &VerificationIssue{Type: IssueUnexpectedFile, Message: "manifest does not belong to any archive"}
+		} else if archiveSha256, found := archiveSha256s[archivePath]; !found {
+			manifestIssue = &VerificationIssue{Type: IssueManifestMismatch, Message: "cannot compute archive checksum"}
+		} else {
+			manifestIssue, err = verifyStoreFile(store, manifestPath, func(localPath string) *VerificationIssue {
+				return verifyManifest(localPath, archiveSha256)
+			})
+			if err != nil {
+				return []VerificationIssue{}, err
+			}
+		}
+		if manifestIssue != nil {
+			manifestIssue.Path = manifestPath
+			issues = append(issues, *manifestIssue)
+		}
+	}
+	issues = append(issues, findDuplicates(archivesInDirs, keepSuperseded)...)
+	return issues, nil
+}
+
+// verifyStoreFile
+// Fetches the file from the store to a temporary directory and verifies it by verifyFunc. The
+// temporary directory is removed after the verification. Returns error if the temporary
+// directory cannot be created.
+func verifyStoreFile(store fileStore, filePath string, verifyFunc func(localPath string) *VerificationIssue) (*VerificationIssue, error) {
+	tmpDir, err := os.MkdirTemp("", tmpDirPattern)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	localPath, err := store.fetchFile(filePath, tmpDir)
+	if err != nil {
+		return &VerificationIssue{Type: IssueCorrupt, Message: fmt.Sprintf("cannot read file - %s", err)}, nil
+	}
+	return verifyFunc(localPath), nil
+}
+
+// verifyArchive
+// Checks that the archive is a readable zip file with at least one file. All files are read, so
+// the checksums are verified. Returns nil if the archive is valid.
+func verifyArchive(archivePath string) *VerificationIssue {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return &VerificationIssue{Type: IssueCorrupt, Message: fmt.Sprintf("cannot open archive - %s", err)}
+	}
+	defer reader.Close()
+
+	filesCount := 0
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		filesCount++
+		err = readZipFile(file)
+		if err != nil {
+			return &VerificationIssue{Type: IssueCorrupt, Message: fmt.Sprintf("cannot read %s - %s", file.Name, err)}
+		}
+	}
+	if filesCount == 0 {
+		return &VerificationIssue{Type: IssueEmpty, Message: "archive does not contain any file"}
+	}
+	return nil
+}
+
+// verifyManifest
+// Checks that the manifest can be read and that the archive checksum in it is archiveSha256.
+// Returns nil if the manifest is valid.
+func verifyManifest(manifestPath string, archiveSha256 string) *VerificationIssue {
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		return &VerificationIssue{Type: IssueManifestMismatch, Message: err.Error()}
+	}
+	if manifest.ArchiveSha256 != archiveSha256 {
+		return &VerificationIssue{Type: IssueManifestMismatch, Message: "archive checksum differs from the manifest"}
+	}
+	return nil
+}
+
+// readZipFile
+// Reads whole file from zip archive. Returns error if the file cannot be read or its checksum
+// does not match.
+func readZipFile(file *zip.File) error {
+	fileReader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
+	_, err = io.Copy(io.Discard, fileReader)
+	return err
+}
+
+// checkArchiveConfig
+// Checks that the archive in packageName directory belongs to a Config from configsMap. Returns
+// nil if a Config with the same short name and VersionTag exists. If keepSuperseded is true and
+// a Config with the same short name but newer VersionTag exists, returns superseded issue. The
+// archive newer than the Config is always orphaned.
+func checkArchiveConfig(
+	packageName    string,
+	nameParts      bacpack_package.PackageNameParts,
+	configsMap     map[string][]*config.Config,
+	keepSuperseded bool,
+) *VerificationIssue {
+	configs, found := configsMap[packageName]
+	if !found {
+		return &VerificationIssue{
+			Type:    IssueOrphaned,
+			Path:    "",
+			Message: fmt.Sprintf("%s is not in Context", packageName),
+		}
+	}
+	for _, cfg := range configs {
+		if cfg.Package.GetShortPackageName() == nameParts.ShortName && cfg.Package.VersionTag == nameParts.VersionTag {
+			return nil
+		}
+	}
+	if keepSuperseded {
+		for _, cfg := range configs {
+			if cfg.Package.GetShortPackageName() == nameParts.ShortName && compareVersionTags(nameParts.VersionTag, cfg.Package.VersionTag) < 0 {
+				return &VerificationIssue{
+					Type:    IssueSuperseded,
+					Path:    "",
+					Message: fmt.Sprintf("%s %s is superseded by %s in Context", nameParts.ShortName, nameParts.VersionTag, cfg.Package.VersionTag),
+				}
+			}
+		}
+	}
+	return &VerificationIssue{
+		Type:    IssueOrphaned,
+		Path:    "",
+		Message: fmt.Sprintf("%s %s does not match any Config of %s in Context", nameParts.ShortName, nameParts.VersionTag, packageName),
+	}
+}
+
+// getFilesSet
+// Returns set of files, so the presence of a file is checked in constant time.
+func getFilesSet(files []string) map[string]struct{} {
+	filesSet := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		filesSet[file] = struct{}{}
+	}
+	return filesSet
+}
+
+// getConfigsMap
+// Returns configs grouped by Package/App name.
+func getConfigsMap(configs []*config.Config) map[string][]*config.Config {
+	configsMap := make(map[string][]*config.Config)
+	for _, cfg := range configs {
+		configsMap[cfg.Package.Name] = append(configsMap[cfg.Package.Name], cfg)
+	}
+	return configsMap
+}
+
+// findDuplicates
+// Returns issues for archives of the same Package (same short name) in one directory. If
+// keepSuperseded is true, only the archives with the same short name and VersionTag are
+// duplicates.
+func findDuplicates(archivesInDirs map[string][]archiveInfo, keepSuperseded bool) []VerificationIssue {
+	var issues []VerificationIssue
+	for _, archives := range archivesInDirs {
+		archivesByName := make(map[string][]string)
+		for _, archive := range archives {
+			key := archive.nameParts.ShortName
+			if keepSuperseded {
+				key += " " + archive.nameParts.VersionTag
+			}
+			archivesByName[key] = append(archivesByName[key], archive.path)
+		}
+		for name, paths := range archivesByName {
+			if len(paths) < 2 {
+				continue
+			}
+			for _, path := range paths {
+				issues = append(issues, VerificationIssue{
+					Type:    IssueDuplicate,
+					Path:    path,
+					Message: fmt.Sprintf("%d archives of %s in one directory", len(paths), name),
+				})
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,85 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"sync"
+)
+
+// KeyPair
+// ed25519 key pair used for authentication to the docker containers. The private key is kept only
+// in memory.
+type KeyPair struct {
+	// Signer signs the authentication requests by the private key
+	Signer        ssh.Signer
+	// AuthorizedKey public key in authorized_keys format
+	AuthorizedKey []byte
+}
+
+var (
+	runKeyPair      *KeyPair
+	runKeyPairErr   error
+	runKeyPairOnce  sync.Once
+	passwordAuthentication bool
+)
+
+// GenerateKeyPair
+// Generates new ed25519 KeyPair.
+func GenerateKeyPair() (*KeyPair, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate SSH key - %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create SSH signer - %w", err)
+	}
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create SSH public key - %w", err)
+	}
+	return &KeyPair{
+		Signer:        signer,
+		AuthorizedKey: ssh.MarshalAuthorizedKey(sshPublicKey),
+	}, nil
+}
+
+// GetRunKeyPair
+// Returns KeyPair generated for the current run of the program. The KeyPair is generated on the
+// first call.
+func GetRunKeyPair() (*KeyPair, error) {
+	runKeyPairOnce.Do(func() {
+		runKeyPair, runKeyPairErr = GenerateKeyPair()
+	})
+	return runKeyPair, runKeyPairErr
+}
+
+// SetPasswordAuthentication
+// Enables or disables password authentication by default password as a fallback of the key
+// authentication. It affects SSHCredentials initialized after the call.
+func SetPasswordAuthentication(enabled bool) {
+	passwordAuthentication = enabled
+}
+
+// ParseHostKeys
+// Parses public keys in authorized_keys format (content of ssh_host_*_key.pub files). Returns error
+// if no key is found or any key is invalid.
+func ParseHostKeys(data []byte) ([]ssh.PublicKey, error) {
+	var hostKeys []ssh.PublicKey
+	rest := bytes.TrimSpace(data)
+	for len(rest) > 0 {
+		hostKey, _, _, nextRest, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse host key - %w", err)
+		}
+		hostKeys = append(hostKeys, hostKey)
+		rest = bytes.TrimSpace(nextRest)
+	}
+	if len(hostKeys) == 0 {
+		return nil, fmt.Errorf("no host key found")
+	}
+	return hostKeys, nil
+}
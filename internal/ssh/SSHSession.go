@@ -3,6 +3,7 @@ package ssh
 import (
 	"github.com/bacpack-system/packager/internal/prerequisites"
 	"github.com/bacpack-system/packager/internal/constants"
+	"bytes"
 	"fmt"
 	"golang.org/x/crypto/ssh"
 	"io"
@@ -11,13 +12,23 @@ import (
 	"time"
 )
 
-// SSHCredentials is used as endpoint credentials of the remote server
+const (
+	defaultPassword = "1234"
+)
+
+// SSHCredentials is used as endpoint credentials of the remote server.
+// The KeyPair is used for authentication, the Password is used only if it is not empty.
+// The server must present one of the HostKeys, else the connection is refused.
 //
 type SSHCredentials struct {
 	IPAddress string
 	Port      uint16
 	Username  string
 	Password  string
+	KeyPair   *KeyPair `json:"-"`
+	// HostKeys public host keys of the server in authorized_keys format, they are pinned when the
+	// server is started
+	HostKeys  []byte   `json:"-"`
 }
 
 // SSHSession represents standard SSH Session needed for each SSH "Connection"
@@ -31,11 +42,18 @@ type SSHSession struct {
 }
 
 func (cred *SSHCredentials) FillDefault(*prerequisites.Args) error {
+	keyPair, err := GetRunKeyPair()
+	if err != nil {
+		return err
+	}
 	*cred = SSHCredentials{
 		IPAddress: "127.0.0.1",
 		Port:      constants.DefaultSSHPort,
 		Username:  "root",
-		Password:  "1234",
+		KeyPair:   keyPair,
+	}
+	if passwordAuthentication {
+		cred.Password = defaultPassword
 	}
 	return nil
 }
@@ -45,9 +63,52 @@ func (cred *SSHCredentials) FillDynamic(*prerequisites.Args) error {
 }
 
 func (cred *SSHCredentials) CheckPrerequisites(*prerequisites.Args) error {
+	if cred.KeyPair == nil && cred.Password == "" {
+		return fmt.Errorf("SSH key or password must be set")
+	}
 	return nil
 }
 
+// GetAuthorizedKey
+// Returns public key in authorized_keys format. If the KeyPair is not set, nil is returned.
+func (cred *SSHCredentials) GetAuthorizedKey() []byte {
+	if cred.KeyPair == nil {
+		return nil
+	}
+	return cred.KeyPair.AuthorizedKey
+}
+
+// getAuthMethods
+// Returns authentication methods for the credentials. The key authentication is tried first.
+func (cred *SSHCredentials) getAuthMethods() []ssh.AuthMethod {
+	var authMethods []ssh.AuthMethod
+	if cred.KeyPair != nil {
+		authMethods = append(authMethods, ssh.PublicKeys(cred.KeyPair.Signer))
+	}
+	if cred.Password != "" {
+		authMethods = append(authMethods, ssh.Password(cred.Password))
+	}
+	return authMethods
+}
+
+// verifyHostKey
+// Returns nil if the key is one of the HostKeys, else returns error.
+func (cred *SSHCredentials) verifyHostKey(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if len(cred.HostKeys) == 0 {
+		return fmt.Errorf("host keys of %s are not known", hostname)
+	}
+	hostKeys, err := ParseHostKeys(cred.HostKeys)
+	if err != nil {
+		return err
+	}
+	for _, hostKey := range hostKeys {
+		if bytes.Equal(hostKey.Marshal(), key.Marshal()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host key of %s (%s) does not match any host key of the server", hostname, ssh.FingerprintSHA256(key))
+}
+
 func (session *SSHSession) GetSSHSession() *ssh.Session {
 	return session.sshSession
 }
@@ -81,12 +142,8 @@ func (session *SSHSession) LoginMultipleAttempts(credentials SSHCredentials) err
 func (session *SSHSession) Login(credentials SSHCredentials) error {
 	sshConfig := &ssh.ClientConfig{
 		User: credentials.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(credentials.Password),
-		},
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		Auth: credentials.getAuthMethods(),
+		HostKeyCallback: credentials.verifyHostKey,
 		BannerCallback: func(message string) error {
 			return nil
 		},
@@ -95,7 +152,7 @@ func (session *SSHSession) Login(credentials SSHCredentials) error {
 	IPAndPort := credentials.IPAddress + ":" + strconv.Itoa(int(credentials.Port))
 	sshClient, err := ssh.Dial("tcp", IPAndPort, sshConfig)
 	if err != nil {
-		return fmt.Errorf("cannot connect to server")
+		return fmt.Errorf("cannot connect to server - %w", err)
 	}
 
 	sshSession, err := sshClient.NewSession()
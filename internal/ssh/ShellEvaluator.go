@@ -60,6 +60,13 @@ func (shell *ShellEvaluator) getPreparingCommandStr() string {
 	return prepCommandStr
 }
 
+// GetScript
+// Returns Bash script which sets environment variables and runs preparing and main commands. The
+// script should be run by "bash -li -c".
+func (shell *ShellEvaluator) GetScript() string {
+	return shell.getEnvStr() + shell.getPreparingCommandStr() + shell.getCommandStr()
+}
+
 // RunOverSSH
 // Runs command over SSH.
 //
@@ -81,8 +88,7 @@ func (shell *ShellEvaluator) RunOverSSH(credentials SSHCredentials) error {
 		return err
 	}
 
-	raw := shell.getEnvStr() + shell.getPreparingCommandStr() + shell.getCommandStr()
-	safe := strings.ReplaceAll(raw, "'", "'\\''") // Escaping all single quotes
+	safe := strings.ReplaceAll(shell.GetScript(), "'", "'\\''") // Escaping all single quotes
 	cmdStr := "bash -li -c '" + safe + "'"
 
 	err = session.Run(cmdStr)
@@ -0,0 +1,43 @@
+package ssh
+
+import (
+	"testing"
+)
+
+func TestVerifyHostKey(t *testing.T) {
+	hostKeyPair, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed - %s", err)
+	}
+	otherKeyPair, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed - %s", err)
+	}
+	credentials := SSHCredentials{
+		HostKeys: append(otherKeyPair.AuthorizedKey, hostKeyPair.AuthorizedKey...),
+	}
+
+	err = credentials.verifyHostKey("container", nil, hostKeyPair.Signer.PublicKey())
+	if err != nil {
+		t.Errorf("host key of the server refused - %s", err)
+	}
+
+	credentials.HostKeys = otherKeyPair.AuthorizedKey
+	err = credentials.verifyHostKey("container", nil, hostKeyPair.Signer.PublicKey())
+	if err == nil {
+		t.Error("host key not matching the server accepted")
+	}
+
+	credentials.HostKeys = nil
+	err = credentials.verifyHostKey("container", nil, hostKeyPair.Signer.PublicKey())
+	if err == nil {
+		t.Error("host key accepted without known host keys")
+	}
+}
+
+func TestParseHostKeysEmpty(t *testing.T) {
+	_, err := ParseHostKeys([]byte("\n"))
+	if err == nil {
+		t.Error("empty host keys parsed")
+	}
+}
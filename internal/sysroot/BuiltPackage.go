@@ -13,6 +13,8 @@ type BuiltPackage struct {
 	DirName string
 	GitUri string
 	GitCommitHash string
+	// CacheKey is computed from all build inputs (see build_cache.KeyInputs)
+	CacheKey string `json:",omitempty"`
 }
 
 type builtPackageInitArgs BuiltPackage
@@ -22,6 +24,7 @@ func (builtPackage *BuiltPackage) FillDefault(*prerequisites.Args) error {
 	builtPackage.DirName = ""
 	builtPackage.GitUri = ""
 	builtPackage.GitCommitHash = ""
+	builtPackage.CacheKey = ""
 	return nil
 }
 
@@ -33,6 +36,7 @@ func (builtPackage *BuiltPackage) FillDynamic(args *prerequisites.Args) error {
 		builtPackage.DirName = argsStruct.DirName
 		builtPackage.GitUri = argsStruct.GitUri
 		builtPackage.GitCommitHash = argsStruct.GitCommitHash
+		builtPackage.CacheKey = argsStruct.CacheKey
 	}
 	return nil
 }
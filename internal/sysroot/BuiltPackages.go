@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync"
 )
 
 const (
@@ -20,20 +21,50 @@ type BuiltPackages struct {
 	Packages []BuiltPackage
 }
 
+// builtPackagesLock serializes access to built_packages.json, which is shared by all Sysroots.
+var builtPackagesLock sync.Mutex
+
 // AddToBuiltPackages
 // Adds packageName to built Packages.
 func (builtPackages *BuiltPackages) AddToBuiltPackages(pack BuiltPackage) error {
+	builtPackagesLock.Lock()
+	defer builtPackagesLock.Unlock()
 	err := builtPackages.updateBuiltPackages()
 	if err != nil {
 		return fmt.Errorf("can't update builtPackages from json - %w", err)
 	}
 	builtPackages.Packages = append(builtPackages.Packages, pack)
+	return builtPackages.writeBuiltPackages()
+}
+
+// removeFromBuiltPackages
+// Removes one occurrence of each of packs from built Packages.
+func (builtPackages *BuiltPackages) removeFromBuiltPackages(packs []BuiltPackage) error {
+	builtPackagesLock.Lock()
+	defer builtPackagesLock.Unlock()
+	err := builtPackages.updateBuiltPackages()
+	if err != nil {
+		return fmt.Errorf("can't update builtPackages from json - %w", err)
+	}
+	for _, pack := range packs {
+		for i, p := range builtPackages.Packages {
+			if p == pack {
+				builtPackages.Packages = append(builtPackages.Packages[:i], builtPackages.Packages[i+1:]...)
+				break
+			}
+		}
+	}
+	return builtPackages.writeBuiltPackages()
+}
+
+// writeBuiltPackages
+// Writes builtPackages struct to built_packages.json.
+func (builtPackages *BuiltPackages) writeBuiltPackages() error {
 	bytes, err := json.MarshalIndent(builtPackages.Packages, "", indent)
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(path.Join(sysrootDirectoryName, jsonFileName), bytes, 0644)
-	return err
+	return os.WriteFile(path.Join(sysrootDirectoryName, jsonFileName), bytes, 0644)
 }
 
 // UpdateBuiltPackages
@@ -41,6 +72,7 @@ func (builtPackages *BuiltPackages) AddToBuiltPackages(pack BuiltPackage) error
 func (builtPackages *BuiltPackages) updateBuiltPackages() error {
 	bytes, err := os.ReadFile(path.Join(sysrootDirectoryName, jsonFileName))
 	if os.IsNotExist(err) {
+		builtPackages.Packages = nil
 		return nil
 	} else if err != nil {
 		return fmt.Errorf("failed to read built packages file - %w", err)
@@ -55,8 +87,11 @@ func (builtPackages *BuiltPackages) updateBuiltPackages() error {
 
 // Contains
 // Returns true if given Package is in builtPackages, else false. All fields of BuiltPackage struct
-// are compared. Only if the pack has empty GitCommitHash, the GitCommitHash is not compared.
+// are compared. Only if the pack has empty GitCommitHash, the GitCommitHash is not compared. The
+// CacheKey is compared only if both Packages have it (Packages built by older versions do not).
 func (builtPackages *BuiltPackages) Contains(pack BuiltPackage) bool {
+	builtPackagesLock.Lock()
+	defer builtPackagesLock.Unlock()
 	err := builtPackages.updateBuiltPackages()
 	if err != nil {
 		logger := log.GetLogger()
@@ -67,6 +102,9 @@ func (builtPackages *BuiltPackages) Contains(pack BuiltPackage) bool {
 		if pack.GitCommitHash != constants.EmptyGitCommitHash {
 			condition = condition && pack.GitCommitHash == p.GitCommitHash
 		}
+		if pack.CacheKey != "" && p.CacheKey != "" {
+			condition = condition && pack.CacheKey == p.CacheKey
+		}
 		if condition {
 			return true
 		}
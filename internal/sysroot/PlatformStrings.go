@@ -0,0 +1,69 @@
+package sysroot
+
+import (
+	"github.com/bacpack-system/packager/internal/bacpack_package"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+)
+
+const (
+	platformStringsFileName = "platform_strings.json"
+)
+
+// SavePlatformString
+// Saves platform string determined for the image to platform_strings.json in sysroot directory, so
+// it can be used later without running the image (for example in dry run).
+func SavePlatformString(imageName string, platformString *bacpack_package.PlatformString) error {
+	platformStrings, err := readPlatformStrings()
+	if err != nil {
+		return err
+	}
+	platformStrings[imageName] = platformString.String
+	bytes, err := json.MarshalIndent(platformStrings, "", indent)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(sysrootDirectoryName, 0777)
+	if err != nil {
+		return fmt.Errorf("cannot create sysroot directory - %w", err)
+	}
+	return os.WriteFile(path.Join(sysrootDirectoryName, platformStringsFileName), bytes, 0644)
+}
+
+// LoadPlatformString
+// Returns platform string saved for the image by SavePlatformString. If there is no platform
+// string saved for the image, nil is returned.
+func LoadPlatformString(imageName string) (*bacpack_package.PlatformString, error) {
+	platformStrings, err := readPlatformStrings()
+	if err != nil {
+		return nil, err
+	}
+	platformStringExplicit, found := platformStrings[imageName]
+	if !found {
+		return nil, nil
+	}
+	return &bacpack_package.PlatformString{
+		Mode:   bacpack_package.ModeExplicit,
+		String: platformStringExplicit,
+	}, nil
+}
+
+// readPlatformStrings
+// Returns map of image names to platform strings from platform_strings.json. If the file does not
+// exist, an empty map is returned.
+func readPlatformStrings() (map[string]bacpack_package.PlatformStringExplicit, error) {
+	platformStrings := make(map[string]bacpack_package.PlatformStringExplicit)
+	bytes, err := os.ReadFile(path.Join(sysrootDirectoryName, platformStringsFileName))
+	if os.IsNotExist(err) {
+		return platformStrings, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read platform strings file - %w", err)
+	}
+	err = json.Unmarshal(bytes, &platformStrings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse platform strings file - %w", err)
+	}
+	return platformStrings, nil
+}
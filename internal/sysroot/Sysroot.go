@@ -12,6 +12,7 @@ import (
 	"io/fs"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 const (
@@ -22,6 +23,10 @@ const (
 	debugName = "_debug"
 )
 
+// copyLock serializes copying to sysroot, so the overwrite check and the copy itself are not
+// interleaved with other copies.
+var copyLock sync.Mutex
+
 // Sysroot represents a standard Linux sysroot with all needed libraries installed.
 // Sysroot for each build type (Release, Debug) the separate sysroot is created
 type Sysroot struct {
@@ -47,9 +52,12 @@ func (sysroot *Sysroot) CheckPrerequisites(args *prerequisites.Args) error {
 	return nil
 }
 
-// CopyToSysroot copy source to a sysroot
+// CopyToSysroot copy source to a sysroot. It is safe to call it from multiple goroutines.
 func (sysroot *Sysroot) CopyToSysroot(source string, pack BuiltPackage) error {
-	err := sysroot.checkForOverwritingFiles(source)
+	copyLock.Lock()
+	defer copyLock.Unlock()
+	filesToCopy := getExistingFilesInDir(source)
+	err := sysroot.checkForOverwritingFiles(filesToCopy)
 	if err != nil {
 		return err
 	}
@@ -66,6 +74,7 @@ func (sysroot *Sysroot) CopyToSysroot(source string, pack BuiltPackage) error {
 	if err != nil {
 		return err
 	}
+	recordCopy(pack, sysroot.GetSysrootPath(), filesToCopy)
 	return nil
 }
 
@@ -77,11 +86,10 @@ func (sysroot *Sysroot) IsPackageInSysroot(pack BuiltPackage) bool {
 }
 
 // checkForOverwritingFiles
-// Checks if in dirPath directory are not files which are also in sysroot directory. If there are
-// some, then prints Error with listing problematic files and returns non nil error. Else returns
-// nil error without printing anything.
-func (sysroot *Sysroot) checkForOverwritingFiles(dirPath string) error {
-	filesToCopy := getExistingFilesInDir(dirPath)
+// Checks if filesToCopy (paths relative to the copied directory) are not also in sysroot directory.
+// If there are some, then prints Error with listing problematic files and returns non nil error.
+// Else returns nil error without printing anything.
+func (sysroot *Sysroot) checkForOverwritingFiles(filesToCopy []string) error {
 	filesInSysrootMap := make(map[string]struct{})
 	for _, file := range getExistingFilesInDir(sysroot.GetSysrootPath()) {
 		filesInSysrootMap[file] = struct{}{}
@@ -0,0 +1,103 @@
+package sysroot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// copiedPackage
+// Package copied to a sysroot in the transaction together with the files it added to the sysroot.
+type copiedPackage struct {
+	pack        BuiltPackage
+	sysrootPath string
+	files       []string
+}
+
+// transactionCopies Packages copied to all sysroots since BeginTransaction. It is guarded by
+// copyLock.
+var transactionCopies []copiedPackage
+
+// inTransaction true between BeginTransaction and CommitTransaction/RollbackTransaction. It is
+// guarded by copyLock.
+var inTransaction bool
+
+// BeginTransaction
+// Starts recording of Packages copied to all sysroots, so they can be removed by
+// RollbackTransaction if they are not stored in Package Repository.
+func BeginTransaction() {
+	copyLock.Lock()
+	defer copyLock.Unlock()
+	inTransaction = true
+	transactionCopies = nil
+}
+
+// CommitTransaction
+// Stops recording of Packages copied to sysroots, the copied Packages stay in sysroots.
+func CommitTransaction() {
+	copyLock.Lock()
+	defer copyLock.Unlock()
+	inTransaction = false
+	transactionCopies = nil
+}
+
+// RollbackTransaction
+// Removes files of all Packages copied to sysroots since BeginTransaction and removes the Packages
+// from built_packages.json, so they are built and copied again by the next build.
+func RollbackTransaction() error {
+	copyLock.Lock()
+	defer copyLock.Unlock()
+	if !inTransaction {
+		return nil
+	}
+	var packs []BuiltPackage
+	for i := len(transactionCopies) - 1; i >= 0; i-- {
+		copied := transactionCopies[i]
+		for _, file := range copied.files {
+			err := removeFromSysroot(copied.sysrootPath, file)
+			if err != nil {
+				return fmt.Errorf("cannot remove Package %s from sysroot - %w", copied.pack.Name, err)
+			}
+		}
+		packs = append(packs, copied.pack)
+	}
+	var builtPackages BuiltPackages
+	err := builtPackages.removeFromBuiltPackages(packs)
+	if err != nil {
+		return err
+	}
+	inTransaction = false
+	transactionCopies = nil
+	return nil
+}
+
+// recordCopy
+// Records Package copied to sysrootPath with its files if a transaction is in progress. Must be
+// called with copyLock held.
+func recordCopy(pack BuiltPackage, sysrootPath string, files []string) {
+	if !inTransaction {
+		return
+	}
+	transactionCopies = append(transactionCopies, copiedPackage{
+		pack:        pack,
+		sysrootPath: sysrootPath,
+		files:       files,
+	})
+}
+
+// removeFromSysroot
+// Removes file (path relative to sysrootPath) and its parent directories which become empty. The
+// sysrootPath itself is kept.
+func removeFromSysroot(sysrootPath string, file string) error {
+	filePath := filepath.Join(sysrootPath, file)
+	err := os.Remove(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for dir := filepath.Dir(filePath); dir != sysrootPath && len(dir) > len(sysrootPath); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
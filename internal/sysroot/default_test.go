@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -46,17 +47,17 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
-	err = prerequisites.Initialize(&builtPackage1, testtools.Pack1Name, sysrootDirName, gitUri, constants.EmptyGitCommitHash)
+	err = prerequisites.Initialize(&builtPackage1, testtools.Pack1Name, sysrootDirName, gitUri, constants.EmptyGitCommitHash, "")
 	if err != nil {
 		panic(err)
 	}
 
-	err = prerequisites.Initialize(&builtPackage2, testtools.Pack2Name, sysrootDirName, gitUri, constants.EmptyGitCommitHash)
+	err = prerequisites.Initialize(&builtPackage2, testtools.Pack2Name, sysrootDirName, gitUri, constants.EmptyGitCommitHash, "")
 	if err != nil {
 		panic(err)
 	}
 
-	err = prerequisites.Initialize(&builtPackage3, testtools.Pack3Name, sysrootDirName, gitUri, constants.EmptyGitCommitHash)
+	err = prerequisites.Initialize(&builtPackage3, testtools.Pack3Name, sysrootDirName, gitUri, constants.EmptyGitCommitHash, "")
 	if err != nil {
 		panic(err)
 	}
@@ -188,6 +189,42 @@ func TestCopyToSysrootMultiplePackages(t *testing.T) {
 	}
 }
 
+func TestCopyToSysrootConcurrently(t *testing.T) {
+	packages := map[string]BuiltPackage{
+		testtools.Pack1Name: builtPackage1,
+		testtools.Pack2Name: builtPackage2,
+		testtools.Pack3Name: builtPackage3,
+	}
+
+	var wg sync.WaitGroup
+	for source, pack := range packages {
+		wg.Add(1)
+		go func(source string, pack BuiltPackage) {
+			defer wg.Done()
+			sysroot := Sysroot {
+				IsDebug: false,
+				PlatformString: &defaultPlatformString,
+			}
+			err := sysroot.CopyToSysroot(source, pack)
+			if err != nil {
+				t.Errorf("CopyToSysroot failed - %s", err)
+			}
+		}(source, pack)
+	}
+	wg.Wait()
+
+	for _, pack := range packages {
+		if !defaultSysroot.IsPackageInSysroot(pack) {
+			t.Errorf("package %s is missing in built packages", pack.Name)
+		}
+	}
+
+	err := clearSysroot()
+	if err != nil {
+		t.Errorf("can't delete sysroot dir - %s", err)
+	}
+}
+
 func TestCopyToSysrootOvewriteFiles(t *testing.T) {
 	err := defaultSysroot.CopyToSysroot(testtools.Pack1Name, builtPackage1)
 	if err != nil {
@@ -267,6 +304,122 @@ func TestIsPackageInSysrootDifferentHash(t *testing.T) {
 	}
 }
 
+func TestIsPackageInSysrootDifferentCacheKey(t *testing.T) {
+	sysroot := Sysroot {
+		IsDebug: false,
+		PlatformString: &defaultPlatformString,
+	}
+	err := prerequisites.Initialize(&defaultSysroot)
+	if err != nil {
+		t.Fatalf("sysroot initialization failed - %s", err)
+	}
+
+	packageWithKey := builtPackage1
+	packageWithKey.CacheKey = "key"
+	err = sysroot.CopyToSysroot(testtools.Pack1Name, packageWithKey)
+	if err != nil {
+		t.Errorf("CopyToSysroot failed - %s", err)
+	}
+
+	otherPackage := packageWithKey
+	otherPackage.CacheKey = "different_key"
+	if sysroot.IsPackageInSysroot(otherPackage) {
+		t.Error("IsPackageInSysroot returned true for package with different cache key")
+	}
+	if !sysroot.IsPackageInSysroot(builtPackage1) {
+		t.Error("IsPackageInSysroot returned false for package without cache key")
+	}
+
+	err = clearSysroot()
+	if err != nil {
+		t.Errorf("can't delete sysroot dir - %s", err)
+	}
+}
+
+func TestRollbackTransaction(t *testing.T) {
+	err := defaultSysroot.CopyToSysroot(testtools.Pack1Name, builtPackage1)
+	if err != nil {
+		t.Errorf("CopyToSysroot failed - %s", err)
+	}
+
+	BeginTransaction()
+	err = defaultSysroot.CopyToSysroot(testtools.Pack2Name, builtPackage2)
+	if err != nil {
+		t.Errorf("CopyToSysroot failed - %s", err)
+	}
+	err = RollbackTransaction()
+	if err != nil {
+		t.Errorf("RollbackTransaction failed - %s", err)
+	}
+
+	if defaultSysroot.IsPackageInSysroot(builtPackage2) {
+		t.Error("IsPackageInSysroot returned true for rolled back package")
+	}
+	pack2Path := filepath.Join(defaultSysroot.GetSysrootPath(), testtools.Pack2FileName)
+	if _, err = os.Stat(pack2Path); !os.IsNotExist(err) {
+		t.Error("file of rolled back package is in sysroot")
+	}
+	if !defaultSysroot.IsPackageInSysroot(builtPackage1) {
+		t.Error("package copied before transaction is missing in built packages")
+	}
+	pack1Path := filepath.Join(defaultSysroot.GetSysrootPath(), testtools.Pack1FileName)
+	if _, err = os.Stat(pack1Path); err != nil {
+		t.Error("file of package copied before transaction is missing in sysroot")
+	}
+
+	BeginTransaction()
+	err = defaultSysroot.CopyToSysroot(testtools.Pack2Name, builtPackage2)
+	if err != nil {
+		t.Errorf("CopyToSysroot of rolled back package failed - %s", err)
+	}
+	CommitTransaction()
+	err = RollbackTransaction()
+	if err != nil {
+		t.Errorf("RollbackTransaction failed - %s", err)
+	}
+	if !defaultSysroot.IsPackageInSysroot(builtPackage2) {
+		t.Error("package committed in transaction is missing in built packages")
+	}
+	if _, err = os.Stat(pack2Path); err != nil {
+		t.Error("file of package committed in transaction is missing in sysroot")
+	}
+
+	err = clearSysroot()
+	if err != nil {
+		t.Errorf("can't delete sysroot dir - %s", err)
+	}
+}
+
+func TestSaveAndLoadPlatformString(t *testing.T) {
+	platformString, err := LoadPlatformString("image")
+	if err != nil {
+		t.Fatalf("LoadPlatformString failed - %s", err)
+	}
+	if platformString != nil {
+		t.Error("LoadPlatformString returned platform string which was not saved")
+	}
+
+	err = SavePlatformString("image", &defaultPlatformString)
+	if err != nil {
+		t.Fatalf("SavePlatformString failed - %s", err)
+	}
+	platformString, err = LoadPlatformString("image")
+	if err != nil {
+		t.Fatalf("LoadPlatformString failed - %s", err)
+	}
+	if platformString == nil || platformString.Serialize() != sysrootDirName {
+		t.Errorf("loaded platform string differs - %v", platformString)
+	}
+	if platformString.Mode != bacpack_package.ModeExplicit {
+		t.Error("loaded platform string is not in explicit mode")
+	}
+
+	err = clearSysroot()
+	if err != nil {
+		t.Errorf("can't delete sysroot dir - %s", err)
+	}
+}
+
 func clearSysroot() error {
 	sysrootPath := defaultSysroot.GetSysrootPath()
 	return os.RemoveAll(filepath.Dir(sysrootPath))